@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+func runAlias(opts globalOptions, args []string) int {
+	if len(args) == 1 && args[0] == "--list" {
+		aliases := controlplane.TargetAliases(controlplane.CanonicalTargets())
+		if opts.JSON {
+			return writeJSON(aliases)
+		}
+		for _, alias := range aliases {
+			fmt.Fprintf(stdout, "%-12s -> %s\n", alias.Alias, alias.Target)
+		}
+		return exitOK
+	}
+
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "error: alias command requires exactly one name (or --list)")
+		return exitUsage
+	}
+
+	target, ok := controlplane.ResolveTarget(args[0])
+	if !ok {
+		return exitCodeForError(fmt.Errorf("%w %q", controlplane.ErrUnknownTarget, args[0]))
+	}
+	if opts.JSON {
+		return writeJSON(target)
+	}
+	fmt.Fprintln(stdout, target.Name)
+	return exitOK
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunAliasResolvesNamesAndAliases(t *testing.T) {
+	out, _ := captureOutput(t)
+	for _, input := range []string{"software", "Ship", "build"} {
+		out.Reset()
+		if code := run([]string{"alias", input}); code != exitOK {
+			t.Fatalf("%s: expected exit %d, got %d", input, exitOK, code)
+		}
+		if out.String() != "build\n" {
+			t.Fatalf("%s: expected canonical target build, got %q", input, out.String())
+		}
+	}
+
+	out.Reset()
+	if code := run([]string{"--json", "alias", "software"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	var target struct {
+		Name  string `json:"name"`
+		Slice string `json:"slice"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &target); err != nil || target.Name != "build" || target.Slice != "software" {
+		t.Fatalf("expected build target JSON, got %q (%v)", out.String(), err)
+	}
+
+	if code := run([]string{"alias", "nope"}); code != exitUsage {
+		t.Fatalf("expected usage exit for unknown alias, got %d", code)
+	}
+}
+
+func TestRunAliasList(t *testing.T) {
+	out, _ := captureOutput(t)
+	if code := run([]string{"alias", "--list"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i-1] > lines[i] {
+			t.Fatalf("expected sorted aliases, got %q before %q", lines[i-1], lines[i])
+		}
+	}
+	if !strings.Contains(out.String(), "software     -> build\n") {
+		t.Fatalf("expected software -> build mapping:\n%s", out.String())
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+type batchOptions struct {
+	Pattern     string
+	FailFast    bool
+	DryRun      bool
+	Target      string
+	Repeat      int
+	StopOnError bool
+}
+
+type batchResult struct {
+	Slice string
+	Code  int
+}
+
+func parseBatchArgs(args []string) (batchOptions, error) {
+	opts := batchOptions{}
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--each":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("--each requires a slice pattern")
+			}
+			i++
+			opts.Pattern = args[i]
+		case "--repeat":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("--repeat requires a run count")
+			}
+			i++
+			if err := opts.setRepeat(args[i]); err != nil {
+				return opts, err
+			}
+		case "--fail-fast":
+			opts.FailFast = true
+		case "--stop-on-error":
+			opts.StopOnError = true
+		case "--dry-run":
+			opts.DryRun = true
+		default:
+			if pattern, ok := strings.CutPrefix(arg, "--each="); ok {
+				opts.Pattern = pattern
+				continue
+			}
+			if value, ok := strings.CutPrefix(arg, "--repeat="); ok {
+				if err := opts.setRepeat(value); err != nil {
+					return opts, err
+				}
+				continue
+			}
+			if strings.HasPrefix(arg, "-") {
+				return opts, fmt.Errorf("unknown run flag %q", arg)
+			}
+			if opts.Target != "" {
+				return opts, fmt.Errorf("run takes at most one target")
+			}
+			opts.Target = arg
+		}
+	}
+
+	switch {
+	case opts.Pattern != "" && (opts.Repeat > 0 || opts.Target != ""):
+		return opts, fmt.Errorf("--each cannot be combined with --repeat or a target")
+	case opts.Repeat > 0 && opts.Target == "":
+		return opts, fmt.Errorf("--repeat requires a target")
+	case opts.Target != "" && opts.Repeat == 0:
+		return opts, fmt.Errorf("run %s requires --repeat <n>", opts.Target)
+	case opts.StopOnError && opts.Repeat == 0:
+		return opts, fmt.Errorf("--stop-on-error requires --repeat")
+	case opts.FailFast && opts.Repeat > 0:
+		return opts, fmt.Errorf("--fail-fast applies to --each; use --stop-on-error with --repeat")
+	case opts.Pattern == "" && opts.Repeat == 0:
+		return opts, fmt.Errorf("run requires --each <slices-glob> or --repeat <n> <target>")
+	}
+	return opts, nil
+}
+
+func (opts *batchOptions) setRepeat(value string) error {
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 1 {
+		return fmt.Errorf("invalid --repeat %q (want a positive number of runs)", value)
+	}
+	opts.Repeat = count
+	return nil
+}
+
+func runBatch(opts globalOptions, args []string, forwarded []string) int {
+	batchOpts, err := parseBatchArgs(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	if batchOpts.Repeat > 0 {
+		return runRepeat(opts, batchOpts, forwarded)
+	}
+
+	root, err := determineRoot(opts)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	slices, err := controlplane.LoadSlices(root)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	names, err := controlplane.MatchSlices(slices, batchOpts.Pattern)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+
+	var results []batchResult
+	for _, name := range names {
+		spec, err := buildSliceSpec(opts, name, forwarded)
+		if err != nil {
+			results = append(results, batchResult{Slice: name, Code: exitCodeForError(fmt.Errorf("slice %s: %w", name, err))})
+		} else if batchOpts.DryRun {
+			fmt.Fprintf(chatter(opts, stdout), "%s: %s\n", name, formatCommand(spec))
+			results = append(results, batchResult{Slice: name, Code: exitOK})
+		} else {
+			fmt.Fprintf(chatter(opts, stderr), "pictl: running slice %s\n", name)
+			results = append(results, batchResult{Slice: name, Code: launch(opts, spec)})
+		}
+
+		if batchOpts.FailFast && results[len(results)-1].Code != exitOK {
+			break
+		}
+	}
+	code := printBatchSummary(chatter(opts, stdout), results, len(names))
+	if batchOpts.DryRun {
+		return code
+	}
+	return recordExitCode(opts, code)
+}
+
+func printBatchSummary(out io.Writer, results []batchResult, total int) int {
+	failed := 0
+	fmt.Fprintln(out, "summary:")
+	for _, result := range results {
+		status := "ok"
+		if result.Code != exitOK {
+			status = fmt.Sprintf("exit %d", result.Code)
+			failed++
+		}
+		fmt.Fprintf(out, "  %-20s %s\n", result.Slice, status)
+	}
+	if skipped := total - len(results); skipped > 0 {
+		fmt.Fprintf(out, "  (%d not run after --fail-fast)\n", skipped)
+	}
+	fmt.Fprintf(out, "%d/%d slices succeeded\n", len(results)-failed, total)
+
+	if failed > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
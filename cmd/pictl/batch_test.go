@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeBatchFixture(t *testing.T) string {
+	t.Helper()
+
+	root := writeFixtureRoot(t, map[string]string{
+		"a-good": validSlice,
+		"b-bad":  `{"extensions": ["extensions/bad.ts"]}`,
+		"c-good": validSlice,
+		"other":  validSlice,
+	})
+	if err := os.WriteFile(filepath.Join(root, "extensions", "bad.ts"), []byte("export default function () {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeFakePi(t, `case "$*" in *bad.ts*) exit 3;; esac; exit 0`)
+	return root
+}
+
+func TestRunBatchContinuesAndSummarizes(t *testing.T) {
+	root := writeBatchFixture(t)
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "run", "--each", "?-*"}); code != exitFailure {
+		t.Fatalf("expected exit %d, got %d", exitFailure, code)
+	}
+	for _, want := range []string{"a-good", "b-bad", "exit 3", "c-good", "2/3 slices succeeded"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in summary:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "other") {
+		t.Fatalf("expected non-matching slice to be skipped:\n%s", out.String())
+	}
+}
+
+func TestRunBatchFailFast(t *testing.T) {
+	root := writeBatchFixture(t)
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "run", "--each=?-*", "--fail-fast"}); code != exitFailure {
+		t.Fatalf("expected exit %d, got %d", exitFailure, code)
+	}
+	if strings.Contains(out.String(), "c-good") || !strings.Contains(out.String(), "1 not run") {
+		t.Fatalf("expected batch to stop after b-bad:\n%s", out.String())
+	}
+}
+
+func TestRunBatchDryRun(t *testing.T) {
+	root := writeBatchFixture(t)
+	writeFakePi(t, "exit 9")
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "run", "--each", "*-good", "--dry-run"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if !strings.Contains(out.String(), "a-good: ") || !strings.Contains(out.String(), " pi --no-extensions") {
+		t.Fatalf("expected previewed commands:\n%s", out.String())
+	}
+
+	if code := run([]string{"--root", root, "run"}); code != exitUsage {
+		t.Fatalf("expected usage exit without --each, got %d", code)
+	}
+	if code := run([]string{"--root", root, "run", "--each", "zzz*"}); code != exitMissing {
+		t.Fatalf("expected missing exit for no matches, got %d", code)
+	}
+}
+
+func TestRunBatchDryRunQuiet(t *testing.T) {
+	root := writeBatchFixture(t)
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--quiet", "--root", root, "--print-cmd", "run", "--each", "*-good", "--dry-run"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if out.Len() != 0 || errOut.Len() != 0 {
+		t.Fatalf("expected no output under --quiet, got stdout %q stderr %q", out.String(), errOut.String())
+	}
+
+	if code := run([]string{"--quiet", "--root", root, "run", "--each", "?-*"}); code != exitFailure {
+		t.Fatalf("expected quiet batch to keep failure exit, got %d", code)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected no summary under --quiet:\n%s", out.String())
+	}
+}
+
+func TestQuietAndVerboseConflict(t *testing.T) {
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--quiet", "--verbose", "list"}); code != exitUsage {
+		t.Fatalf("expected usage exit, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "mutually exclusive") {
+		t.Fatalf("expected conflict error, got %q", errOut.String())
+	}
+}
+
+func TestSummarizeRuns(t *testing.T) {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	summary := summarizeRuns([]repeatRun{
+		{Run: 1, Duration: ms(40)},
+		{Run: 2, Code: 3, Duration: ms(10)},
+		{Run: 3, Duration: ms(30)},
+		{Run: 4, Code: 124, Duration: ms(90)},
+	})
+	if summary.Runs != 4 || summary.Min != ms(10) || summary.Median != ms(35) || summary.Max != ms(90) {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if len(summary.Failed) != 2 || summary.Failed[0].Run != 2 || summary.Failed[1].Code != 124 {
+		t.Fatalf("unexpected failures %+v", summary.Failed)
+	}
+
+	odd := summarizeRuns([]repeatRun{{Duration: ms(5)}, {Duration: ms(1)}, {Duration: ms(3)}})
+	if odd.Median != ms(3) || len(odd.Failed) != 0 {
+		t.Fatalf("unexpected odd summary %+v", odd)
+	}
+}
+
+func TestRunRepeat(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	counter := filepath.Join(t.TempDir(), "count")
+	writeFakePi(t, `n=0; [ -f `+counter+` ] && read n < `+counter+`; n=$((n + 1)); echo $n > `+counter+`
+echo "noisy output $n"
+if [ $n -eq 2 ]; then echo "run two failed" >&2; exit 3; fi`)
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "run", "meta", "--repeat", "3"}); code != exitFailure {
+		t.Fatalf("expected exit %d, got %d", exitFailure, code)
+	}
+	for _, want := range []string{"min ", "median ", "max ", "run 2    exit 3", "2/3 runs succeeded"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in summary:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "noisy output") {
+		t.Fatalf("expected pi output to be captured:\n%s", out.String())
+	}
+	if !strings.Contains(errOut.String(), "run two failed") || !strings.Contains(errOut.String(), "run 3/3 ok") {
+		t.Fatalf("expected per-run progress and failing output on stderr:\n%s", errOut.String())
+	}
+
+	os.Remove(counter)
+	out.Reset()
+	if code := run([]string{"--root", root, "run", "--repeat=5", "--stop-on-error", "meta"}); code != exitFailure {
+		t.Fatalf("expected exit %d, got %d", exitFailure, code)
+	}
+	if !strings.Contains(out.String(), "(3 not run after --stop-on-error)") || !strings.Contains(out.String(), "1/5 runs succeeded") {
+		t.Fatalf("expected repeat to stop after run 2:\n%s", out.String())
+	}
+}
+
+func TestParseBatchArgsRepeat(t *testing.T) {
+	for _, args := range [][]string{
+		{"--repeat", "3"},
+		{"meta"},
+		{"--repeat", "0", "meta"},
+		{"--repeat", "x", "meta"},
+		{"--each", "*", "--repeat", "2", "meta"},
+		{"--each", "*", "--stop-on-error"},
+		{"--repeat", "2", "--fail-fast", "meta"},
+		{"--repeat", "2", "meta", "build"},
+	} {
+		if _, err := parseBatchArgs(args); err == nil {
+			t.Fatalf("expected %v to be rejected", args)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+const benchmarkRuns = 5
+
+type benchmarkPhase struct {
+	Name     string  `json:"name"`
+	Subject  string  `json:"subject,omitempty"`
+	MinMS    float64 `json:"minMs"`
+	MedianMS float64 `json:"medianMs"`
+	Error    string  `json:"error,omitempty"`
+}
+
+type benchmarkReport struct {
+	Root   string           `json:"root"`
+	Runs   int              `json:"runs"`
+	Phases []benchmarkPhase `json:"phases"`
+}
+
+func runDoctorBenchmark(opts globalOptions) int {
+	root, err := determineRoot(opts)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+
+	report := benchmarkReport{Root: root, Runs: benchmarkRuns}
+	report.Phases = append(report.Phases, timePhase("determine-root", "", func() error {
+		_, _, err := controlplane.ResolveRoot(opts.Root, nil)
+		return err
+	}))
+
+	var slices map[string]controlplane.SliceManifest
+	report.Phases = append(report.Phases, timePhase("load-slices", "", func() error {
+		slices, err = controlplane.LoadSlices(root)
+		return err
+	}))
+	if slices != nil {
+		report.Phases[len(report.Phases)-1].Subject = fmt.Sprintf("%d slices", len(slices))
+		name, launchOpts := benchmarkLaunch(opts, slices)
+		report.Phases = append(report.Phases, timePhase("build-launch-spec", name, func() error {
+			if name == "" {
+				return fmt.Errorf("no slices to build")
+			}
+			_, err := controlplane.BuildLaunchSpecWithOptions(root, slices[name], launchOpts)
+			return err
+		}))
+	}
+
+	code := exitOK
+	for _, phase := range report.Phases {
+		if phase.Error != "" {
+			code = exitFailure
+		}
+	}
+
+	if opts.JSON {
+		if jsonCode := writeJSON(report); jsonCode != exitOK {
+			return jsonCode
+		}
+		return code
+	}
+
+	fmt.Fprintf(stdout, "startup timings for %s (%d runs each)\n", root, report.Runs)
+	for _, phase := range report.Phases {
+		label := phase.Name
+		if phase.Subject != "" {
+			label += " (" + phase.Subject + ")"
+		}
+		if phase.Error != "" {
+			fmt.Fprintf(stdout, "%s %-36s %s\n", statusMarker(controlplane.CheckFail), label, phase.Error)
+			continue
+		}
+		fmt.Fprintf(stdout, "  %-36s min %8.3fms  median %8.3fms\n", label, phase.MinMS, phase.MedianMS)
+	}
+	return code
+}
+
+func benchmarkLaunch(opts globalOptions, slices map[string]controlplane.SliceManifest) (string, controlplane.LaunchOptions) {
+	launchOpts := launchOptions(opts, nil)
+	for _, target := range controlplane.CanonicalTargets() {
+		if _, ok := slices[target.Slice]; ok {
+			launchOpts.DefaultProfile = target.DefaultProfile
+			launchOpts.ForwardedArgs = concatArgs(target.DefaultArgs, launchOpts.ForwardedArgs)
+			return target.Slice, launchOpts
+		}
+	}
+
+	names := make([]string, 0, len(slices))
+	for name := range slices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return "", launchOpts
+	}
+	return names[0], launchOpts
+}
+
+func timePhase(name string, subject string, fn func() error) benchmarkPhase {
+	phase := benchmarkPhase{Name: name, Subject: subject}
+	durations := make([]time.Duration, 0, benchmarkRuns)
+	for i := 0; i < benchmarkRuns; i++ {
+		start := time.Now()
+		if err := fn(); err != nil {
+			phase.Error = err.Error()
+			return phase
+		}
+		durations = append(durations, time.Since(start))
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	phase.MinMS = milliseconds(durations[0])
+	phase.MedianMS = milliseconds(durations[len(durations)/2])
+	return phase
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+)
+
+var colorEnabled bool
+
+func shouldColor(opts globalOptions) bool {
+	if opts.NoColor {
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := stdout.(*os.File)
+	return ok && controlplane.IsTerminal(file)
+}
+
+func colorize(code string, text string) string {
+	if !colorEnabled {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+func statusMarker(status controlplane.CheckStatus) string {
+	switch status {
+	case controlplane.CheckOK:
+		return colorize(colorGreen, "✓")
+	case controlplane.CheckWarn:
+		return colorize(colorYellow, "!")
+	default:
+		return colorize(colorRed, "✗")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShouldColorDisabled(t *testing.T) {
+	captureOutput(t)
+	t.Setenv("NO_COLOR", "")
+
+	if shouldColor(globalOptions{NoColor: true}) {
+		t.Fatalf("expected --no-color to disable color")
+	}
+	if shouldColor(globalOptions{}) {
+		t.Fatalf("expected non-TTY stdout to disable color")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if shouldColor(globalOptions{}) {
+		t.Fatalf("expected NO_COLOR to disable color")
+	}
+}
+
+func TestColorize(t *testing.T) {
+	prev := colorEnabled
+	t.Cleanup(func() { colorEnabled = prev })
+
+	colorEnabled = false
+	if got := colorize(colorGreen, "ok"); got != "ok" {
+		t.Fatalf("expected plain text with color disabled, got %q", got)
+	}
+	colorEnabled = true
+	if got := colorize(colorGreen, "ok"); got != "\x1b[32mok\x1b[0m" {
+		t.Fatalf("expected green escape codes, got %q", got)
+	}
+}
+
+func TestRunDoctorNoEscapeCodesWithoutColor(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice, "scratch": validSlice})
+	t.Setenv("NO_COLOR", "1")
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "doctor"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if strings.Contains(out.String(), "\x1b[") {
+		t.Fatalf("expected no escape codes, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "! unused-slices: scratch") {
+		t.Fatalf("expected warn marker for unused slice, got %q", out.String())
+	}
+}
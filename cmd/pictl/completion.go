@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const completionRCMarker = "# pictl completion"
+
+var completionCommands = []string{
+	"alias", "args", "completion", "config", "disable-slice", "doctor", "enable-slice", "explain", "extensions", "help",
+	"init", "list", "lock", "open", "profiles", "replay", "rm-slice", "run", "set-profile", "slice", "slices", "targets",
+	"unset-profile", "version", "watch",
+}
+
+var completionBoolFlags = []string{
+	"--strict", "--strict-extensions", "--strict-paths", "--json", "--print-cmd", "--verbose", "--quiet", "--no-color",
+	"--no-env-file", "--skip-requires", "--profile-from-branch", "--yes", "--show-hidden", "--version", "--help",
+}
+
+var completionPathFlags = map[string]bool{
+	"--root": true, "--env-file": true, "--args-file": true, "--slice-dir": true, "--profile-file": true,
+	"--exit-code-file": true, "--trace-launch": true, "--cwd": true, "--stdin-file": true,
+}
+
+const (
+	completionTargets  = `pictl list --all --format '{{.Name}}' 2>/dev/null`
+	completionSlices   = `pictl slices --all --format '{{.Name}}' 2>/dev/null`
+	completionProfiles = `pictl profiles 2>/dev/null | awk '{print $1}'`
+)
+
+type completionOptions struct {
+	Shell   string
+	Install bool
+	DryRun  bool
+}
+
+type completionInstall struct {
+	Shell      string
+	ScriptPath string
+	RCPath     string
+	RCBlock    string
+	Reload     string
+}
+
+func parseCompletionArgs(args []string) (completionOptions, error) {
+	opts := completionOptions{}
+	for _, arg := range args {
+		switch arg {
+		case "--install":
+			opts.Install = true
+		case "--dry-run":
+			opts.DryRun = true
+		case "bash", "zsh", "fish":
+			if opts.Shell != "" {
+				return opts, fmt.Errorf("completion takes one shell")
+			}
+			opts.Shell = arg
+		default:
+			return opts, fmt.Errorf("unknown completion argument %q (want bash, zsh, fish, --install, or --dry-run)", arg)
+		}
+	}
+	if opts.DryRun && !opts.Install {
+		return opts, fmt.Errorf("--dry-run requires --install")
+	}
+	if opts.Shell == "" {
+		shell := filepath.Base(os.Getenv("SHELL"))
+		if shell != "bash" && shell != "zsh" && shell != "fish" {
+			return opts, fmt.Errorf("cannot detect a supported shell from $SHELL=%q; pass bash, zsh, or fish", os.Getenv("SHELL"))
+		}
+		opts.Shell = shell
+	}
+	return opts, nil
+}
+
+func runCompletion(opts globalOptions, args []string) int {
+	completionOpts, err := parseCompletionArgs(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	script := completionScript(completionOpts.Shell)
+	if !completionOpts.Install {
+		fmt.Fprint(stdout, script)
+		return exitOK
+	}
+
+	plan, err := completionInstallPlan(completionOpts.Shell)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	actions, err := installCompletion(plan, script, completionOpts.DryRun)
+	for _, action := range actions {
+		fmt.Fprintln(stdout, action)
+	}
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	if !completionOpts.DryRun {
+		fmt.Fprintf(chatter(opts, stdout), "to reload: %s\n", plan.Reload)
+	}
+	return exitOK
+}
+
+func completionInstallPlan(shell string) (completionInstall, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return completionInstall{}, err
+	}
+	envDir := func(key string, fallback ...string) string {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+		return filepath.Join(append([]string{home}, fallback...)...)
+	}
+
+	switch shell {
+	case "bash":
+		path := filepath.Join(envDir("XDG_DATA_HOME", ".local", "share"), "bash-completion", "completions", "pictl")
+		return completionInstall{
+			Shell:      shell,
+			ScriptPath: path,
+			Reload:     fmt.Sprintf("open a new shell (bash-completion loads it on demand), or run: source %s", path),
+		}, nil
+	case "zsh":
+		return completionInstall{
+			Shell:      shell,
+			ScriptPath: filepath.Join(home, ".zsh", "completions", "_pictl"),
+			RCPath:     filepath.Join(envDir("ZDOTDIR"), ".zshrc"),
+			RCBlock:    completionRCMarker + "\nfpath=(~/.zsh/completions $fpath)\n",
+			Reload:     "exec zsh (if nothing in your .zshrc runs compinit yet, add: autoload -Uz compinit && compinit)",
+		}, nil
+	case "fish":
+		return completionInstall{
+			Shell:      shell,
+			ScriptPath: filepath.Join(envDir("XDG_CONFIG_HOME", ".config"), "fish", "completions", "pictl.fish"),
+			Reload:     "open a new fish shell (fish loads completions on demand)",
+		}, nil
+	}
+	return completionInstall{}, fmt.Errorf("unsupported shell %q", shell)
+}
+
+func installCompletion(plan completionInstall, script string, dryRun bool) ([]string, error) {
+	var actions []string
+	existing, err := os.ReadFile(plan.ScriptPath)
+	switch {
+	case err == nil && bytes.Equal(existing, []byte(script)):
+		actions = append(actions, fmt.Sprintf("unchanged %s (already up to date)", plan.ScriptPath))
+	case err != nil && !os.IsNotExist(err):
+		return actions, err
+	case dryRun:
+		actions = append(actions, fmt.Sprintf("would write %s", plan.ScriptPath))
+	default:
+		if err := os.MkdirAll(filepath.Dir(plan.ScriptPath), 0o755); err != nil {
+			return actions, err
+		}
+		if err := os.WriteFile(plan.ScriptPath, []byte(script), 0o644); err != nil {
+			return actions, err
+		}
+		actions = append(actions, fmt.Sprintf("wrote %s", plan.ScriptPath))
+	}
+
+	if plan.RCPath == "" {
+		return actions, nil
+	}
+	rc, err := os.ReadFile(plan.RCPath)
+	if err != nil && !os.IsNotExist(err) {
+		return actions, err
+	}
+	if bytes.Contains(rc, []byte(completionRCMarker)) {
+		return append(actions, fmt.Sprintf("unchanged %s (already loads pictl completion)", plan.RCPath)), nil
+	}
+	if dryRun {
+		return append(actions, fmt.Sprintf("would append to %s:\n%s", plan.RCPath, indentLines(plan.RCBlock))), nil
+	}
+
+	block := plan.RCBlock
+	if len(rc) > 0 && !bytes.HasSuffix(rc, []byte("\n")) {
+		block = "\n" + block
+	}
+	file, err := os.OpenFile(plan.RCPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return actions, err
+	}
+	if _, err := file.WriteString(block); err != nil {
+		file.Close()
+		return actions, err
+	}
+	if err := file.Close(); err != nil {
+		return actions, err
+	}
+	return append(actions, fmt.Sprintf("appended to %s:\n%s", plan.RCPath, indentLines(plan.RCBlock))), nil
+}
+
+func indentLines(text string) string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	return "  " + strings.Join(lines, "\n  ")
+}
+
+func completionFlags() []string {
+	flags := append([]string{}, completionBoolFlags...)
+	for flag := range valueFlags {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+func fishQuote(value string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(value, `\`, `\\`), "'", `\'`) + "'"
+}
+
+func completionScript(shell string) string {
+	commands := strings.Join(completionCommands, " ")
+	flags := strings.Join(completionFlags(), " ")
+	var paths []string
+	for flag := range completionPathFlags {
+		paths = append(paths, flag)
+	}
+	sort.Strings(paths)
+	pathFlags := strings.Join(paths, "|")
+
+	switch shell {
+	case "zsh":
+		return fmt.Sprintf(`#compdef pictl
+
+_pictl() {
+	local -a commands flags
+	commands=(%s)
+	flags=(%s)
+	case ${words[CURRENT-1]} in
+	%s)
+		_files
+		return
+		;;
+	--profile|--append-profile)
+		compadd -- ${(f)"$(%s)"}
+		return
+		;;
+	slice|rm-slice|enable-slice|disable-slice)
+		compadd -- ${(f)"$(%s)"}
+		return
+		;;
+	completion)
+		compadd -- bash zsh fish --install --dry-run
+		return
+		;;
+	esac
+	if [[ $PREFIX == -* ]]; then
+		compadd -- $flags
+	else
+		compadd -- $commands ${(f)"$(%s)"}
+	fi
+}
+
+if [ "$funcstack[1]" = "_pictl" ]; then
+	_pictl "$@"
+else
+	compdef _pictl pictl
+fi
+`, commands, flags, pathFlags, completionProfiles, completionSlices, completionTargets)
+	case "fish":
+		var out strings.Builder
+		out.WriteString("complete -c pictl -f\n")
+		fmt.Fprintf(&out, "complete -c pictl -n __fish_use_subcommand -a %s\n", fishQuote(commands))
+		fmt.Fprintf(&out, "complete -c pictl -n __fish_use_subcommand -a %s\n", fishQuote("("+completionTargets+")"))
+		fmt.Fprintf(&out, "complete -c pictl -n '__fish_seen_subcommand_from slice rm-slice enable-slice disable-slice' -a %s\n", fishQuote("("+completionSlices+")"))
+		out.WriteString("complete -c pictl -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish --install --dry-run'\n")
+		for _, flag := range completionFlags() {
+			line := "complete -c pictl -l " + strings.TrimPrefix(flag, "--")
+			switch {
+			case completionPathFlags[flag]:
+				line += " -r -F"
+			case flag == "--profile" || flag == "--append-profile":
+				line += " -x -a " + fishQuote("("+completionProfiles+")")
+			case valueFlags[flag] != nil:
+				line += " -x"
+			}
+			out.WriteString(line + "\n")
+		}
+		return out.String()
+	default:
+		return fmt.Sprintf(`# bash completion for pictl
+_pictl() {
+	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+	case $prev in
+	%s)
+		COMPREPLY=($(compgen -f -- "$cur"))
+		return
+		;;
+	--profile|--append-profile)
+		COMPREPLY=($(compgen -W "$(%s)" -- "$cur"))
+		return
+		;;
+	slice|rm-slice|enable-slice|disable-slice)
+		COMPREPLY=($(compgen -W "$(%s)" -- "$cur"))
+		return
+		;;
+	completion)
+		COMPREPLY=($(compgen -W "bash zsh fish --install --dry-run" -- "$cur"))
+		return
+		;;
+	esac
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "%s" -- "$cur"))
+		return
+	fi
+	COMPREPLY=($(compgen -W "%s $(%s)" -- "$cur"))
+}
+complete -o default -F _pictl pictl
+`, pathFlags, completionProfiles, completionSlices, flags, commands, completionTargets)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func fakeCompletionHome(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, key := range []string{"XDG_DATA_HOME", "XDG_CONFIG_HOME", "ZDOTDIR"} {
+		t.Setenv(key, "")
+	}
+	return home
+}
+
+func TestCompletionInstallPlan(t *testing.T) {
+	home := fakeCompletionHome(t)
+
+	cases := map[string]struct{ script, rc string }{
+		"bash": {filepath.Join(home, ".local", "share", "bash-completion", "completions", "pictl"), ""},
+		"zsh":  {filepath.Join(home, ".zsh", "completions", "_pictl"), filepath.Join(home, ".zshrc")},
+		"fish": {filepath.Join(home, ".config", "fish", "completions", "pictl.fish"), ""},
+	}
+	for shell, want := range cases {
+		plan, err := completionInstallPlan(shell)
+		if err != nil || plan.ScriptPath != want.script || plan.RCPath != want.rc {
+			t.Fatalf("%s: unexpected plan %+v (%v)", shell, plan, err)
+		}
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+	t.Setenv("ZDOTDIR", filepath.Join(home, "zdot"))
+	if plan, _ := completionInstallPlan("fish"); plan.ScriptPath != filepath.Join(home, "cfg", "fish", "completions", "pictl.fish") {
+		t.Fatalf("expected XDG_CONFIG_HOME to be honored, got %s", plan.ScriptPath)
+	}
+	if plan, _ := completionInstallPlan("zsh"); plan.RCPath != filepath.Join(home, "zdot", ".zshrc") {
+		t.Fatalf("expected ZDOTDIR to be honored, got %s", plan.RCPath)
+	}
+}
+
+func TestRunCompletionInstallIsIdempotent(t *testing.T) {
+	home := fakeCompletionHome(t)
+	t.Setenv("SHELL", "/usr/bin/zsh")
+	zshrc := filepath.Join(home, ".zshrc")
+	if err := os.WriteFile(zshrc, []byte("export EDITOR=vi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"completion", "--install", "--dry-run"}); code != exitOK {
+		t.Fatalf("expected dry run to succeed, got %d", code)
+	}
+	if !strings.Contains(out.String(), "would write "+filepath.Join(home, ".zsh", "completions", "_pictl")) || !strings.Contains(out.String(), "would append to "+zshrc) {
+		t.Fatalf("expected a dry-run plan:\n%s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(home, ".zsh")); !os.IsNotExist(err) {
+		t.Fatalf("expected --dry-run to write nothing, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		out.Reset()
+		if code := run([]string{"completion", "--install"}); code != exitOK {
+			t.Fatalf("install %d: expected exit %d, got %d", i+1, exitOK, code)
+		}
+	}
+	if !strings.Contains(out.String(), "unchanged "+zshrc) || !strings.Contains(out.String(), "(already up to date)") || !strings.Contains(out.String(), "to reload: exec zsh") {
+		t.Fatalf("expected the second install to change nothing:\n%s", out.String())
+	}
+	rc, err := os.ReadFile(zshrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rc) != "export EDITOR=vi\n"+completionRCMarker+"\nfpath=(~/.zsh/completions $fpath)\n" {
+		t.Fatalf("expected exactly one fpath-only completion block after the existing config, got:\n%s", rc)
+	}
+	if !strings.Contains(out.String(), "autoload -Uz compinit && compinit") {
+		t.Fatalf("expected the reload hint to mention compinit:\n%s", out.String())
+	}
+	script, err := os.ReadFile(filepath.Join(home, ".zsh", "completions", "_pictl"))
+	if err != nil || !strings.HasPrefix(string(script), "#compdef pictl\n") {
+		t.Fatalf("expected the zsh completion script, got %q (%v)", script, err)
+	}
+}
+
+func TestCompletionScriptsCoverUsage(t *testing.T) {
+	var usage strings.Builder
+	printUsage(&usage)
+	_, globals, _ := strings.Cut(usage.String(), "Global flags:")
+
+	var flags []string
+	for _, line := range strings.Split(globals, "\n") {
+		if strings.HasPrefix(line, "  --") {
+			flags = append(flags, strings.Fields(line)[0])
+		}
+	}
+	if len(flags) == 0 {
+		t.Fatal("expected global flags in usage")
+	}
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		script := completionScript(shell)
+		for _, flag := range flags {
+			word := flag
+			if shell == "fish" {
+				word = "-l " + strings.TrimPrefix(flag, "--") + "\n"
+			}
+			if !strings.Contains(script, word) && !strings.Contains(script, strings.TrimSuffix(word, "\n")+" ") {
+				t.Fatalf("%s completion is missing %s", shell, flag)
+			}
+		}
+		for _, command := range []string{"watch", "replay", "completion", "explain"} {
+			if !strings.Contains(script, command) {
+				t.Fatalf("%s completion is missing the %s command", shell, command)
+			}
+		}
+	}
+
+	_, errOut := captureOutput(t)
+	t.Setenv("SHELL", "/bin/tcsh")
+	if code := run([]string{"completion"}); code != exitUsage || !strings.Contains(errOut.String(), "pass bash, zsh, or fish") {
+		t.Fatalf("expected an undetectable shell to be a usage error, got %d: %s", code, errOut.String())
+	}
+}
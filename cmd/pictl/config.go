@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+type configReport struct {
+	Root            string                  `json:"root,omitempty"`
+	RootSource      controlplane.RootSource `json:"rootSource,omitempty"`
+	RootError       string                  `json:"rootError,omitempty"`
+	RootTrace       []string                `json:"rootTrace"`
+	RootMarkers     []string                `json:"rootMarkers"`
+	MarkerMode      string                  `json:"markerMode"`
+	RootSubdirs     []string                `json:"rootSubdirs,omitempty"`
+	HomeCandidates  []string                `json:"homeCandidates"`
+	SliceDir        string                  `json:"sliceDir,omitempty"`
+	EnvFiles        []string                `json:"envFiles,omitempty"`
+	EnvProfilesFile string                  `json:"envProfilesFile,omitempty"`
+	EnvProfiles     []string                `json:"envProfiles,omitempty"`
+	EnvProfile      string                  `json:"envProfile,omitempty"`
+	TargetCatalog   string                  `json:"targetCatalog"`
+	Targets         int                     `json:"targets"`
+	ProfileCatalog  string                  `json:"profileCatalog"`
+	Profiles        []controlplane.Profile  `json:"profiles"`
+	StateFile       string                  `json:"stateFile,omitempty"`
+	SavedProfiles   map[string]string       `json:"savedProfiles,omitempty"`
+	Picker          string                  `json:"picker"`
+}
+
+func runConfig(opts globalOptions, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(stderr, "error: unknown config argument %q\n", args[0])
+		return exitUsage
+	}
+
+	report, rootErr := buildConfigReport(opts)
+	if opts.JSON {
+		if code := writeJSON(report); code != exitOK {
+			return code
+		}
+	} else {
+		printConfigReport(report)
+	}
+	if rootErr != nil {
+		return exitCodeForError(rootErr)
+	}
+	return exitOK
+}
+
+func buildConfigReport(opts globalOptions) (configReport, error) {
+	report := configReport{
+		RootTrace:      []string{},
+		RootSubdirs:    controlplane.RootSubdirs(),
+		HomeCandidates: controlplane.HomeRootCandidates(),
+		EnvProfile:     opts.EnvProfile,
+		TargetCatalog:  controlplane.TargetCatalogPath(),
+		Targets:        len(controlplane.CanonicalTargets()),
+		ProfileCatalog: controlplane.ProfileCatalogPath(),
+		Profiles:       controlplane.CanonicalProfiles(),
+		Picker:         pickerName(opts.Picker),
+	}
+	markers, mode := controlplane.RootMarkers()
+	report.RootMarkers, report.MarkerMode = markers, "all"
+	if mode == controlplane.MarkersAny {
+		report.MarkerMode = "any"
+	}
+	if report.ProfileCatalog == "" {
+		report.ProfileCatalog = "built-in"
+	}
+	if report.TargetCatalog == "" {
+		report.TargetCatalog = "built-in"
+	}
+
+	if path, err := controlplane.StatePath(); err == nil {
+		report.StateFile = path
+		if state, err := controlplane.LoadUserState(path); err == nil {
+			report.SavedProfiles = state.Profiles
+		} else {
+			logger.Warn("could not read user state", "path", path, "error", err)
+		}
+	}
+
+	root, source, err := controlplane.ResolveRoot(opts.Root, func(message string) {
+		report.RootTrace = append(report.RootTrace, message)
+	})
+	if err != nil {
+		report.RootError = err.Error()
+		return report, err
+	}
+	report.Root, report.RootSource = root, source
+	report.SliceDir = controlplane.SliceDir(root)
+
+	switch {
+	case opts.NoEnvFile:
+	case len(opts.EnvFiles) > 0:
+		report.EnvFiles = opts.EnvFiles
+	default:
+		if path := filepath.Join(root, controlplane.DotenvFile); fileExists(path) {
+			report.EnvFiles = []string{path}
+		}
+	}
+	if path := filepath.Join(root, controlplane.EnvProfilesFile); fileExists(path) {
+		report.EnvProfilesFile = path
+		if profiles, err := controlplane.LoadEnvProfiles(root); err == nil {
+			report.EnvProfiles = controlplane.EnvProfileNames(profiles)
+		} else {
+			logger.Warn("could not read env profiles", "path", path, "error", err)
+		}
+	}
+	return report, nil
+}
+
+func printConfigReport(report configReport) {
+	orNone := func(value string) string {
+		if value == "" {
+			return "(none)"
+		}
+		return value
+	}
+
+	if report.Root != "" {
+		fmt.Fprintf(stdout, "root:             %s (from %s)\n", report.Root, report.RootSource)
+	} else {
+		fmt.Fprintf(stdout, "root:             (not found) %s\n", report.RootError)
+	}
+	for _, message := range report.RootTrace {
+		fmt.Fprintf(stdout, "  %s\n", message)
+	}
+	fmt.Fprintf(stdout, "root markers:     %s of %s\n", report.MarkerMode, strings.Join(report.RootMarkers, ", "))
+	if len(report.RootSubdirs) > 0 {
+		fmt.Fprintf(stdout, "nested dirs:      %s\n", strings.Join(report.RootSubdirs, ", "))
+	}
+	fmt.Fprintf(stdout, "home candidates:  %s\n", strings.Join(report.HomeCandidates, ", "))
+	if report.SliceDir != "" {
+		fmt.Fprintf(stdout, "slice dir:        %s\n", report.SliceDir)
+	}
+	fmt.Fprintf(stdout, "env files:        %s\n", orNone(strings.Join(report.EnvFiles, ", ")))
+	fmt.Fprintf(stdout, "env profiles:     %s", orNone(report.EnvProfilesFile))
+	if len(report.EnvProfiles) > 0 {
+		fmt.Fprintf(stdout, " (%s)", strings.Join(report.EnvProfiles, ", "))
+	}
+	fmt.Fprintln(stdout)
+	if report.EnvProfile != "" {
+		fmt.Fprintf(stdout, "env profile:      %s\n", report.EnvProfile)
+	}
+	fmt.Fprintf(stdout, "target catalog:   %s (%d targets)\n", report.TargetCatalog, report.Targets)
+	fmt.Fprintf(stdout, "profile catalog:  %s\n", report.ProfileCatalog)
+	for _, profile := range report.Profiles {
+		fmt.Fprintf(stdout, "  %-10s aliases=%s\n", profile.Name, strings.Join(profile.Aliases, ","))
+	}
+	fmt.Fprintf(stdout, "state file:       %s\n", orNone(report.StateFile))
+	targets := make([]string, 0, len(report.SavedProfiles))
+	for target := range report.SavedProfiles {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		fmt.Fprintf(stdout, "  %-10s profile=%s\n", target, report.SavedProfiles[target])
+	}
+	fmt.Fprintf(stdout, "picker:           %s\n", report.Picker)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+func runConfigJSON(t *testing.T, args []string, wantCode int) configReport {
+	t.Helper()
+
+	out, _ := captureOutput(t)
+	if code := run(append(args, "--json", "config")); code != wantCode {
+		t.Fatalf("expected exit %d, got %d", wantCode, code)
+	}
+	var report configReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("config --json: %v\n%s", err, out.String())
+	}
+	return report
+}
+
+func TestRunConfigJSONRootFromFlag(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	if err := os.WriteFile(filepath.Join(root, controlplane.EnvProfilesFile), []byte(`{"work": {"A": "1"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := runConfigJSON(t, []string{"--root", root, "--env-profile", "work"}, exitOK)
+	if report.Root != root || report.RootSource != controlplane.RootFromFlag {
+		t.Fatalf("expected root %s from --root, got %s from %q", root, report.Root, report.RootSource)
+	}
+	if report.EnvProfilesFile != filepath.Join(root, controlplane.EnvProfilesFile) || strings.Join(report.EnvProfiles, ",") != "work" || report.EnvProfile != "work" {
+		t.Fatalf("unexpected env profile fields: %+v", report)
+	}
+	if report.ProfileCatalog != "built-in" || len(report.Profiles) == 0 {
+		t.Fatalf("expected the built-in profile catalog, got %q with %d profiles", report.ProfileCatalog, len(report.Profiles))
+	}
+	if report.Picker != "numeric" || len(report.EnvFiles) != 0 {
+		t.Fatalf("unexpected picker %q or env files %v", report.Picker, report.EnvFiles)
+	}
+}
+
+func TestRunConfigJSONRootFromWorkingDirectory(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	t.Setenv("PI_AGENT_CONFIG_ROOT", "")
+	t.Chdir(filepath.Join(root, "extensions"))
+
+	report := runConfigJSON(t, nil, exitOK)
+	if report.Root != root || report.RootSource != controlplane.RootFromWorkdir {
+		t.Fatalf("expected root %s from the working directory, got %s from %q", root, report.Root, report.RootSource)
+	}
+	if len(report.RootTrace) == 0 {
+		t.Fatal("expected the discovery trace in the report")
+	}
+}
+
+func TestRunConfigReportsMissingRoot(t *testing.T) {
+	t.Setenv("PICTL_STATE_FILE", filepath.Join(t.TempDir(), "state.json"))
+
+	report := runConfigJSON(t, []string{"--root", t.TempDir()}, exitRootNotFound)
+	if report.Root != "" || report.RootError == "" || len(report.HomeCandidates) == 0 {
+		t.Fatalf("expected a root error with candidates, got %+v", report)
+	}
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"config", "extra"}); code != exitUsage {
+		t.Fatalf("expected usage exit for an extra argument, got %d", code)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected no report on usage error:\n%s", out.String())
+	}
+}
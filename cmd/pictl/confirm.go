@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+var isInteractive = controlplane.IsTTY
+
+func confirmTarget(opts globalOptions, target controlplane.Target) bool {
+	if !target.Confirm || opts.Yes || !isInteractive() {
+		return true
+	}
+	fmt.Fprintf(stderr, "Launch %s? [y/N] ", target.Name)
+	line, _ := bufio.NewReader(stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	fmt.Fprintf(stderr, "pictl: not launching %s\n", target.Name)
+	return false
+}
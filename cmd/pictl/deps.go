@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+func runDoctorDeps(opts globalOptions, root string, format string) int {
+	slices, err := controlplane.LoadSliceManifests(root)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	graph := controlplane.DependencyGraph(slices)
+
+	code := exitOK
+	for _, edge := range graph.Edges {
+		if edge.Missing || edge.Cycle {
+			code = exitFailure
+		}
+	}
+
+	switch {
+	case opts.JSON:
+		if jsonCode := writeJSON(graph); jsonCode != exitOK {
+			return jsonCode
+		}
+	case format == "dot":
+		fmt.Fprint(stdout, dependencyDot(graph))
+	default:
+		printDependencyTree(graph)
+	}
+	return code
+}
+
+func printDependencyTree(graph controlplane.SliceGraph) {
+	printed := map[string]bool{}
+	var walk func(name string, depth int, path map[string]bool)
+	walk = func(name string, depth int, path map[string]bool) {
+		printed[name] = true
+		path[name] = true
+		defer delete(path, name)
+		for _, edge := range graph.Includes(name) {
+			indent := strings.Repeat("  ", depth+1)
+			switch {
+			case edge.Missing:
+				fmt.Fprintf(stdout, "%s%s %s missing\n", indent, edge.To, statusMarker(controlplane.CheckFail))
+			case path[edge.To]:
+				fmt.Fprintf(stdout, "%s%s %s cycle\n", indent, edge.To, statusMarker(controlplane.CheckFail))
+			default:
+				fmt.Fprintf(stdout, "%s%s\n", indent, edge.To)
+				walk(edge.To, depth+1, path)
+			}
+		}
+	}
+
+	for _, name := range append(graph.Roots(), graph.Slices...) {
+		if printed[name] {
+			continue
+		}
+		fmt.Fprintln(stdout, name)
+		walk(name, 0, map[string]bool{})
+	}
+	for _, cycle := range graph.Cycles {
+		fmt.Fprintf(stdout, "%s include cycle: %s\n", statusMarker(controlplane.CheckFail), strings.Join(cycle, " -> "))
+	}
+}
+
+func dependencyDot(graph controlplane.SliceGraph) string {
+	var out strings.Builder
+	out.WriteString("digraph slices {\n  rankdir=LR;\n")
+	for _, name := range graph.Slices {
+		fmt.Fprintf(&out, "  %s;\n", strconv.Quote(name))
+	}
+	missing := map[string]bool{}
+	for _, edge := range graph.Edges {
+		attrs := ""
+		switch {
+		case edge.Missing:
+			attrs = " [style=dashed]"
+			missing[edge.To] = true
+		case edge.Cycle:
+			attrs = " [color=red, label=\"cycle\"]"
+		}
+		fmt.Fprintf(&out, "  %s -> %s%s;\n", strconv.Quote(edge.From), strconv.Quote(edge.To), attrs)
+	}
+	for _, name := range graph.Slices {
+		delete(missing, name)
+	}
+	for _, edge := range graph.Edges {
+		if missing[edge.To] {
+			fmt.Fprintf(&out, "  %s [style=dashed, color=red, label=%s];\n", strconv.Quote(edge.To), strconv.Quote(edge.To+" (missing)"))
+			delete(missing, edge.To)
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
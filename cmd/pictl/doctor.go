@@ -0,0 +1,707 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/phaedrus/pi-agent-config/internal/buildinfo"
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+type doctorReport struct {
+	Root    string                     `json:"root"`
+	Targets int                        `json:"targets"`
+	Slices  int                        `json:"slices"`
+	Strict  bool                       `json:"strict"`
+	Since   string                     `json:"since,omitempty"`
+	Changed []string                   `json:"changed,omitempty"`
+	OK      bool                       `json:"ok"`
+	Checks  []controlplane.CheckResult `json:"checks"`
+}
+
+const (
+	typecheckTimeout = 60 * time.Second
+	piHelpTimeout    = 10 * time.Second
+	networkTimeout   = 5 * time.Second
+)
+
+type doctorOptions struct {
+	Fix             bool
+	Write           bool
+	CheckExtensions bool
+	Typecheck       bool
+	RootTrace       bool
+	CheckPiFlags    bool
+	Since           string
+	WriteReport     string
+	RepairAliases   bool
+	Benchmark       bool
+	CheckPerms      bool
+	CheckCwd        bool
+	CheckFormatting bool
+	Network         bool
+	JSONSchema      bool
+	Out             string
+	FixExtensions   bool
+	FixSchema       bool
+	CompareLockfile string
+	Deps            bool
+	Format          string
+}
+
+func parseDoctorArgs(args []string) (doctorOptions, error) {
+	opts := doctorOptions{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if value, ok := strings.CutPrefix(arg, "--since="); ok {
+			opts.Since = value
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--format="); ok {
+			opts.Format = value
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--compare-lockfile="); ok {
+			opts.CompareLockfile = value
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--write-report="); ok {
+			opts.WriteReport = value
+			continue
+		}
+		switch arg {
+		case "--fix":
+			opts.Fix = true
+		case "--fix-extensions":
+			opts.FixExtensions = true
+		case "--fix-schema-version":
+			opts.FixSchema = true
+		case "--deps":
+			opts.Deps = true
+		case "--format":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("--format requires tree or dot")
+			}
+			i++
+			opts.Format = args[i]
+		case "--write":
+			opts.Write = true
+		case "--check-extensions":
+			opts.CheckExtensions = true
+		case "--typecheck":
+			opts.CheckExtensions = true
+			opts.Typecheck = true
+		case "--root-trace":
+			opts.RootTrace = true
+		case "--repair-aliases":
+			opts.RepairAliases = true
+		case "--benchmark":
+			opts.Benchmark = true
+		case "--check-pi-flags":
+			opts.CheckPiFlags = true
+		case "--check-permissions":
+			opts.CheckPerms = true
+		case "--check-cwd":
+			opts.CheckCwd = true
+		case "--check-json-formatting":
+			opts.CheckFormatting = true
+		case "--network":
+			opts.Network = true
+		case "--json-schema":
+			opts.JSONSchema = true
+		case "--out":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("--out requires a path")
+			}
+			i++
+			opts.Out = args[i]
+		case "--since":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("--since requires a duration or git ref")
+			}
+			i++
+			opts.Since = args[i]
+		case "--compare-lockfile":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("--compare-lockfile requires a path")
+			}
+			i++
+			opts.CompareLockfile = args[i]
+		case "--write-report":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("--write-report requires a path")
+			}
+			i++
+			opts.WriteReport = args[i]
+		default:
+			return opts, fmt.Errorf("unknown doctor flag %q", arg)
+		}
+	}
+	var modes, checks []string
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"--fix", opts.Fix},
+		{"--fix-extensions", opts.FixExtensions},
+		{"--fix-schema-version", opts.FixSchema},
+		{"--deps", opts.Deps},
+		{"--json-schema", opts.JSONSchema},
+		{"--root-trace", opts.RootTrace},
+		{"--repair-aliases", opts.RepairAliases},
+		{"--benchmark", opts.Benchmark},
+	} {
+		if flag.set {
+			modes = append(modes, flag.name)
+		}
+	}
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"--typecheck", opts.Typecheck},
+		{"--check-extensions", opts.CheckExtensions},
+		{"--check-pi-flags", opts.CheckPiFlags},
+		{"--check-permissions", opts.CheckPerms},
+		{"--check-cwd", opts.CheckCwd},
+		{"--check-json-formatting", opts.CheckFormatting},
+		{"--network", opts.Network},
+		{"--compare-lockfile", opts.CompareLockfile != ""},
+		{"--since", opts.Since != ""},
+		{"--write-report", opts.WriteReport != ""},
+	} {
+		if flag.set {
+			checks = append(checks, flag.name)
+		}
+	}
+	if len(modes) > 1 {
+		return opts, fmt.Errorf("%s cannot be combined with %s", modes[0], modes[1])
+	}
+	if len(modes) == 1 && len(checks) > 0 {
+		return opts, fmt.Errorf("%s cannot be combined with %s", modes[0], checks[0])
+	}
+	if opts.Out != "" && !opts.JSONSchema {
+		return opts, fmt.Errorf("--out requires --json-schema")
+	}
+	if opts.Format != "" && !opts.Deps {
+		return opts, fmt.Errorf("--format requires --deps")
+	}
+	if opts.Format != "" && opts.Format != "tree" && opts.Format != "dot" {
+		return opts, fmt.Errorf("invalid --format %q (want tree or dot)", opts.Format)
+	}
+	if opts.Write && !opts.Fix && !opts.FixExtensions && !opts.FixSchema {
+		return opts, fmt.Errorf("--write requires --fix, --fix-extensions, or --fix-schema-version")
+	}
+	if opts.Since != "" && opts.CompareLockfile != "" {
+		return opts, fmt.Errorf("--since cannot be combined with --compare-lockfile (the lockfile covers every slice)")
+	}
+	return opts, nil
+}
+
+func runDoctor(opts globalOptions, args []string) int {
+	doctorOpts, err := parseDoctorArgs(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+
+	if doctorOpts.JSONSchema {
+		return runDoctorJSONSchema(opts, doctorOpts.Out)
+	}
+	if doctorOpts.RootTrace {
+		return runRootTrace(opts)
+	}
+	if doctorOpts.RepairAliases {
+		return runRepairAliases(opts, controlplane.CanonicalTargets())
+	}
+	if doctorOpts.Benchmark {
+		return runDoctorBenchmark(opts)
+	}
+
+	root, err := determineRoot(opts)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+
+	if doctorOpts.Fix {
+		return runDoctorFix(opts, root, doctorOpts.Write)
+	}
+	if doctorOpts.FixExtensions {
+		return runDoctorFixExtensions(opts, root, doctorOpts.Write)
+	}
+	if doctorOpts.FixSchema {
+		return runDoctorFixSchemaVersion(opts, root, doctorOpts.Write)
+	}
+	if doctorOpts.Deps {
+		return runDoctorDeps(opts, root, doctorOpts.Format)
+	}
+
+	var results []controlplane.CheckResult
+	var changed []string
+	slices, err := controlplane.LoadSlices(root)
+	switch {
+	case errors.Is(err, controlplane.ErrNoSlicesDir):
+		results = []controlplane.CheckResult{{Name: "slices-dir", Status: controlplane.CheckFail, Message: err.Error()}}
+	case err != nil:
+		return exitCodeForError(err)
+	case doctorOpts.Since != "":
+		changed, results, err = diagnoseChanged(root, slices, doctorOpts.Since)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitUsage
+		}
+		slices = selectSlices(slices, changed)
+	default:
+		results = controlplane.Diagnose(root, slices)
+	}
+	if doctorOpts.CheckExtensions {
+		results = append(results, controlplane.CheckExtensionFiles(root, slices)...)
+	}
+	if doctorOpts.Typecheck {
+		results = append(results, controlplane.TypecheckExtensions(root, slices, typecheckTimeout)...)
+	}
+	if doctorOpts.CheckPiFlags {
+		results = append(results, controlplane.CheckPiFlags(piHelpTimeout))
+	}
+	if doctorOpts.CheckPerms {
+		results = append(results, controlplane.CheckPermissions(root, slices, permissionConfigFiles(opts))...)
+	}
+	if doctorOpts.CheckCwd {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return exitCodeForError(err)
+		}
+		results = append(results, controlplane.CheckWorkdir(root, cwd))
+	}
+	if doctorOpts.CheckFormatting {
+		results = append(results, controlplane.CheckManifestFormatting(root, slices)...)
+	}
+	if doctorOpts.CompareLockfile != "" {
+		results = append(results, controlplane.CheckLockfile(doctorOpts.CompareLockfile, root, slices)...)
+	}
+	if doctorOpts.Network {
+		env, err := controlplane.LaunchEnv(root, launchOptions(opts, nil))
+		if err != nil {
+			return exitCodeForError(err)
+		}
+		results = append(results, controlplane.CheckNetwork(env, networkTimeout))
+	}
+	if opts.Strict {
+		results = controlplane.PromoteWarnings(results)
+	}
+	failed := false
+	for _, result := range results {
+		if result.Status == controlplane.CheckFail {
+			failed = true
+		}
+	}
+
+	code := exitOK
+	if failed {
+		code = exitFailure
+	}
+
+	report := doctorReport{
+		Root:    root,
+		Targets: len(controlplane.CanonicalTargets()),
+		Slices:  len(slices),
+		Strict:  opts.Strict,
+		Since:   doctorOpts.Since,
+		Changed: changed,
+		OK:      !failed,
+		Checks:  results,
+	}
+	if doctorOpts.WriteReport != "" {
+		if err := writeDoctorReport(doctorOpts.WriteReport, report, time.Now()); err != nil {
+			fmt.Fprintf(stderr, "error: write report: %v\n", err)
+			code = exitFailure
+		}
+	}
+
+	if opts.JSON {
+		if jsonCode := writeJSON(report); jsonCode != exitOK {
+			return jsonCode
+		}
+		return code
+	}
+
+	info := chatter(opts, stdout)
+	fmt.Fprintf(info, "root: %s\n", root)
+	fmt.Fprintf(info, "targets: %d\n", len(controlplane.CanonicalTargets()))
+	if doctorOpts.Since != "" {
+		fmt.Fprintf(info, "changed slices since %s: %d\n", doctorOpts.Since, len(slices))
+	} else {
+		fmt.Fprintf(info, "slices: %d\n", len(slices))
+	}
+	if opts.Strict {
+		fmt.Fprintln(info, "strict: warnings count as failures")
+	}
+	if opts.Profile != "" {
+		fmt.Fprintf(info, "profile override: %s\n", opts.Profile)
+	}
+	if env := os.Getenv("PI_AGENT_CONFIG_ROOT"); env != "" {
+		fmt.Fprintf(info, "env PI_AGENT_CONFIG_ROOT: %s\n", env)
+	}
+	for _, result := range results {
+		if opts.Quiet {
+			if result.Status == controlplane.CheckFail {
+				fmt.Fprintf(stderr, "error: %s: %s\n", result.Name, result.Message)
+			}
+			continue
+		}
+		fmt.Fprintf(stdout, "%s %s: %s\n", statusMarker(result.Status), result.Name, result.Message)
+	}
+	return code
+}
+
+func permissionConfigFiles(opts globalOptions) []string {
+	var files []string
+	if path, err := controlplane.StatePath(); err == nil {
+		files = append(files, path)
+	}
+	for _, path := range append(append([]string{}, opts.EnvFiles...), controlplane.ProfileCatalogPath()) {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+type doctorFileReport struct {
+	GeneratedAt  string `json:"generatedAt"`
+	PictlVersion string `json:"pictlVersion"`
+	doctorReport
+}
+
+func writeDoctorReport(path string, report doctorReport, now time.Time) error {
+	raw, err := json.MarshalIndent(doctorFileReport{
+		GeneratedAt:  now.UTC().Format(time.RFC3339),
+		PictlVersion: buildinfo.Current().Version,
+		doctorReport: report,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
+
+func diagnoseChanged(root string, slices map[string]controlplane.SliceManifest, since string) ([]string, []controlplane.CheckResult, error) {
+	files, method, err := controlplane.ChangedSliceFiles(root, since, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	changed := make([]string, 0, len(files))
+	for _, file := range files {
+		changed = append(changed, file.Name)
+	}
+	if len(changed) == 0 {
+		return changed, []controlplane.CheckResult{{Name: "changed-slices", Status: controlplane.CheckOK, Message: fmt.Sprintf("no slice files changed since %s (by %s)", since, method)}}, nil
+	}
+
+	subset := selectSlices(slices, changed)
+	results := []controlplane.CheckResult{{Name: "changed-slices", Status: controlplane.CheckOK, Message: fmt.Sprintf("%s (by %s)", strings.Join(changed, ", "), method)}}
+	results = append(results, controlplane.CheckSliceDescriptions(subset))
+	results = append(results, controlplane.CheckSliceExtensions(root, subset)...)
+	return changed, results, nil
+}
+
+func selectSlices(slices map[string]controlplane.SliceManifest, names []string) map[string]controlplane.SliceManifest {
+	out := make(map[string]controlplane.SliceManifest, len(names))
+	for _, name := range names {
+		if manifest, ok := slices[name]; ok {
+			out[name] = manifest
+		}
+	}
+	return out
+}
+
+func runRepairAliases(opts globalOptions, targets []controlplane.Target) int {
+	repairs := controlplane.AliasRepairs(targets)
+	code := exitOK
+	if len(repairs) > 0 {
+		code = exitFailure
+	}
+
+	if opts.JSON {
+		if repairs == nil {
+			repairs = []controlplane.AliasRepair{}
+		}
+		if jsonCode := writeJSON(repairs); jsonCode != exitOK {
+			return jsonCode
+		}
+		return code
+	}
+
+	if len(repairs) == 0 {
+		fmt.Fprintf(chatter(opts, stdout), "%s no alias collisions across %d targets; nothing to repair\n", statusMarker(controlplane.CheckOK), len(targets))
+		return exitOK
+	}
+	for _, repair := range repairs {
+		if repair.Alias == "" {
+			fmt.Fprintf(stdout, "%s empty name or alias on %s\n", statusMarker(controlplane.CheckFail), strings.Join(repair.Claimants, ", "))
+		} else {
+			fmt.Fprintf(stdout, "%s alias %q resolves to %s; also claimed by %s\n", statusMarker(controlplane.CheckFail), repair.Alias, repair.Winner, strings.Join(repair.Claimants[1:], ", "))
+		}
+		for _, suggestion := range repair.Suggestions {
+			fmt.Fprintf(stdout, "  suggest: %s\n", suggestion)
+		}
+	}
+	if path := controlplane.TargetCatalogPath(); path != "" {
+		fmt.Fprintf(chatter(opts, stdout), "apply these in %s\n", path)
+	} else {
+		fmt.Fprintln(chatter(opts, stdout), "targets are built into pictl, so apply these in the target catalog source")
+	}
+	return code
+}
+
+func runRootTrace(opts globalOptions) int {
+	markers, mode := controlplane.RootMarkers()
+	require := "all of"
+	if mode == controlplane.MarkersAny {
+		require = "any of"
+	}
+	fmt.Fprintf(stdout, "root markers (%s): %s\n", require, strings.Join(markers, ", "))
+	if subdirs := controlplane.RootSubdirs(); len(subdirs) > 0 {
+		fmt.Fprintf(stdout, "nested config dirs (checked first at each level): %s\n", strings.Join(subdirs, ", "))
+	}
+	root, _, err := controlplane.ResolveRoot(opts.Root, func(message string) {
+		fmt.Fprintf(stdout, "  %s\n", message)
+	})
+	if err != nil {
+		fmt.Fprintf(stdout, "%s no root found\n", statusMarker(controlplane.CheckFail))
+		return exitCodeForError(err)
+	}
+	fmt.Fprintf(stdout, "%s root: %s\n", statusMarker(controlplane.CheckOK), root)
+	return exitOK
+}
+
+func runDoctorJSONSchema(opts globalOptions, out string) int {
+	if out == "" {
+		return writeJSON(controlplane.SliceManifestSchema())
+	}
+	raw, err := json.MarshalIndent(controlplane.SliceManifestSchema(), "", "  ")
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	if err := os.WriteFile(out, append(raw, '\n'), 0o644); err != nil {
+		return exitCodeForError(err)
+	}
+	fmt.Fprintf(chatter(opts, stdout), "wrote %s\n", out)
+	return exitOK
+}
+
+func runDoctorFix(opts globalOptions, root string, write bool) int {
+	files, err := controlplane.SliceFiles(root)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+
+	failed := false
+	pending := 0
+	for _, file := range files {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return exitCodeForError(err)
+		}
+		raw, err := os.ReadFile(file.Path)
+		if err != nil {
+			return exitCodeForError(err)
+		}
+
+		fixed, err := controlplane.NormalizeManifest(raw)
+		if errors.Is(err, controlplane.ErrManifestHasComments) {
+			fmt.Fprintf(chatter(opts, stdout), "skipped %s: contains comments (left as-is)\n", file.Path)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "error: refusing to fix slice %s: %v\n", file.Name, err)
+			failed = true
+			continue
+		}
+		if bytes.Equal(raw, fixed) {
+			continue
+		}
+
+		if write {
+			if err := os.WriteFile(file.Path, fixed, info.Mode().Perm()); err != nil {
+				return exitCodeForError(err)
+			}
+			fmt.Fprintf(chatter(opts, stdout), "fixed %s\n", file.Path)
+			continue
+		}
+
+		pending++
+		fmt.Fprintf(stdout, "--- %s\n+++ %s (fixed)\n", file.Path, file.Path)
+		fmt.Fprint(stdout, lineDiff(string(raw), string(fixed)))
+	}
+
+	if pending > 0 {
+		fmt.Fprintf(chatter(opts, stdout), "%d manifest(s) would change; re-run with --fix --write to apply\n", pending)
+	}
+	if failed {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runDoctorFixExtensions(opts globalOptions, root string, write bool) int {
+	files, err := controlplane.SliceFiles(root)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+
+	failed := false
+	pending := 0
+	for _, file := range files {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return exitCodeForError(err)
+		}
+		raw, err := os.ReadFile(file.Path)
+		if err != nil {
+			return exitCodeForError(err)
+		}
+		missing, err := controlplane.MissingExtensionRefs(root, raw)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: slice %s: %v\n", file.Name, err)
+			failed = true
+			continue
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		fmt.Fprintf(stdout, "slice %s: missing %s\n", file.Name, strings.Join(missing, ", "))
+
+		pruned, err := controlplane.PruneManifestExtensions(raw, missing)
+		if errors.Is(err, controlplane.ErrManifestHasComments) {
+			fmt.Fprintf(chatter(opts, stdout), "skipped %s: contains comments (remove the entries by hand)\n", file.Path)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "error: refusing to prune slice %s: %v\n", file.Name, err)
+			failed = true
+			continue
+		}
+
+		if write {
+			if err := os.WriteFile(file.Path, pruned, info.Mode().Perm()); err != nil {
+				return exitCodeForError(err)
+			}
+			fmt.Fprintf(chatter(opts, stdout), "pruned %d extension(s) from %s\n", len(missing), file.Path)
+			continue
+		}
+
+		pending++
+		fmt.Fprintf(stdout, "--- %s\n+++ %s (pruned)\n", file.Path, file.Path)
+		fmt.Fprint(stdout, lineDiff(string(raw), string(pruned)))
+	}
+
+	if pending > 0 {
+		fmt.Fprintf(chatter(opts, stdout), "%d manifest(s) would change; re-run with --fix-extensions --write to apply\n", pending)
+	}
+	if failed {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func lineDiff(before, after string) string {
+	a := strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(after, "\n"), "\n")
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			fmt.Fprintf(&out, " %s\n", a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			fmt.Fprintf(&out, "-%s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&out, "+%s\n", b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		fmt.Fprintf(&out, "-%s\n", a[i])
+	}
+	for ; j < len(b); j++ {
+		fmt.Fprintf(&out, "+%s\n", b[j])
+	}
+	return out.String()
+}
+
+func runDoctorFixSchemaVersion(opts globalOptions, root string, write bool) int {
+	files, err := controlplane.SliceFiles(root)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+
+	failed := false
+	pending := 0
+	for _, file := range files {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return exitCodeForError(err)
+		}
+		raw, err := os.ReadFile(file.Path)
+		if err != nil {
+			return exitCodeForError(err)
+		}
+
+		stamped, err := controlplane.StampSchemaVersion(raw)
+		if errors.Is(err, controlplane.ErrManifestHasComments) {
+			fmt.Fprintf(chatter(opts, stdout), "skipped %s: contains comments (add \"schemaVersion\": %d by hand)\n", file.Path, controlplane.CurrentSchemaVersion)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "error: refusing to stamp slice %s: %v\n", file.Name, err)
+			failed = true
+			continue
+		}
+		if bytes.Equal(raw, stamped) {
+			continue
+		}
+
+		if write {
+			if err := os.WriteFile(file.Path, stamped, info.Mode().Perm()); err != nil {
+				return exitCodeForError(err)
+			}
+			fmt.Fprintf(chatter(opts, stdout), "stamped %s with schemaVersion %d\n", file.Path, controlplane.CurrentSchemaVersion)
+			continue
+		}
+
+		pending++
+		fmt.Fprintf(stdout, "would stamp %s\n", file.Path)
+	}
+
+	if pending > 0 {
+		fmt.Fprintf(chatter(opts, stdout), "%d manifest(s) lack schemaVersion; re-run with --fix-schema-version --write to add %d\n", pending, controlplane.CurrentSchemaVersion)
+	}
+	if failed {
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+func runExplain(opts globalOptions, args []string, forwarded []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "error: explain command requires exactly one target")
+		return exitUsage
+	}
+
+	input := args[0]
+	target, ok := controlplane.ResolveTarget(input)
+	if !ok {
+		return exitCodeForError(fmt.Errorf("%w %q", controlplane.ErrUnknownTarget, input))
+	}
+
+	root, err := determineRoot(opts)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	slices, err := controlplane.LoadSlices(root)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	manifest, sliceName, err := controlplane.LookupTargetSlice(slices, target)
+	if err != nil {
+		return exitCodeForError(fmt.Errorf("target %q: %w", target.Name, err))
+	}
+
+	step := 0
+	say := func(format string, a ...any) {
+		step++
+		fmt.Fprintf(stdout, "%d. %s\n", step, fmt.Sprintf(format, a...))
+	}
+
+	normalized := strings.ToLower(strings.TrimSpace(input))
+	if normalized == target.Name {
+		say("%q is the name of target %q.", input, target.Name)
+	} else {
+		say("%q is an alias of target %q.", input, target.Name)
+	}
+
+	if sliceName != target.Slice {
+		say("Target %q maps to slice %q, which is missing, so it falls back to slice %q, loaded from %s.", target.Name, target.Slice, sliceName, slicePath(root, sliceName))
+	} else {
+		say("Target %q maps to slice %q, loaded from %s.", target.Name, target.Slice, slicePath(root, target.Slice))
+	}
+	launchOpts := launchOptions(opts, forwarded)
+	env, err := controlplane.LaunchEnv(root, launchOpts)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	say("%s", explainProfile(opts, target, manifest, launchOpts.ForwardedArgs, env))
+
+	if opts.Strict {
+		say("Strict mode is on (--strict), so discovered skills, prompt templates, and themes are disabled.")
+	} else {
+		say("Strict mode is off, so Pi still discovers skills, prompt templates, and themes (pass --strict to disable them).")
+	}
+
+	if len(target.DefaultArgs) > 0 {
+		say("Target %q forwards its default Pi args first (%s), so your own args come after and win.", target.Name, shellJoin(target.DefaultArgs))
+	}
+
+	if model := strings.TrimSpace(manifest.Model); model != "" {
+		if controlplane.HasFlag(launchOpts.ForwardedArgs, "--model") {
+			say("Slice %q defaults to model %s, but you forwarded --model, so yours is used.", sliceName, model)
+		} else {
+			say("Slice %q adds --model %s (forward your own --model to override it).", sliceName, model)
+		}
+	}
+
+	spec, err := buildTargetSpec(opts, input, forwarded)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	extensions := extensionArgs(spec.Args)
+	say("Pi is started with --no-extensions plus these %d extension(s):", len(extensions))
+	for _, extension := range extensions {
+		fmt.Fprintf(stdout, "   - %s\n", extension)
+	}
+	for _, warning := range spec.Warnings {
+		fmt.Fprintf(stdout, "   warning: %s\n", warning)
+	}
+	if spec.Dir != "" {
+		say("Pi starts in %s (from --cwd, or else the slice's cwd), so its AGENTS.md lookup begins there.", spec.Dir)
+	} else {
+		say("Pi starts in the current directory (set cwd in the slice or pass --cwd to change that).")
+	}
+	return exitOK
+}
+
+func explainProfile(opts globalOptions, target controlplane.Target, manifest controlplane.SliceManifest, forwarded []string, env []string) string {
+	targetDefault, defaultFrom, err := targetDefaultProfile(target)
+	if err != nil {
+		targetDefault, defaultFrom = target.DefaultProfile, fmt.Sprintf("target %q's default", target.Name)
+	}
+
+	decision := controlplane.DecideProfile(opts.Profile, targetDefault, manifest.DefaultProfile, forwarded, env)
+	explanation := explainProfileSource(decision, defaultFrom, targetDefault)
+	if opts.BranchProfile {
+		var note string
+		if decision, note = controlplane.DecideBranchProfile(decision, "."); decision.Source == controlplane.ProfileFromBranch {
+			explanation = explainProfileSource(decision, defaultFrom, targetDefault)
+		} else {
+			explanation = fmt.Sprintf("%s (%s.)", explanation, note)
+		}
+	}
+	if extra := strings.TrimSpace(opts.AppendProfile); extra != "" {
+		if decision.Source == controlplane.ProfileFromForwarded {
+			return explanation + " --append-profile is ignored because of it."
+		}
+		return fmt.Sprintf("%s --append-profile adds %q, so pictl exports PI_DEFAULT_PROFILE=%s.", explanation, extra, controlplane.AppendProfile(decision.Profile, extra))
+	}
+	return explanation
+}
+
+func explainProfileSource(decision controlplane.ProfileDecision, defaultFrom string, targetDefault string) string {
+	profile := describeProfile(decision.Profile)
+	switch decision.Source {
+	case controlplane.ProfileFromForwarded:
+		return fmt.Sprintf("Profile is %s because --profile is forwarded to Pi after --, which overrides everything pictl would set.", profile)
+	case controlplane.ProfileFromRequest:
+		return fmt.Sprintf("Profile is %s, from the --profile flag, which also beats any inherited PI_DEFAULT_PROFILE (pictl exports it as PI_DEFAULT_PROFILE).", profile)
+	case controlplane.ProfileFromEnv:
+		return fmt.Sprintf("Profile is %s because PI_DEFAULT_PROFILE is already set in the environment (or the root's .env or the selected --env-profile); pictl leaves it alone instead of using %s (%q). Pass --profile to override it.", profile, defaultFrom, targetDefault)
+	case controlplane.ProfileFromTarget:
+		return fmt.Sprintf("Profile is %s, from %s (pictl exports it as PI_DEFAULT_PROFILE).", profile, defaultFrom)
+	case controlplane.ProfileFromSlice:
+		return fmt.Sprintf("Profile is %s, from the slice manifest's defaultProfile.", profile)
+	case controlplane.ProfileFromBranch:
+		return fmt.Sprintf("Profile is %s, from the current git branch via --profile-from-branch (pictl exports it as PI_DEFAULT_PROFILE).", profile)
+	case controlplane.ProfileFromPictlEnv:
+		return fmt.Sprintf("Profile is %s, from %s, the last-resort default used when nothing else sets one (pictl exports it as PI_DEFAULT_PROFILE).", profile, controlplane.PictlProfileEnv)
+	default:
+		return "No profile is set, so Pi uses its own default."
+	}
+}
+
+func describeProfile(name string) string {
+	if profile, ok := controlplane.ResolveProfile(name); ok && profile.Name != strings.ToLower(strings.TrimSpace(name)) {
+		return fmt.Sprintf("%q (alias of %q)", name, profile.Name)
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+func slicePath(root string, name string) string {
+	files, err := controlplane.SliceFiles(root)
+	if err != nil {
+		return controlplane.SliceDir(root)
+	}
+	for _, file := range files {
+		if file.Name == name {
+			return file.Path
+		}
+	}
+	return controlplane.SliceDir(root)
+}
+
+func extensionArgs(args []string) []string {
+	var out []string
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-e" {
+			out = append(out, args[i+1])
+			i++
+		}
+	}
+	return out
+}
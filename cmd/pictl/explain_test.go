@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunExplainProfileBranches(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"software": validSlice})
+
+	cases := []struct {
+		name string
+		env  string
+		args []string
+		want string
+	}{
+		{"target default", "", []string{"explain", "software"}, `Profile is "execute", from target "build"'s default`},
+		{"flag", "", []string{"--profile", "quick", "explain", "build"}, `Profile is "quick" (alias of "fast"), from the --profile flag`},
+		{"env", "ship", []string{"explain", "build"}, `Profile is "ship" because PI_DEFAULT_PROFILE is already set`},
+		{"flag beats env", "ship", []string{"--profile", "fast", "explain", "build"}, `Profile is "fast", from the --profile flag, which also beats any inherited PI_DEFAULT_PROFILE`},
+		{"forwarded", "ship", []string{"explain", "build", "--", "--profile", "meta"}, `Profile is "meta" (alias of "ultrathink") because --profile is forwarded`},
+		{"append", "", []string{"--append-profile", "quick", "explain", "build"}, `--append-profile adds "quick", so pictl exports PI_DEFAULT_PROFILE=execute,fast.`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("PI_DEFAULT_PROFILE", tc.env)
+			out, _ := captureOutput(t)
+			if code := run(append([]string{"--root", root}, tc.args...)); code != exitOK {
+				t.Fatalf("expected exit %d, got %d", exitOK, code)
+			}
+			if !strings.Contains(out.String(), tc.want) {
+				t.Fatalf("expected %q in:\n%s", tc.want, out.String())
+			}
+		})
+	}
+}
+
+func TestRunExplainDescribesChain(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"software": validSlice})
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "--strict", "explain", "software"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	for _, want := range []string{
+		`"software" is an alias of target "build"`,
+		"slices/software.json",
+		"Strict mode is on",
+		"extensions/x.ts",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	if code := run([]string{"--root", root, "explain"}); code != exitUsage {
+		t.Fatalf("expected usage exit without a target, got %d", code)
+	}
+}
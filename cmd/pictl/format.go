@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+var formatEscapes = strings.NewReplacer(`\t`, "\t", `\n`, "\n")
+
+var formatFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"pad": func(width int, value string) string {
+		return fmt.Sprintf("%-*s", width, value)
+	},
+	"default": func(fallback string, value string) string {
+		if strings.TrimSpace(value) == "" {
+			return fallback
+		}
+		return value
+	},
+	"json": func(value any) (string, error) {
+		raw, err := json.Marshal(value)
+		return string(raw), err
+	},
+}
+
+func parseFormat(text string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(formatFuncs).Option("missingkey=error").Parse(formatEscapes.Replace(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format: %w", err)
+	}
+	return tmpl, nil
+}
+
+func renderFormat[T any](tmpl *template.Template, items []T) int {
+	var out bytes.Buffer
+	for _, item := range items {
+		if err := tmpl.Execute(&out, item); err != nil {
+			fmt.Fprintf(stderr, "error: invalid --format: %v\n", err)
+			return exitUsage
+		}
+		out.WriteByte('\n')
+	}
+	_, err := stdout.Write(out.Bytes())
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	return exitOK
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestListFormat(t *testing.T) {
+	out, errOut := captureOutput(t)
+	if code := run([]string{"list", "--category", "ops", "--format", `{{.Name}}\t{{.Slice}}\t{{join .Aliases ","}}`}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if out.String() != "ops\tsysadmin\tsysadmin,admin,argus,guardian\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestSlicesFormat(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"meta":    validSlice,
+		"scratch": `{"enabled": false, "extensions": ["extensions/x.ts"]}`,
+	})
+
+	out, errOut := captureOutput(t)
+	args := []string{"--root", root, "slices", "--all", "--format", `{{pad 8 .Name}}{{default "none" .Manifest.DefaultProfile}} {{.Manifest.IsEnabled}}`}
+	if code := run(args); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if out.String() != "meta    meta true\nscratch none false\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestFormatErrorsBeforeOutput(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+
+	cases := map[string][]string{
+		"parse error":   {"list", "--format", "{{.Name"},
+		"unknown field": {"list", "--format", "{{.Nope}}"},
+		"slices parse":  {"--root", root, "slices", "--format", "{{if}}"},
+		"slices field":  {"--root", root, "slices", "--format", "{{.Manifest.Nope}}"},
+		"with json":     {"--root", root, "--json", "slices", "--format", "{{.Name}}"},
+		"with summary":  {"--root", root, "slices", "--summary", "--format", "{{.Name}}"},
+	}
+	for name, args := range cases {
+		out, errOut := captureOutput(t)
+		if code := run(args); code != exitUsage {
+			t.Fatalf("%s: expected exit %d, got %d", name, exitUsage, code)
+		}
+		if out.Len() != 0 {
+			t.Fatalf("%s: expected no output, got %q", name, out.String())
+		}
+		if !strings.Contains(errOut.String(), "error:") {
+			t.Fatalf("%s: expected an error, got %q", name, errOut.String())
+		}
+	}
+}
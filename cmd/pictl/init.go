@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+type initOptions struct {
+	Name        string
+	Description string
+	Profile     string
+	Extensions  []string
+	Force       bool
+}
+
+var initValueFlags = map[string]func(*initOptions, string){
+	"--name":        func(opts *initOptions, value string) { opts.Name = value },
+	"--description": func(opts *initOptions, value string) { opts.Description = value },
+	"--extensions":  func(opts *initOptions, value string) { opts.Extensions = splitList(value) },
+}
+
+func parseInitArgs(args []string) (initOptions, error) {
+	opts := initOptions{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--force" {
+			opts.Force = true
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(arg, "=")
+		setter, ok := initValueFlags[name]
+		if !ok {
+			return opts, fmt.Errorf("unknown init flag %q", arg)
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		setter(&opts, value)
+	}
+	return opts, nil
+}
+
+func runInit(opts globalOptions, args []string) int {
+	initOpts, err := parseInitArgs(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	if initOpts.Profile == "" {
+		initOpts.Profile = opts.Profile
+	}
+	if initOpts.Name == "" || len(initOpts.Extensions) == 0 {
+		if !controlplane.IsTTY() {
+			fmt.Fprintln(stderr, "error: init requires --name and --extensions when no interactive TTY is available")
+			return exitUsage
+		}
+		if err := promptInit(&initOpts); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	name := strings.TrimSpace(initOpts.Name)
+	if !validSliceName(name) {
+		fmt.Fprintf(stderr, "error: invalid slice name %q (use letters, digits, -, _ and / for nesting)\n", name)
+		return exitUsage
+	}
+
+	root, err := determineRoot(opts)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+
+	manifest := controlplane.SliceManifest{
+		Description:    strings.TrimSpace(initOpts.Description),
+		DefaultProfile: strings.TrimSpace(initOpts.Profile),
+	}
+	for _, extension := range initOpts.Extensions {
+		manifest.Extensions = append(manifest.Extensions, controlplane.ExtensionRef{Path: extension})
+	}
+	raw, err := controlplane.ScaffoldManifest(manifest)
+	if err != nil {
+		return exitCodeForError(fmt.Errorf("slice %s: %w", name, err))
+	}
+
+	target := filepath.Join(controlplane.SliceDir(root), filepath.FromSlash(name)+".json")
+	if _, err := os.Stat(target); err == nil && !initOpts.Force {
+		return exitCodeForError(fmt.Errorf("%s already exists (use --force to overwrite)", target))
+	}
+	if files, err := controlplane.SliceFiles(root); err == nil {
+		for _, file := range files {
+			if file.Name == name && file.Path != target {
+				return exitCodeForError(fmt.Errorf("slice %s already defined in %s", name, file.Path))
+			}
+		}
+	}
+	if _, err := controlplane.ResolveExtensions(root, manifest, controlplane.LaunchOptions{}); err != nil {
+		fmt.Fprintf(chatter(opts, stderr), "warning: %v\n", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return exitCodeForError(err)
+	}
+	if err := os.WriteFile(target, raw, 0o644); err != nil {
+		return exitCodeForError(err)
+	}
+	fmt.Fprintln(stdout, target)
+	return exitOK
+}
+
+func promptInit(opts *initOptions) error {
+	reader := bufio.NewReader(os.Stdin)
+	ask := func(label string, current string) (string, error) {
+		if current != "" {
+			return current, nil
+		}
+		fmt.Fprintf(stdout, "%s: ", label)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+
+	var err error
+	if opts.Name, err = ask("Slice name", opts.Name); err != nil {
+		return err
+	}
+	if opts.Description, err = ask("Description", opts.Description); err != nil {
+		return err
+	}
+	if opts.Profile, err = ask("Default profile (blank for none)", opts.Profile); err != nil {
+		return err
+	}
+	extensions, err := ask("Extensions (comma-separated paths)", strings.Join(opts.Extensions, ","))
+	if err != nil {
+		return err
+	}
+	opts.Extensions = splitList(extensions)
+	return nil
+}
+
+func validSliceName(name string) bool {
+	if name == "" || path.Clean(name) != name || strings.HasPrefix(name, "/") || strings.HasPrefix(name, "..") {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '-' || r == '_' || r == '/' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+func TestRunInitWritesLoadableManifest(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+
+	out, errOut := captureOutput(t)
+	args := []string{"--root", root, "init", "--name", "team/review", "--description", "code review", "--profile", "quick", "--extensions", "extensions/x.ts, extensions/x.ts"}
+	if code := run(args); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	path := filepath.Join(root, "slices", "team", "review.json")
+	if out.String() != path+"\n" {
+		t.Fatalf("expected written path, got %q", out.String())
+	}
+
+	slices, err := controlplane.LoadSlices(root)
+	if err != nil {
+		t.Fatalf("expected generated manifest to load: %v", err)
+	}
+	manifest := slices["team/review"]
+	if manifest.SchemaVersion != controlplane.CurrentSchemaVersion || manifest.DefaultProfile != "quick" || manifest.Description != "code review" {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	raw, _ := os.ReadFile(path)
+	if fixed, err := controlplane.NormalizeManifest(raw); err != nil || string(fixed) != string(raw) {
+		t.Fatalf("expected canonical output that doctor --fix leaves alone, got %s (%v)", raw, err)
+	}
+
+	if code := run(args); code != exitFailure {
+		t.Fatalf("expected refusal to overwrite, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "--force") {
+		t.Fatalf("expected hint about --force, got %q", errOut.String())
+	}
+	if code := run(append(args, "--force")); code != exitOK {
+		t.Fatalf("expected --force to overwrite, got %d", code)
+	}
+}
+
+func TestRunInitValidates(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	captureOutput(t)
+
+	cases := map[string][]string{
+		"unknown profile": {"init", "--name", "x", "--profile", "turbo", "--extensions", "extensions/x.ts"},
+		"bad name":        {"init", "--name", "../x", "--extensions", "extensions/x.ts"},
+		"no extensions":   {"init", "--name", "x"},
+	}
+	for name, args := range cases {
+		if code := run(append([]string{"--root", root}, args...)); code == exitOK {
+			t.Fatalf("%s: expected failure", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "slices", "x.json")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing written for invalid input")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+type lockOptions struct {
+	Write bool
+	Out   string
+}
+
+func parseLockArgs(args []string) (lockOptions, error) {
+	opts := lockOptions{}
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--write":
+			opts.Write = true
+		case "--out":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("--out requires a path")
+			}
+			i++
+			opts.Out = args[i]
+		default:
+			return opts, fmt.Errorf("unknown lock flag %q", arg)
+		}
+	}
+	if opts.Out != "" && !opts.Write {
+		return opts, fmt.Errorf("--out requires --write")
+	}
+	return opts, nil
+}
+
+func runLock(opts globalOptions, args []string) int {
+	lockOpts, err := parseLockArgs(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	root, err := determineRoot(opts)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	slices, err := controlplane.LoadSlices(root)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	lock, err := controlplane.BuildLockfile(root, slices)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	raw, err := controlplane.MarshalLockfile(lock)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	if !lockOpts.Write {
+		if _, err := stdout.Write(raw); err != nil {
+			return exitCodeForError(err)
+		}
+		return exitOK
+	}
+
+	path := lockOpts.Out
+	if path == "" {
+		path = filepath.Join(root, controlplane.LockfileName)
+	}
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, raw) {
+		fmt.Fprintf(chatter(opts, stdout), "unchanged %s\n", path)
+		return exitOK
+	}
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return exitCodeForError(err)
+	}
+	fmt.Fprintf(chatter(opts, stdout), "wrote %s (%d targets, %d slices, %d profiles)\n", path, len(lock.Targets), len(lock.Slices), len(lock.Profiles))
+	return exitOK
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+var logger = slog.New(newTextLogHandler(io.Discard, slog.LevelInfo))
+
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+func newLogger(opts globalOptions) *slog.Logger {
+	level := slog.LevelInfo
+	if opts.Verbose {
+		level = slog.LevelDebug
+	}
+	if opts.LogLevel != "" {
+		level = logLevels[opts.LogLevel]
+	}
+
+	if opts.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(stderr, &slog.HandlerOptions{Level: level}))
+	}
+	return slog.New(newTextLogHandler(stderr, level))
+}
+
+type textLogHandler struct {
+	out   io.Writer
+	level slog.Level
+	attrs []slog.Attr
+}
+
+func newTextLogHandler(out io.Writer, level slog.Level) *textLogHandler {
+	return &textLogHandler{out: out, level: level}
+}
+
+func (h *textLogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textLogHandler) Handle(_ context.Context, record slog.Record) error {
+	var line strings.Builder
+	line.WriteString("pictl: ")
+	if record.Level >= slog.LevelWarn {
+		line.WriteString(strings.ToLower(record.Level.String()) + ": ")
+	}
+	line.WriteString(record.Message)
+
+	write := func(attr slog.Attr) bool {
+		fmt.Fprintf(&line, " %s=%s", attr.Key, shellQuote(attr.Value.String()))
+		return true
+	}
+	for _, attr := range h.attrs {
+		write(attr)
+	}
+	record.Attrs(write)
+	line.WriteByte('\n')
+
+	_, err := io.WriteString(h.out, line.String())
+	return err
+}
+
+func (h *textLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &textLogHandler{out: h.out, level: h.level, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+func (h *textLogHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONLogLinesHaveExpectedKeys(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"software": validSlice})
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--log-format", "json", "--log-level", "debug", "args", "build"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if strings.Contains(out.String(), "level") {
+		t.Fatalf("expected logs to stay off stdout, got %q", out.String())
+	}
+
+	var loaded map[string]any
+	lines := strings.Split(strings.TrimSpace(errOut.String()), "\n")
+	for _, line := range lines {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("expected every stderr line to be JSON, got %q: %v", line, err)
+		}
+		if entry["level"] != "DEBUG" || entry["msg"] == nil {
+			t.Fatalf("expected level and msg keys, got %v", entry)
+		}
+		if entry["msg"] == "loaded slice" {
+			loaded = entry
+		}
+	}
+	if loaded == nil || loaded["target"] != "build" || loaded["slice"] != "software" || loaded["root"] != root {
+		t.Fatalf("expected a loaded slice entry with target, slice and root, got %v", lines)
+	}
+}
+
+func TestTextLogOnlyUnderVerbose(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"software": validSlice})
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "args", "build"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if errOut.Len() != 0 {
+		t.Fatalf("expected no diagnostics without --verbose, got %q", errOut.String())
+	}
+
+	if code := run([]string{"--root", root, "--verbose", "args", "build"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if !strings.Contains(errOut.String(), "pictl: loaded slice target=build slice=software root=") {
+		t.Fatalf("expected text diagnostics under --verbose, got %q", errOut.String())
+	}
+
+	if code := run([]string{"--log-format", "xml", "list"}); code != exitUsage {
+		t.Fatalf("expected usage exit for unknown log format, got %d", code)
+	}
+}
+
+func TestVerboseShowsRootDiscoveryAfterCatalogLoad(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"software": validSlice})
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--verbose", "args", "build"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if !strings.Contains(errOut.String(), "pictl: root from --root: "+root) {
+		t.Fatalf("expected the root discovery steps under --verbose, got %q", errOut.String())
+	}
+}
@@ -2,21 +2,109 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
+	"github.com/phaedrus/pi-agent-config/internal/buildinfo"
 	"github.com/phaedrus/pi-agent-config/internal/controlplane"
 )
 
+const (
+	exitOK           = 0
+	exitFailure      = 1
+	exitUsage        = 2
+	exitRootNotFound = 3
+	exitMissing      = 4
+	exitPiNotFound   = 5
+	exitTimeout      = 124
+)
+
+const (
+	defaultRetryCode  = 75
+	defaultRetryDelay = time.Second
+)
+
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+type listOptions struct {
+	Category        string
+	Format          string
+	All             bool
+	Resolve         []string
+	AllowUnresolved bool
+}
+
+type resolvedTarget struct {
+	Input    string `json:"input"`
+	Target   string `json:"target,omitempty"`
+	Slice    string `json:"slice,omitempty"`
+	Resolved bool   `json:"resolved"`
+}
+
+type slicesOptions struct {
+	All      bool
+	Unused   bool
+	Orphans  bool
+	Summary  bool
+	Format   string
+	Tags     []string
+	TagMatch string
+	Diff     string
+}
+
 type globalOptions struct {
-	Root    string
-	Strict  bool
-	Profile string
-	Help    bool
+	Root             string
+	Strict           bool
+	StrictExtensions bool
+	StrictPaths      bool
+	SkipExtensions   []string
+	OnlyExtensions   []string
+	Profile          string
+	AppendProfile    string
+	BranchProfile    bool
+	Timeout          time.Duration
+	StartupTimeout   time.Duration
+	Retries          int
+	EnvFiles         []string
+	ArgsFile         string
+	FileArgs         []string
+	NoEnvFile        bool
+	SkipRequires     bool
+	Yes              bool
+	ShowHidden       bool
+	Cwd              string
+	StdinFile        string
+	EnvProfile       string
+	Env              []string
+	ProfileFile      string
+	SliceDir         string
+	ExitCodeFile     string
+	TraceLaunch      string
+	Picker           string
+	Index            string
+	RetryCodes       []int
+	JSON             bool
+	PrintCmd         bool
+	Verbose          bool
+	LogFormat        string
+	LogLevel         string
+	Quiet            bool
+	Version          bool
+	NoColor          bool
+	Help             bool
 }
 
 func main() {
@@ -26,68 +114,280 @@ func main() {
 func run(argv []string) int {
 	opts, tokens, forwardedAfterSeparator, err := parseArgs(argv)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		printUsage(os.Stderr)
-		return 2
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		printUsage(stderr)
+		return exitUsage
 	}
+	colorEnabled = shouldColor(opts)
+	logger = newLogger(opts)
+	controlplane.UseRootCache(true)
+	loadTargetCatalog(opts)
 
 	if opts.Help {
-		printUsage(os.Stdout)
-		return 0
+		printUsage(stdout)
+		return exitOK
+	}
+
+	if opts.Version {
+		return printVersion(opts)
+	}
+
+	if opts.ArgsFile != "" {
+		opts.FileArgs, err = controlplane.LoadArgsFile(opts.ArgsFile)
+		if err != nil {
+			return exitCodeForError(err)
+		}
+	}
+
+	controlplane.UseSliceDir(opts.SliceDir)
+	if err := loadProfileCatalog(opts); err != nil {
+		return exitCodeForError(err)
+	}
+	if err := loadBranchProfileRules(opts); err != nil {
+		return exitCodeForError(err)
+	}
+
+	leading, tokens := splitLeadingFlags(tokens)
+	if len(leading) > 0 && opts.Index == "" && len(tokens) > 0 && !isLaunchToken(tokens[0]) {
+		fmt.Fprintf(stderr, "error: unknown flag %q before command %q (pi flags are only forwarded to targets)\n", leading[0], tokens[0])
+		return exitUsage
+	}
+
+	if opts.Index != "" {
+		return runTargetIndex(opts, opts.Index, concatArgs(leading, tokens, forwardedAfterSeparator))
 	}
 
 	if len(tokens) == 0 {
-		target, pickErr := pickTargetInteractive()
+		target, pickErr := pickTarget(opts)
 		if pickErr != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", pickErr)
-			return 2
+			fmt.Fprintf(stderr, "error: %v\n", pickErr)
+			return exitUsage
 		}
-		return runTarget(opts, target, forwardedAfterSeparator)
+		return runTarget(opts, target, concatArgs(leading, forwardedAfterSeparator))
 	}
 
 	first := strings.ToLower(tokens[0])
 	switch first {
 	case "help", "-h", "--help":
-		printUsage(os.Stdout)
-		return 0
+		printUsage(stdout)
+		return exitOK
 	case "list", "targets":
-		printTargets()
-		return 0
+		return printTargets(opts, tokens[1:])
 	case "slices":
-		return printSlices(opts)
+		return printSlices(opts, tokens[1:])
+	case "profiles":
+		return printProfiles(opts)
+	case "extensions":
+		return printExtensions(opts, tokens[1:])
+	case "version":
+		return printVersion(opts)
 	case "doctor":
-		return runDoctor(opts)
+		return runDoctor(opts, tokens[1:])
+	case "config":
+		return runConfig(opts, tokens[1:])
+	case "completion":
+		return runCompletion(opts, tokens[1:])
 	case "open":
 		target := ""
 		forwarded := forwardedAfterSeparator
-		if len(tokens) > 1 {
+		if len(tokens) > 1 && tokens[1] == "-" {
+			name, readErr := readTargetFromStdin()
+			if readErr != nil {
+				fmt.Fprintf(stderr, "error: %v\n", readErr)
+				return exitUsage
+			}
+			target = name
+			forwarded = concatArgs(leading, tokens[2:], forwarded)
+		} else if len(tokens) > 1 {
 			target = tokens[1]
-			forwarded = append(tokens[2:], forwarded...)
+			forwarded = concatArgs(leading, tokens[2:], forwarded)
 		} else {
-			picked, pickErr := pickTargetInteractive()
+			picked, pickErr := pickTarget(opts)
 			if pickErr != nil {
-				fmt.Fprintf(os.Stderr, "error: %v\n", pickErr)
-				return 2
+				fmt.Fprintf(stderr, "error: %v\n", pickErr)
+				return exitUsage
 			}
 			target = picked
+			forwarded = concatArgs(leading, forwarded)
 		}
 		return runTarget(opts, target, forwarded)
+	case "args":
+		return runArgs(opts, tokens[1:], forwardedAfterSeparator)
+	case "replay":
+		return runReplay(opts, tokens[1:])
+	case "lock":
+		return runLock(opts, tokens[1:])
+	case "run":
+		return runBatch(opts, tokens[1:], forwardedAfterSeparator)
+	case "watch":
+		return runWatch(opts, tokens[1:], forwardedAfterSeparator)
+	case "alias":
+		return runAlias(opts, tokens[1:])
+	case "init":
+		return runInit(opts, tokens[1:])
+	case "rm-slice":
+		return runRmSlice(opts, tokens[1:])
+	case "disable-slice":
+		return runSetSliceEnabled(opts, tokens[1:], false)
+	case "enable-slice":
+		return runSetSliceEnabled(opts, tokens[1:], true)
+	case "explain":
+		return runExplain(opts, tokens[1:], forwardedAfterSeparator)
+	case "set-profile":
+		return runSetProfile(tokens[1:])
+	case "unset-profile":
+		return runUnsetProfile(tokens[1:])
 	case "slice":
 		if len(tokens) < 2 {
-			fmt.Fprintln(os.Stderr, "error: slice command requires a slice name")
-			return 2
+			fmt.Fprintln(stderr, "error: slice command requires a slice name")
+			return exitUsage
+		}
+		if tokens[1] == "--validate-only" {
+			return runValidateSlice(opts, tokens[2:])
 		}
 		return runSlice(opts, tokens[1], append(tokens[2:], forwardedAfterSeparator...))
 	default:
+		if rest, ok := strings.CutPrefix(first, ":"); ok {
+			return runTargetIndex(opts, rest, concatArgs(leading, tokens[1:], forwardedAfterSeparator))
+		}
 		if _, ok := controlplane.ResolveTarget(first); ok {
-			return runTarget(opts, first, append(tokens[1:], forwardedAfterSeparator...))
+			return runTarget(opts, first, concatArgs(leading, tokens[1:], forwardedAfterSeparator))
 		}
-		fmt.Fprintf(os.Stderr, "error: unknown command or target %q\n", first)
-		printUsage(os.Stderr)
-		return 2
+		fmt.Fprintf(stderr, "error: unknown command or target %q\n", first)
+		printUsage(stderr)
+		return exitUsage
 	}
 }
 
+var valueFlags = map[string]func(*globalOptions, string) error{
+	"--root": func(opts *globalOptions, value string) error {
+		opts.Root = value
+		return nil
+	},
+	"--profile": func(opts *globalOptions, value string) error {
+		opts.Profile = value
+		return nil
+	},
+	"--append-profile": func(opts *globalOptions, value string) error {
+		opts.AppendProfile = value
+		return nil
+	},
+	"--timeout": func(opts *globalOptions, value string) error {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid --timeout %q (want a duration like 30s or 10m)", value)
+		}
+		opts.Timeout = timeout
+		return nil
+	},
+	"--launch-timeout-startup": func(opts *globalOptions, value string) error {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid --launch-timeout-startup %q (want a duration like 30s)", value)
+		}
+		opts.StartupTimeout = timeout
+		return nil
+	},
+	"--index": func(opts *globalOptions, value string) error {
+		opts.Index = value
+		return nil
+	},
+	"--env-file": func(opts *globalOptions, value string) error {
+		opts.EnvFiles = append(opts.EnvFiles, value)
+		return nil
+	},
+	"--env": func(opts *globalOptions, value string) error {
+		if _, _, err := controlplane.ParseEnvAssignment(value); err != nil {
+			return err
+		}
+		opts.Env = append(opts.Env, value)
+		return nil
+	},
+	"--skip-ext": func(opts *globalOptions, value string) error {
+		opts.SkipExtensions = append(opts.SkipExtensions, value)
+		return nil
+	},
+	"--only-ext": func(opts *globalOptions, value string) error {
+		opts.OnlyExtensions = append(opts.OnlyExtensions, value)
+		return nil
+	},
+	"--picker": func(opts *globalOptions, value string) error {
+		if value != "numeric" && value != "fzf" {
+			return fmt.Errorf("invalid --picker %q (want numeric or fzf)", value)
+		}
+		opts.Picker = value
+		return nil
+	},
+	"--trace-launch": func(opts *globalOptions, value string) error {
+		opts.TraceLaunch = value
+		return nil
+	},
+	"--exit-code-file": func(opts *globalOptions, value string) error {
+		opts.ExitCodeFile = value
+		return nil
+	},
+	"--cwd": func(opts *globalOptions, value string) error {
+		if strings.TrimSpace(value) == "" {
+			return errors.New("--cwd requires a directory")
+		}
+		opts.Cwd = value
+		return nil
+	},
+	"--stdin-file": func(opts *globalOptions, value string) error {
+		opts.StdinFile = value
+		return nil
+	},
+	"--slice-dir": func(opts *globalOptions, value string) error {
+		opts.SliceDir = value
+		return nil
+	},
+	"--profile-file": func(opts *globalOptions, value string) error {
+		opts.ProfileFile = value
+		return nil
+	},
+	"--env-profile": func(opts *globalOptions, value string) error {
+		opts.EnvProfile = value
+		return nil
+	},
+	"--log-format": func(opts *globalOptions, value string) error {
+		if value != "text" && value != "json" {
+			return fmt.Errorf("invalid --log-format %q (want text or json)", value)
+		}
+		opts.LogFormat = value
+		return nil
+	},
+	"--log-level": func(opts *globalOptions, value string) error {
+		if _, ok := logLevels[value]; !ok {
+			return fmt.Errorf("invalid --log-level %q (want debug, info, warn, or error)", value)
+		}
+		opts.LogLevel = value
+		return nil
+	},
+	"--args-file": func(opts *globalOptions, value string) error {
+		opts.ArgsFile = value
+		return nil
+	},
+	"--retries": func(opts *globalOptions, value string) error {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			return fmt.Errorf("invalid --retries %q (want a non-negative integer)", value)
+		}
+		opts.Retries = retries
+		return nil
+	},
+	"--retry-on": func(opts *globalOptions, value string) error {
+		opts.RetryCodes = nil
+		for _, item := range strings.Split(value, ",") {
+			code, err := strconv.Atoi(strings.TrimSpace(item))
+			if err != nil || code <= 0 {
+				return fmt.Errorf("invalid --retry-on %q (want comma-separated nonzero exit codes)", value)
+			}
+			opts.RetryCodes = append(opts.RetryCodes, code)
+		}
+		return nil
+	},
+}
+
 func parseArgs(argv []string) (globalOptions, []string, []string, error) {
 	opts := globalOptions{}
 	pre, post := splitOnDoubleDash(argv)
@@ -95,35 +395,97 @@ func parseArgs(argv []string) (globalOptions, []string, []string, error) {
 	tokens := make([]string, 0, len(pre))
 	for i := 0; i < len(pre); i++ {
 		arg := pre[i]
-		switch {
-		case arg == "--strict":
-			opts.Strict = true
-		case arg == "-h" || arg == "--help":
-			opts.Help = true
-		case arg == "--root":
-			if i+1 >= len(pre) {
-				return opts, nil, nil, errors.New("--root requires a value")
+
+		name, value, hasValue := strings.Cut(arg, "=")
+		if setter, ok := valueFlags[name]; ok {
+			if !hasValue {
+				if i+1 >= len(pre) {
+					return opts, nil, nil, fmt.Errorf("%s requires a value", name)
+				}
+				i++
+				value = pre[i]
 			}
-			i++
-			opts.Root = pre[i]
-		case strings.HasPrefix(arg, "--root="):
-			opts.Root = strings.TrimPrefix(arg, "--root=")
-		case arg == "--profile":
-			if i+1 >= len(pre) {
-				return opts, nil, nil, errors.New("--profile requires a value")
+			if err := setter(&opts, value); err != nil {
+				return opts, nil, nil, err
 			}
-			i++
-			opts.Profile = pre[i]
-		case strings.HasPrefix(arg, "--profile="):
-			opts.Profile = strings.TrimPrefix(arg, "--profile=")
+			continue
+		}
+
+		switch arg {
+		case "--strict":
+			opts.Strict = true
+		case "--strict-extensions":
+			opts.StrictExtensions = true
+		case "--strict-paths":
+			opts.StrictPaths = true
+		case "--json":
+			opts.JSON = true
+		case "--print-cmd":
+			opts.PrintCmd = true
+		case "--verbose":
+			opts.Verbose = true
+		case "--quiet":
+			opts.Quiet = true
+		case "--no-color":
+			opts.NoColor = true
+		case "--no-env-file":
+			opts.NoEnvFile = true
+		case "--skip-requires":
+			opts.SkipRequires = true
+		case "--profile-from-branch":
+			opts.BranchProfile = true
+		case "--yes":
+			opts.Yes = true
+		case "--show-hidden":
+			opts.ShowHidden = true
+		case "--version":
+			opts.Version = true
+		case "-h", "--help":
+			opts.Help = true
 		default:
 			tokens = append(tokens, arg)
 		}
 	}
 
+	if opts.Quiet && opts.Verbose {
+		return opts, nil, nil, fmt.Errorf("--quiet and --verbose are mutually exclusive")
+	}
+	if opts.Profile != "" && opts.AppendProfile != "" {
+		return opts, nil, nil, fmt.Errorf("--profile and --append-profile are mutually exclusive")
+	}
 	return opts, tokens, post, nil
 }
 
+func splitLeadingFlags(tokens []string) ([]string, []string) {
+	var leading []string
+	i := 0
+	for i < len(tokens) && strings.HasPrefix(tokens[i], "-") {
+		leading = append(leading, tokens[i])
+		i++
+		if !strings.Contains(leading[len(leading)-1], "=") && i < len(tokens) && !strings.HasPrefix(tokens[i], "-") && i+1 < len(tokens) && !isLaunchToken(tokens[i]) {
+			leading = append(leading, tokens[i])
+			i++
+		}
+	}
+	return leading, tokens[i:]
+}
+
+func isLaunchToken(token string) bool {
+	if strings.HasPrefix(token, ":") || strings.EqualFold(token, "open") {
+		return true
+	}
+	_, ok := controlplane.ResolveTarget(token)
+	return ok
+}
+
+func concatArgs(parts ...[]string) []string {
+	var out []string
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
 func splitOnDoubleDash(args []string) ([]string, []string) {
 	for i, arg := range args {
 		if arg == "--" {
@@ -133,22 +495,98 @@ func splitOnDoubleDash(args []string) ([]string, []string) {
 	return args, nil
 }
 
-func printUsage(out *os.File) {
+func printUsage(out io.Writer) {
 	fmt.Fprintln(out, "pictl - Pi control-plane launcher")
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "Usage:")
 	fmt.Fprintln(out, "  pictl [global flags]                     # interactive target picker")
 	fmt.Fprintln(out, "  pictl <target> [pi args...]              # launch target")
+	fmt.Fprintln(out, "  pictl :<n> [pi args...]                  # launch the nth picker target (also --index <n>)")
 	fmt.Fprintln(out, "  pictl open <target> [pi args...]")
+	fmt.Fprintln(out, "  pictl open - [pi args...]                # read the target name from stdin (echo build | pictl open -)")
 	fmt.Fprintln(out, "  pictl slice <slice> [pi args...]")
-	fmt.Fprintln(out, "  pictl list|targets")
-	fmt.Fprintln(out, "  pictl slices")
-	fmt.Fprintln(out, "  pictl doctor")
+	fmt.Fprintln(out, "  pictl slice --validate-only <slice>      # parse and validate one manifest, ignoring the rest of the catalog")
+	fmt.Fprintln(out, "  pictl args [--line] <target> [pi args...] # print pi argv without launching")
+	fmt.Fprintln(out, "  pictl run --each <glob> [--fail-fast] [--dry-run] [-- pi args...] # launch each matching slice in turn")
+	fmt.Fprintln(out, "  pictl run <target> --repeat <n> [--stop-on-error] [-- pi args...] # launch a target n times and summarize durations")
+	fmt.Fprintln(out, "  pictl watch <target> [pi args...]        # relaunch pi whenever the slice or one of its extensions changes")
+	fmt.Fprintln(out, "  pictl replay <trace.json>                # relaunch exactly what --trace-launch recorded")
+	fmt.Fprintln(out, "  pictl explain <target>                   # describe how a target resolves, step by step")
+	fmt.Fprintln(out, "  pictl list|targets [--category <name>] [--format <tmpl>] [--all] # e.g. --format '{{.Name}}\\t{{.Slice}}'; --all adds hidden targets")
+	fmt.Fprintln(out, "  pictl targets --resolve <a,b,...> [--allow-unresolved] [--json] # canonical target for each name or alias; exit 1 if any is unknown")
+	fmt.Fprintln(out, "  pictl alias <name> | --list              # print the canonical target for a name or alias")
+	fmt.Fprintln(out, "  pictl slices [--all] [--summary]         # --all includes disabled slices; --summary adds catalog totals")
+	fmt.Fprintln(out, "  pictl slices [--all] --format <tmpl>     # one line per slice via a Go template ({{.Name}}, {{.Manifest.DefaultProfile}})")
+	fmt.Fprintln(out, "  pictl slices --tag <t> [--tag <t>] [--tag-match all|any] # only slices with these tags (default: all of them)")
+	fmt.Fprintln(out, "  pictl slices --diff <other-root>         # slices added, removed, or changed in another checkout")
+	fmt.Fprintln(out, "  pictl slices --unused|--orphans          # slices no target uses / extensions no slice loads")
+	fmt.Fprintln(out, "  pictl profiles")
+	fmt.Fprintln(out, "  pictl init [--name <slice>] [--profile <p>] [--extensions a,b] [--force] # scaffold slices/<name>.json")
+	fmt.Fprintln(out, "  pictl rm-slice <slice> [--dry-run] [--force] # delete a slice file; --force even if a target uses it")
+	fmt.Fprintln(out, "  pictl disable-slice|enable-slice <slice> [--force] # toggle \"enabled\" in the manifest in place")
+	fmt.Fprintln(out, "  pictl extensions [--json]                # every loaded extension file and the slices using it")
+	fmt.Fprintln(out, "  pictl set-profile <target> <profile>     # save your default profile for a target")
+	fmt.Fprintln(out, "  pictl unset-profile <target>             # go back to the target's built-in default")
+	fmt.Fprintln(out, "  pictl version                            # also --version")
+	fmt.Fprintln(out, "  pictl completion [bash|zsh|fish]         # print a shell completion script (shell defaults to $SHELL)")
+	fmt.Fprintln(out, "  pictl completion --install [--dry-run]   # write it where the shell loads completions, once")
+	fmt.Fprintln(out, "  pictl lock [--write [--out <path>]]      # snapshot the resolved catalog; --write saves <root>/pictl.lock.json")
+	fmt.Fprintln(out, "  pictl config [--json]                    # everything pictl resolved: root and how, env files, profiles, state")
+	fmt.Fprintln(out, "  pictl doctor [--fix [--write]]           # --fix previews canonical manifests")
+	fmt.Fprintln(out, "  pictl doctor --fix-extensions [--write]  # drop extension entries whose files are gone (never the last one)")
+	fmt.Fprintln(out, "  pictl doctor --fix-schema-version [--write] # add schemaVersion to manifests that lack it")
+	fmt.Fprintln(out, "  pictl doctor --strict [--json]           # warnings fail too (exit 1); for CI")
+	fmt.Fprintln(out, "  pictl doctor --check-extensions [--typecheck] # per-slice file checks; --typecheck runs deno/tsc")
+	fmt.Fprintln(out, "  pictl doctor --check-pi-flags            # confirm pi --help lists every flag pictl passes")
+	fmt.Fprintln(out, "  pictl doctor --json-schema [--out <path>] # JSON Schema for slice manifests (reference it via \"$schema\")")
+	fmt.Fprintln(out, "  pictl doctor --check-cwd                 # warn when the working directory is outside the root (AGENTS.md layering)")
+	fmt.Fprintln(out, "  pictl doctor --check-json-formatting     # warn on manifests not in the canonical form --fix writes")
+	fmt.Fprintln(out, "  pictl doctor --compare-lockfile <path>   # fail when targets, slices, extensions, or profiles drift from a lockfile")
+	fmt.Fprintln(out, "  pictl doctor --network                   # HEAD the provider URL (PICTL_PROVIDER_URL) and report latency; opt-in")
+	fmt.Fprintln(out, "  pictl doctor --check-permissions         # warn on unreadable or world-writable slices, extensions, state")
+	fmt.Fprintln(out, "  pictl doctor --write-report <file>       # also save the JSON report with a timestamp and version")
+	fmt.Fprintln(out, "  pictl doctor --since <24h|git-ref>       # re-validate only slices changed since then (mtime or git diff)")
+	fmt.Fprintln(out, "  pictl doctor --repair-aliases            # who wins each contested target alias, and suggested renames")
+	fmt.Fprintln(out, "  pictl doctor --benchmark [--json]        # min/median ms for root discovery, slice loading, launch spec")
+	fmt.Fprintln(out, "  pictl doctor --deps [--format tree|dot]  # slice include graph; missing includes and cycles fail")
+	fmt.Fprintln(out, "  pictl doctor --root-trace                # show how the root was found (or why not)")
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "Global flags:")
 	fmt.Fprintln(out, "  --root <path>       Override pi-agent-config root")
 	fmt.Fprintln(out, "  --strict            Disable discovered skills/prompts/themes")
+	fmt.Fprintln(out, "  --strict-extensions Fail (instead of warn) on suspicious extension files")
+	fmt.Fprintln(out, "  --skip-ext <substr> Leave out resolved extensions whose path contains substr (repeatable)")
+	fmt.Fprintln(out, "  --only-ext <substr> Keep only resolved extensions whose path contains substr (repeatable)")
+	fmt.Fprintln(out, "  --strict-paths      Fail (instead of warn) when an extension path's case differs from disk")
 	fmt.Fprintln(out, "  --profile <name>    Override profile (meta|execute|ship|fast aliases)")
+	fmt.Fprintln(out, "  --append-profile <p> Add a profile to the resolved one (PI_DEFAULT_PROFILE=default,p); not with --profile")
+	fmt.Fprintln(out, "  --profile-from-branch Pick the profile from the git branch when none is set (main=ship, others=execute; <root>/branch-profiles.json)")
+	fmt.Fprintln(out, "  --timeout <dur>     Stop pi after a duration (e.g. 30m); 0 means no limit")
+	fmt.Fprintln(out, "  --launch-timeout-startup <dur> Stop pi if it prints nothing within a duration (e.g. 20s); pipes pi's output")
+	fmt.Fprintln(out, "  --env KEY=VALUE     Set a variable for pi, over everything else (repeatable)")
+	fmt.Fprintln(out, "  --env-file <path>   Load KEY=value defaults from this file instead of <root>/.env (repeatable; later files win)")
+	fmt.Fprintln(out, "  --args-file <path>  Forward pi args read from a file (one or more per line, # comments) before inline args")
+	fmt.Fprintln(out, "  --no-env-file       Skip loading <root>/.env")
+	fmt.Fprintln(out, "  --skip-requires     Launch even when tools the slice requires are missing from PATH")
+	fmt.Fprintln(out, "  --show-hidden       Include targets marked hidden in list, the picker, and :<n> numbering")
+	fmt.Fprintln(out, "  --yes               Skip the launch confirmation for targets marked confirm (e.g. ops)")
+	fmt.Fprintln(out, "  --picker <name>     Interactive target picker: numeric (default) or fzf; PICTL_PICKER sets a default")
+	fmt.Fprintln(out, "  --cwd <dir>         Start pi in this directory (relative to the root); overrides the slice's cwd")
+	fmt.Fprintln(out, "  --stdin-file <path> Feed this file to pi's stdin instead of the terminal (e.g. a prompt for a scripted run)")
+	fmt.Fprintln(out, "  --slice-dir <path>  Read slice manifests from this directory instead of <root>/slices (also PICTL_SLICE_DIR)")
+	fmt.Fprintln(out, "  --profile-file <p>  Read the profile catalog from this file instead of <root>/profiles.json")
+	fmt.Fprintln(out, "  --env-profile <n>   Add the named variable set from <root>/env-profiles.json (beneath the shell env)")
+	fmt.Fprintln(out, "  --exit-code-file <p> After pi exits, also write pictl's exit code to this file")
+	fmt.Fprintln(out, "  --trace-launch <p>  Before launching, write the resolved command and env changes to this JSON file")
+	fmt.Fprintln(out, "  --retries <n>       Relaunch pi up to n times on retryable exit codes")
+	fmt.Fprintln(out, "  --retry-on <codes>  Retryable pi exit codes, comma-separated (default 75)")
+	fmt.Fprintln(out, "  --json              Emit machine-readable JSON (profiles, version, doctor, slices)")
+	fmt.Fprintln(out, "  --print-cmd         Echo the resolved pi command to stderr before launching")
+	fmt.Fprintln(out, "  --verbose           Print launch diagnostics to stderr")
+	fmt.Fprintln(out, "  --log-format <fmt>  Diagnostics as text (default) or json lines")
+	fmt.Fprintln(out, "  --log-level <lvl>   debug|info|warn|error (default info, debug with --verbose)")
+	fmt.Fprintln(out, "  --quiet             Silence pictl's own messages; only errors and pi's output remain")
+	fmt.Fprintln(out, "  --no-color          Disable colored output (also NO_COLOR=1 or a non-TTY stdout)")
 	fmt.Fprintln(out, "  --help              Show help")
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "Examples:")
@@ -163,165 +601,733 @@ func printUsage(out *os.File) {
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "Targets:")
 	for _, target := range controlplane.CanonicalTargets() {
-		fmt.Fprintf(out, "  %-10s -> %-9s / %-7s %s\n", target.Name, target.Slice, target.DefaultProfile, target.Description)
+		fmt.Fprintf(out, "  %-10s -> %-9s / %-7s %s\n", target.Name, target.Slice, target.DefaultProfile, controlplane.TargetDescription(target))
 	}
 }
 
-func printTargets() {
-	for _, target := range controlplane.CanonicalTargets() {
-		fmt.Printf("%-10s -> %-9s / %-7s (%s)\n", target.Name, target.Slice, target.DefaultProfile, target.Description)
+func parseListArgs(args []string) (listOptions, error) {
+	opts := listOptions{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--category":
+			if i+1 >= len(args) {
+				return opts, errors.New("--category requires a value")
+			}
+			i++
+			opts.Category = args[i]
+		case strings.HasPrefix(arg, "--category="):
+			opts.Category = strings.TrimPrefix(arg, "--category=")
+		case arg == "--format":
+			if i+1 >= len(args) {
+				return opts, errors.New("--format requires a template")
+			}
+			i++
+			opts.Format = args[i]
+		case strings.HasPrefix(arg, "--format="):
+			opts.Format = strings.TrimPrefix(arg, "--format=")
+		case arg == "--all":
+			opts.All = true
+		case arg == "--resolve":
+			if i+1 >= len(args) {
+				return opts, errors.New("--resolve requires a comma-separated list of names")
+			}
+			i++
+			opts.Resolve = append(opts.Resolve, splitList(args[i])...)
+		case strings.HasPrefix(arg, "--resolve="):
+			opts.Resolve = append(opts.Resolve, splitList(strings.TrimPrefix(arg, "--resolve="))...)
+		case arg == "--allow-unresolved":
+			opts.AllowUnresolved = true
+		default:
+			return opts, fmt.Errorf("unknown list flag %q", arg)
+		}
+	}
+	if opts.AllowUnresolved && len(opts.Resolve) == 0 {
+		return opts, errors.New("--allow-unresolved requires --resolve")
+	}
+	if len(opts.Resolve) > 0 && (opts.Category != "" || opts.Format != "" || opts.All) {
+		return opts, errors.New("--resolve cannot be combined with --category, --format, or --all")
 	}
+	return opts, nil
 }
 
-func printSlices(opts globalOptions) int {
-	root, err := controlplane.DetermineRoot(opts.Root)
+func printTargets(opts globalOptions, args []string) int {
+	listOpts, err := parseListArgs(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		return 1
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+
+	if len(listOpts.Resolve) > 0 {
+		return printResolvedTargets(opts, listOpts.Resolve, listOpts.AllowUnresolved)
+	}
+
+	targets := controlplane.VisibleTargets(controlplane.CanonicalTargets(), listOpts.All || opts.ShowHidden)
+	groups := controlplane.GroupTargets(targets, listOpts.Category)
+	if len(groups) == 0 {
+		fmt.Fprintf(stderr, "error: no targets in category %q\n", listOpts.Category)
+		return exitUsage
+	}
+
+	if listOpts.Format != "" {
+		tmpl, err := parseFormat(listOpts.Format)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitUsage
+		}
+		var targets []controlplane.Target
+		for _, group := range groups {
+			targets = append(targets, group.Targets...)
+		}
+		return renderFormat(tmpl, targets)
+	}
+
+	for _, group := range groups {
+		fmt.Fprintf(stdout, "%s:\n", group.Category)
+		for _, target := range group.Targets {
+			fmt.Fprintf(stdout, "  %-10s -> %-9s / %-7s (%s)\n", target.Name, target.Slice, target.DefaultProfile, controlplane.TargetDescription(target))
+		}
+	}
+	return exitOK
+}
+
+func printResolvedTargets(opts globalOptions, names []string, allowUnresolved bool) int {
+	results := make([]resolvedTarget, 0, len(names))
+	var unresolved []string
+	for _, name := range names {
+		result := resolvedTarget{Input: name}
+		if target, ok := controlplane.ResolveTarget(name); ok {
+			result.Target, result.Slice, result.Resolved = target.Name, target.Slice, true
+		} else {
+			unresolved = append(unresolved, name)
+		}
+		results = append(results, result)
+	}
+
+	if opts.JSON {
+		if code := writeJSON(results); code != exitOK {
+			return code
+		}
+	} else {
+		for _, result := range results {
+			resolved := "unresolved"
+			if result.Resolved {
+				resolved = result.Target
+			}
+			fmt.Fprintf(stdout, "%-10s -> %s\n", result.Input, resolved)
+		}
+	}
+
+	if len(unresolved) > 0 && !allowUnresolved {
+		fmt.Fprintf(stderr, "error: %d of %d name(s) unresolved: %s (pass --allow-unresolved to exit 0)\n", len(unresolved), len(names), strings.Join(unresolved, ", "))
+		return exitFailure
+	}
+	return exitOK
+}
+
+func printVersion(opts globalOptions) int {
+	info := buildinfo.Current()
+	if opts.JSON {
+		return writeJSON(info)
+	}
+	fmt.Fprintf(stdout, "pictl %s\n", info)
+	return exitOK
+}
+
+func printProfiles(opts globalOptions) int {
+	profiles := controlplane.CanonicalProfiles()
+	if opts.JSON {
+		return writeJSON(profiles)
+	}
+
+	for _, profile := range profiles {
+		fmt.Fprintf(stdout, "%-10s aliases=%-20s %s\n", profile.Name, strings.Join(profile.Aliases, ","), profile.Description)
+	}
+	return exitOK
+}
+
+func writeJSON(value any) int {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return exitCodeForError(err)
+	}
+	return exitOK
+}
+
+func printExtensions(opts globalOptions, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(stderr, "error: unknown extensions flag %q\n", args[0])
+		return exitUsage
 	}
 
+	root, err := determineRoot(opts)
+	if err != nil {
+		return exitCodeForError(err)
+	}
 	slices, err := controlplane.LoadSlices(root)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		return 1
+		return exitCodeForError(err)
+	}
+	inventory, err := controlplane.ExtensionInventory(root, slices)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+
+	if opts.JSON {
+		return writeJSON(inventory)
+	}
+	for _, usage := range inventory {
+		fmt.Fprintf(stdout, "%-40s %s\n", usage.Path, strings.Join(usage.Slices, ", "))
+	}
+	return exitOK
+}
+
+func printSliceSummary(summary controlplane.SliceSummary) {
+	profiles := make([]string, 0, len(summary.Profiles))
+	for profile := range summary.Profiles {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+	for i, profile := range profiles {
+		profiles[i] = fmt.Sprintf("%s=%d", profile, summary.Profiles[profile])
 	}
 
-	for _, info := range controlplane.SortedSliceInfos(slices) {
+	fmt.Fprintln(stdout)
+	fmt.Fprintf(stdout, "slices: %d (%d enabled, %d disabled)\n", summary.Total, summary.Enabled, summary.Disabled)
+	fmt.Fprintf(stdout, "distinct extensions: %d\n", summary.Extensions)
+	fmt.Fprintf(stdout, "profiles: %s\n", strings.Join(profiles, ", "))
+}
+
+func parseSlicesArgs(args []string) (slicesOptions, error) {
+	opts := slicesOptions{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if value, ok := strings.CutPrefix(arg, "--format="); ok {
+			opts.Format = value
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--tag="); ok {
+			opts.Tags = append(opts.Tags, value)
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--diff="); ok {
+			opts.Diff = value
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--tag-match="); ok {
+			opts.TagMatch = value
+			continue
+		}
+		switch arg {
+		case "--format":
+			if i+1 >= len(args) {
+				return opts, errors.New("--format requires a template")
+			}
+			i++
+			opts.Format = args[i]
+		case "--tag":
+			if i+1 >= len(args) {
+				return opts, errors.New("--tag requires a tag")
+			}
+			i++
+			opts.Tags = append(opts.Tags, args[i])
+		case "--diff":
+			if i+1 >= len(args) {
+				return opts, errors.New("--diff requires another root")
+			}
+			i++
+			opts.Diff = args[i]
+		case "--tag-match":
+			if i+1 >= len(args) {
+				return opts, errors.New("--tag-match requires all or any")
+			}
+			i++
+			opts.TagMatch = args[i]
+		case "--all":
+			opts.All = true
+		case "--unused":
+			opts.Unused = true
+		case "--orphans":
+			opts.Orphans = true
+		case "--summary":
+			opts.Summary = true
+		default:
+			return opts, fmt.Errorf("unknown slices flag %q", arg)
+		}
+	}
+	if opts.Unused && opts.Orphans {
+		return opts, fmt.Errorf("--unused and --orphans are mutually exclusive")
+	}
+	if opts.Format != "" && (opts.Unused || opts.Orphans || opts.Summary) {
+		return opts, fmt.Errorf("--format only applies to the slice listing")
+	}
+	if len(opts.Tags) > 0 && (opts.Unused || opts.Orphans) {
+		return opts, fmt.Errorf("--tag only applies to the slice listing")
+	}
+	if opts.TagMatch != "" && opts.TagMatch != "all" && opts.TagMatch != "any" {
+		return opts, fmt.Errorf("invalid --tag-match %q (want all or any)", opts.TagMatch)
+	}
+	if opts.TagMatch != "" && len(opts.Tags) == 0 {
+		return opts, fmt.Errorf("--tag-match requires --tag")
+	}
+	if opts.Diff != "" && (opts.All || opts.Unused || opts.Orphans || opts.Summary || opts.Format != "" || len(opts.Tags) > 0) {
+		return opts, fmt.Errorf("--diff cannot be combined with other slices flags")
+	}
+	return opts, nil
+}
+
+func printSlices(opts globalOptions, args []string) int {
+	slicesOpts, err := parseSlicesArgs(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	var format *template.Template
+	if slicesOpts.Format != "" {
+		if opts.JSON {
+			fmt.Fprintln(stderr, "error: --format and --json are mutually exclusive")
+			return exitUsage
+		}
+		if format, err = parseFormat(slicesOpts.Format); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	root, err := determineRoot(opts)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+
+	slices, err := controlplane.LoadSlices(root)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+
+	if slicesOpts.Diff != "" {
+		return runSliceDiff(opts, slices, slicesOpts.Diff)
+	}
+	if slicesOpts.Unused {
+		return printNames(opts, controlplane.UnusedSlices(slices, controlplane.CanonicalTargets()))
+	}
+	if slicesOpts.Orphans {
+		orphans, err := controlplane.OrphanExtensions(root, slices)
+		if err != nil {
+			return exitCodeForError(err)
+		}
+		return printNames(opts, orphans)
+	}
+
+	all := controlplane.SortedSliceInfos(slices)
+	infos := all
+	if !slicesOpts.All {
+		infos = controlplane.EnabledSliceInfos(infos)
+	}
+	infos = controlplane.FilterSliceInfosByTag(infos, slicesOpts.Tags, slicesOpts.TagMatch != "any")
+	if slicesOpts.Summary && opts.JSON {
+		return writeJSON(struct {
+			Summary controlplane.SliceSummary `json:"summary"`
+		}{controlplane.SummarizeSlices(all)})
+	}
+	if format != nil {
+		return renderFormat(format, infos)
+	}
+	if opts.JSON {
+		return writeSliceListing(infos)
+	}
+
+	for _, info := range infos {
 		profile := info.Manifest.DefaultProfile
 		if profile == "" {
 			profile = "(none)"
 		}
-		description := info.Manifest.Description
+		description := controlplane.SliceDescription(info.Name, info.Manifest, controlplane.CanonicalTargets())
 		if description == "" {
 			description = "(no description)"
 		}
-		fmt.Printf("%-12s profile=%-10s extensions=%-2d %s\n", info.Name, profile, len(info.Manifest.Extensions), description)
+		if !info.Manifest.IsEnabled() {
+			description += " [disabled]"
+		}
+		if len(info.Manifest.Tags) > 0 {
+			description += " [tags: " + strings.Join(info.Manifest.Tags, ", ") + "]"
+		}
+		fmt.Fprintf(stdout, "%-12s profile=%-10s extensions=%-2d %s\n", info.Name, profile, len(info.Manifest.Extensions), description)
+	}
+	if slicesOpts.Summary {
+		printSliceSummary(controlplane.SummarizeSlices(all))
 	}
 
-	return 0
+	return exitOK
 }
 
-func runDoctor(opts globalOptions) int {
-	root, err := controlplane.DetermineRoot(opts.Root)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		return 1
+func writeSliceListing(infos []controlplane.SliceInfo) int {
+	type sliceListing struct {
+		Name string `json:"name"`
+		controlplane.SliceManifest
+		Enabled bool `json:"enabled"`
 	}
-
-	slices, err := controlplane.LoadSlices(root)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		return 1
+	out := make([]sliceListing, 0, len(infos))
+	for _, info := range infos {
+		out = append(out, sliceListing{Name: info.Name, SliceManifest: info.Manifest, Enabled: info.Manifest.IsEnabled()})
 	}
+	return writeJSON(out)
+}
 
-	fmt.Printf("root: %s\n", root)
-	fmt.Printf("targets: %d\n", len(controlplane.CanonicalTargets()))
-	fmt.Printf("slices: %d\n", len(slices))
-	fmt.Printf("strict default: %v\n", opts.Strict)
-	if opts.Profile != "" {
-		fmt.Printf("profile override: %s\n", opts.Profile)
+func printNames(opts globalOptions, names []string) int {
+	if opts.JSON {
+		if names == nil {
+			names = []string{}
+		}
+		return writeJSON(names)
 	}
-	if env := os.Getenv("PI_AGENT_CONFIG_ROOT"); env != "" {
-		fmt.Printf("env PI_AGENT_CONFIG_ROOT: %s\n", env)
+	for _, name := range names {
+		fmt.Fprintln(stdout, name)
 	}
-	return 0
+	return exitOK
 }
 
 func runTarget(opts globalOptions, targetName string, forwarded []string) int {
+	spec, err := buildTargetSpec(opts, targetName, forwarded)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	if target, _ := controlplane.ResolveTarget(targetName); !confirmTarget(opts, target) {
+		return exitFailure
+	}
+	return launch(opts, spec)
+}
+
+func buildTargetSpec(opts globalOptions, targetName string, forwarded []string) (controlplane.LaunchSpec, error) {
 	target, ok := controlplane.ResolveTarget(targetName)
 	if !ok {
-		fmt.Fprintf(os.Stderr, "error: unknown target %q\n", targetName)
-		return 2
+		return controlplane.LaunchSpec{}, fmt.Errorf("%w %q", controlplane.ErrUnknownTarget, targetName)
 	}
+	return buildResolvedTargetSpec(opts, target, forwarded)
+}
 
-	root, err := controlplane.DetermineRoot(opts.Root)
+func buildResolvedTargetSpec(opts globalOptions, target controlplane.Target, forwarded []string) (controlplane.LaunchSpec, error) {
+	root, err := determineRoot(opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		return 1
+		return controlplane.LaunchSpec{}, err
 	}
 
 	slices, err := controlplane.LoadSlices(root)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		return 1
+		return controlplane.LaunchSpec{}, err
 	}
 
-	manifest, ok := slices[target.Slice]
-	if !ok {
-		fmt.Fprintf(os.Stderr, "error: target %q maps to missing slice %q\n", target.Name, target.Slice)
-		return 1
+	manifest, sliceName, err := controlplane.LookupTargetSlice(slices, target)
+	if err != nil {
+		return controlplane.LaunchSpec{}, fmt.Errorf("target %q: %w", target.Name, err)
+	}
+	if sliceName != target.Slice {
+		logger.Warn("slice missing; launching the fallback slice instead", "target", target.Name, "slice", target.Slice, "fallback", sliceName)
 	}
+	logger.Debug("loaded slice", "target", target.Name, "slice", sliceName, "root", root, "extensions", len(manifest.Extensions))
 
-	profile := strings.TrimSpace(opts.Profile)
-	if profile == "" {
-		profile = target.DefaultProfile
+	defaultProfile, _, err := targetDefaultProfile(target)
+	if err != nil {
+		return controlplane.LaunchSpec{}, err
 	}
 
-	spec, err := controlplane.BuildLaunchSpec(root, manifest, opts.Strict, profile, forwarded)
+	launchOpts := launchOptions(opts, forwarded)
+	launchOpts.DefaultProfile = defaultProfile
+	launchOpts.ForwardedArgs = concatArgs(target.DefaultArgs, launchOpts.ForwardedArgs)
+	spec, err := controlplane.BuildLaunchSpecWithOptions(root, manifest, launchOpts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		return 1
+		return controlplane.LaunchSpec{}, err
 	}
 	spec.Env = append(spec.Env,
 		"PI_WORKFLOW_TARGET="+target.Name,
-		"PI_WORKFLOW_SLICE="+target.Slice,
+		"PI_WORKFLOW_SLICE="+sliceName,
 	)
+	return spec, nil
+}
 
-	if err := controlplane.LaunchPi(spec); err != nil {
+func runTargetIndex(opts globalOptions, value string, forwarded []string) int {
+	index, err := strconv.Atoi(value)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: invalid target index %q (want a number from the picker, e.g. :2)\n", value)
+		return exitUsage
+	}
+	target, err := controlplane.TargetByIndex(index, opts.ShowHidden)
+	if err != nil {
 		return exitCodeForError(err)
 	}
-	return 0
+	return runTarget(opts, target.Name, forwarded)
 }
 
 func runSlice(opts globalOptions, sliceName string, forwarded []string) int {
-	root, err := controlplane.DetermineRoot(opts.Root)
+	spec, err := buildSliceSpec(opts, sliceName, forwarded)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	return launch(opts, spec)
+}
+
+func runValidateSlice(opts globalOptions, args []string) int {
+	if len(args) != 1 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(stderr, "error: slice --validate-only takes exactly one slice name")
+		return exitUsage
+	}
+	root, err := determineRoot(opts)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	file, err := controlplane.ValidateSliceFile(root, args[0])
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	fmt.Fprintf(chatter(opts, stdout), "slice %s is valid (%s)\n", file.Name, file.Path)
+	return exitOK
+}
+
+func buildSliceSpec(opts globalOptions, sliceName string, forwarded []string) (controlplane.LaunchSpec, error) {
+	root, err := determineRoot(opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		return 1
+		return controlplane.LaunchSpec{}, err
 	}
 
 	slices, err := controlplane.LoadSlices(root)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		return 1
+		return controlplane.LaunchSpec{}, err
 	}
 
-	manifest, ok := slices[sliceName]
-	if !ok {
-		fmt.Fprintf(os.Stderr, "error: unknown slice %q\n", sliceName)
-		return 2
+	manifest, err := controlplane.LookupSlice(slices, sliceName)
+	if err != nil {
+		return controlplane.LaunchSpec{}, err
 	}
+	logger.Debug("loaded slice", "slice", sliceName, "root", root, "extensions", len(manifest.Extensions))
 
-	spec, err := controlplane.BuildLaunchSpec(root, manifest, opts.Strict, opts.Profile, forwarded)
+	spec, err := controlplane.BuildLaunchSpecWithOptions(root, manifest, launchOptions(opts, forwarded))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		return 1
+		return controlplane.LaunchSpec{}, err
 	}
 	spec.Env = append(spec.Env,
 		"PI_WORKFLOW_TARGET=slice",
 		"PI_WORKFLOW_SLICE="+sliceName,
 	)
+	return spec, nil
+}
 
-	if err := controlplane.LaunchPi(spec); err != nil {
+func runArgs(opts globalOptions, args []string, forwarded []string) int {
+	line := false
+	rest := args
+	for len(rest) > 0 && rest[0] == "--line" {
+		line = true
+		rest = rest[1:]
+	}
+	if len(rest) == 0 {
+		fmt.Fprintln(stderr, "error: args command requires a target")
+		return exitUsage
+	}
+
+	spec, err := buildTargetSpec(opts, rest[0], append(rest[1:], forwarded...))
+	if err != nil {
 		return exitCodeForError(err)
 	}
-	return 0
+	printDiagnostics(opts, spec)
+
+	if line {
+		fmt.Fprintln(stdout, shellJoin(spec.Args))
+		return exitOK
+	}
+	for _, arg := range spec.Args {
+		fmt.Fprintln(stdout, arg)
+	}
+	return exitOK
+}
+
+func formatCommand(spec controlplane.LaunchSpec) string {
+	var parts []string
+	if removed := controlplane.EnvRemoved(spec.Env, os.Environ()); len(removed) > 0 {
+		parts = append(parts, "env")
+		for _, key := range removed {
+			parts = append(parts, "-u", shellQuote(key))
+		}
+	}
+	for _, entry := range controlplane.EnvDiff(spec.Env, os.Environ()) {
+		key, value, _ := strings.Cut(entry, "=")
+		parts = append(parts, key+"="+shellQuote(value))
+	}
+	if spec.Dir != "" {
+		parts = append([]string{"cd", shellQuote(spec.Dir), "&&"}, parts...)
+	}
+	parts = append(parts, "pi")
+	if len(spec.Args) > 0 {
+		parts = append(parts, shellJoin(spec.Args))
+	}
+	if spec.StdinFile != "" {
+		parts = append(parts, "<", shellQuote(spec.StdinFile))
+	}
+	return strings.Join(parts, " ")
+}
+
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-./:=@%+,", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func determineRoot(opts globalOptions) (string, error) {
+	return controlplane.DetermineRootTrace(opts.Root, func(message string) {
+		logger.Debug(message)
+	})
+}
+
+func launchOptions(opts globalOptions, forwarded []string) controlplane.LaunchOptions {
+	return controlplane.LaunchOptions{
+		Strict:           opts.Strict,
+		Profile:          opts.Profile,
+		AppendProfile:    opts.AppendProfile,
+		ForwardedArgs:    concatArgs(opts.FileArgs, forwarded),
+		StrictExtensions: opts.StrictExtensions,
+		StrictPaths:      opts.StrictPaths,
+		SkipExtensions:   opts.SkipExtensions,
+		OnlyExtensions:   opts.OnlyExtensions,
+		EnvFiles:         opts.EnvFiles,
+		NoEnvFile:        opts.NoEnvFile,
+		EnvProfile:       opts.EnvProfile,
+		Env:              opts.Env,
+		SkipRequires:     opts.SkipRequires,
+		BranchProfile:    opts.BranchProfile,
+		Dir:              opts.Cwd,
+		StdinFile:        opts.StdinFile,
+	}
+}
+
+func chatter(opts globalOptions, w io.Writer) io.Writer {
+	if opts.Quiet {
+		return io.Discard
+	}
+	return w
+}
+
+func printDiagnostics(opts globalOptions, spec controlplane.LaunchSpec) {
+	for _, warning := range spec.Warnings {
+		fmt.Fprintf(chatter(opts, stderr), "warning: %s\n", warning)
+	}
+	for _, note := range spec.Notes {
+		logger.Debug(note)
+	}
 }
 
-func pickTargetInteractive() (string, error) {
+func launch(opts globalOptions, spec controlplane.LaunchSpec) int {
+	printDiagnostics(opts, spec)
+
+	if opts.PrintCmd {
+		fmt.Fprintln(chatter(opts, stderr), formatCommand(spec))
+	}
+
+	if opts.Timeout > 0 {
+		spec.Timeout = opts.Timeout
+	}
+	if opts.StartupTimeout > 0 {
+		spec.StartupTimeout = opts.StartupTimeout
+	}
+	if err := writeLaunchTrace(opts, spec); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return recordExitCode(opts, exitFailure)
+	}
+	code := exitOK
+	if err := controlplane.LaunchPiWithRetry(spec, retryPolicy(opts)); err != nil {
+		code = exitCodeForError(err)
+	}
+	return recordExitCode(opts, code)
+}
+
+func recordExitCode(opts globalOptions, code int) int {
+	if opts.ExitCodeFile == "" {
+		return code
+	}
+	if err := os.WriteFile(opts.ExitCodeFile, []byte(fmt.Sprintf("%d\n", code)), 0o644); err != nil {
+		fmt.Fprintf(stderr, "error: write exit code file: %v\n", err)
+	}
+	return code
+}
+
+func retryPolicy(opts globalOptions) controlplane.RetryPolicy {
+	codes := opts.RetryCodes
+	if len(codes) == 0 {
+		codes = []int{defaultRetryCode}
+	}
+	return controlplane.RetryPolicy{
+		Retries:   opts.Retries,
+		Codes:     codes,
+		BaseDelay: defaultRetryDelay,
+		OnRetry: func(attempt int, code int, delay time.Duration) {
+			fmt.Fprintf(chatter(opts, stderr), "pictl: pi exited %d; retrying in %s (retry %d/%d)\n", code, delay, attempt, opts.Retries)
+		},
+	}
+}
+
+func readTargetFromStdin() (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			line = append(line, buf[0])
+			if buf[0] == '\n' {
+				break
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("read target from stdin: %w", err)
+		}
+	}
+	name := strings.TrimSpace(string(line))
+	if name == "" {
+		return "", errors.New("open -: no target name on stdin")
+	}
+	target, ok := controlplane.ResolveTarget(name)
+	if !ok {
+		return "", fmt.Errorf("open -: %w %q (read from stdin)", controlplane.ErrUnknownTarget, name)
+	}
+	return target.Name, nil
+}
+
+func pickTargetInteractive(ordered []controlplane.Target) (string, error) {
 	if !controlplane.IsTTY() {
 		return "", errors.New("no target specified and no interactive TTY available")
 	}
 
-	targets := controlplane.CanonicalTargets()
-	fmt.Println("Select workload target:")
-	for i, target := range targets {
-		fmt.Printf("%d) %-10s %s\n", i+1, target.Name, target.Description)
+	var targets []controlplane.Target
+	fmt.Fprintln(stdout, "Select workload target:")
+	for _, group := range controlplane.GroupTargets(ordered, "") {
+		fmt.Fprintf(stdout, "%s:\n", group.Category)
+		for _, target := range group.Targets {
+			targets = append(targets, target)
+			fmt.Fprintf(stdout, "  %d) %-10s %s\n", len(targets), target.Name, controlplane.TargetDescription(target))
+		}
 	}
 
 	reader := bufio.NewReader(os.Stdin)
 	for {
-		fmt.Print("Choice: ")
+		fmt.Fprint(stdout, "Choice: ")
 		line, err := reader.ReadString('\n')
 		if err != nil {
 			return "", err
@@ -334,7 +1340,7 @@ func pickTargetInteractive() (string, error) {
 
 		index, err := strconv.Atoi(line)
 		if err != nil || index < 1 || index > len(targets) {
-			fmt.Println("Invalid selection. Try again.")
+			fmt.Fprintln(stdout, "Invalid selection. Try again.")
 			continue
 		}
 
@@ -344,7 +1350,7 @@ func pickTargetInteractive() (string, error) {
 
 func exitCodeForError(err error) int {
 	if err == nil {
-		return 0
+		return exitOK
 	}
 
 	var exitErr *exec.ExitError
@@ -352,6 +1358,19 @@ func exitCodeForError(err error) int {
 		return exitErr.ExitCode()
 	}
 
-	fmt.Fprintf(os.Stderr, "error: %v\n", err)
-	return 1
+	fmt.Fprintf(stderr, "error: %v\n", err)
+	switch {
+	case errors.Is(err, controlplane.ErrRootNotFound), errors.Is(err, controlplane.ErrInvalidRoot):
+		return exitRootNotFound
+	case errors.Is(err, controlplane.ErrUnknownTarget), errors.Is(err, controlplane.ErrUnknownEnvProfile), errors.Is(err, controlplane.ErrUnknownProfile):
+		return exitUsage
+	case errors.Is(err, controlplane.ErrSliceNotFound), errors.Is(err, controlplane.ErrExtensionMissing), errors.Is(err, controlplane.ErrNoSlicesDir), errors.Is(err, controlplane.ErrMissingRequirements):
+		return exitMissing
+	case errors.Is(err, controlplane.ErrPiNotFound):
+		return exitPiNotFound
+	case errors.Is(err, controlplane.ErrLaunchTimeout):
+		return exitTimeout
+	default:
+		return exitFailure
+	}
 }
@@ -0,0 +1,1835 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+func writeFixtureRoot(t *testing.T, slices map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	t.Setenv("PICTL_STATE_FILE", filepath.Join(t.TempDir(), "state.json"))
+	files := map[string]string{
+		"settings.json":   "{}",
+		"extensions/x.ts": "export default function () {}",
+	}
+	for name, body := range slices {
+		files[filepath.Join("slices", name+".json")] = body
+	}
+
+	for rel, body := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func writeFakePi(t *testing.T, script string) {
+	t.Helper()
+
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "pi"), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+}
+
+func captureOutput(t *testing.T) (*bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+
+	prevOut, prevErr := stdout, stderr
+	outBuf, errBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	stdout, stderr = outBuf, errBuf
+	t.Cleanup(func() {
+		stdout, stderr = prevOut, prevErr
+	})
+	return outBuf, errBuf
+}
+
+const validSlice = `{"description": "test", "defaultProfile": "meta", "extensions": ["extensions/x.ts"]}`
+
+func TestRunExitCodeUsage(t *testing.T) {
+	captureOutput(t)
+
+	if code := run([]string{"no-such-target"}); code != exitUsage {
+		t.Fatalf("expected exit %d, got %d", exitUsage, code)
+	}
+}
+
+func TestRunExitCodeRootNotFound(t *testing.T) {
+	captureOutput(t)
+
+	if code := run([]string{"--root", t.TempDir(), "slices"}); code != exitRootNotFound {
+		t.Fatalf("expected exit %d, got %d", exitRootNotFound, code)
+	}
+	if code := run([]string{"--root", t.TempDir(), "doctor"}); code != exitRootNotFound {
+		t.Fatalf("expected exit %d, got %d", exitRootNotFound, code)
+	}
+}
+
+func TestRunExitCodeSliceMissing(t *testing.T) {
+	captureOutput(t)
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+
+	if code := run([]string{"--root", root, "build"}); code != exitMissing {
+		t.Fatalf("expected exit %d for target with missing slice, got %d", exitMissing, code)
+	}
+	if code := run([]string{"--root", root, "slice", "nope"}); code != exitMissing {
+		t.Fatalf("expected exit %d for unknown slice, got %d", exitMissing, code)
+	}
+}
+
+func TestRunExitCodeExtensionMissing(t *testing.T) {
+	captureOutput(t)
+	root := writeFixtureRoot(t, map[string]string{
+		"meta": `{"extensions": ["extensions/missing.ts"]}`,
+	})
+
+	if code := run([]string{"--root", root, "meta"}); code != exitMissing {
+		t.Fatalf("expected exit %d, got %d", exitMissing, code)
+	}
+}
+
+func TestRunExitCodePiNotFound(t *testing.T) {
+	captureOutput(t)
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	t.Setenv("PATH", t.TempDir())
+
+	if code := run([]string{"--root", root, "meta"}); code != exitPiNotFound {
+		t.Fatalf("expected exit %d, got %d", exitPiNotFound, code)
+	}
+}
+
+func TestRunExitCodePassesThroughPi(t *testing.T) {
+	captureOutput(t)
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	writeFakePi(t, "exit 7")
+
+	if code := run([]string{"--root", root, "meta"}); code != 7 {
+		t.Fatalf("expected pi exit code 7, got %d", code)
+	}
+	if code := run([]string{"--root", root, "slice", "meta"}); code != 7 {
+		t.Fatalf("expected pi exit code 7, got %d", code)
+	}
+}
+
+func TestRunRetriesOnlyOnRetryableCodes(t *testing.T) {
+	_, errOut := captureOutput(t)
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	writeFakePi(t, "exit 7")
+
+	if code := run([]string{"--root", root, "--retries", "2", "--retry-on", "75,76", "meta"}); code != 7 {
+		t.Fatalf("expected pi exit code 7, got %d", code)
+	}
+	if strings.Contains(errOut.String(), "retrying") {
+		t.Fatalf("expected no retry for non-retryable exit, got %q", errOut.String())
+	}
+
+	for _, args := range [][]string{{"--retries", "-1"}, {"--retry-on", "x"}} {
+		if code := run(append(append([]string{"--root", root}, args...), "meta")); code != exitUsage {
+			t.Fatalf("expected usage exit for %v, got %d", args, code)
+		}
+	}
+}
+
+func TestRunForwardsUnknownFlagsBeforeTarget(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"software": validSlice})
+	record := filepath.Join(t.TempDir(), "args")
+	writeFakePi(t, `echo "$*" > "`+record+`"`)
+
+	captureOutput(t)
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--unknown-flag", "build"}, "x.ts --unknown-flag"},
+		{[]string{"--model", "x", "build", "--", "hi"}, "x.ts --model x hi"},
+		{[]string{"--model=meta", "build"}, "x.ts --model=meta"},
+	}
+	for _, tc := range cases {
+		if code := run(append([]string{"--root", root}, tc.args...)); code != exitOK {
+			t.Fatalf("%v: expected exit %d, got %d", tc.args, exitOK, code)
+		}
+		got, _ := os.ReadFile(record)
+		if !strings.HasSuffix(strings.TrimSpace(string(got)), tc.want) {
+			t.Fatalf("%v: expected pi args to end with %q, got %q", tc.args, tc.want, got)
+		}
+	}
+
+	if code := run([]string{"--root", root, "--unknown-flag", "--strict", "build"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if got, _ := os.ReadFile(record); !strings.Contains(string(got), "--no-skills") || !strings.HasSuffix(strings.TrimSpace(string(got)), "x.ts --unknown-flag") {
+		t.Fatalf("expected --strict to apply and --unknown-flag to be forwarded, got %q", got)
+	}
+
+	if code := run([]string{"--root", root, "--unknown-flag", "doctor"}); code != exitUsage {
+		t.Fatalf("expected usage exit for unknown flag before a command, got %d", code)
+	}
+}
+
+func TestRunArgsFileForwardsBeforeInlineArgs(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"software": validSlice})
+	argsFile := filepath.Join(t.TempDir(), "pi.args")
+	if err := os.WriteFile(argsFile, []byte("# long flags\n--model 'openai/gpt 5'\n\n--thinking high\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--args-file", argsFile, "args", "--line", "build", "--", "--thinking", "low"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if !strings.HasSuffix(out.String(), "x.ts --model 'openai/gpt 5' --thinking high --thinking low\n") {
+		t.Fatalf("expected args-file args before inline args, got %q", out.String())
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.args")
+	if code := run([]string{"--root", root, "--args-file", missing, "build"}); code != exitFailure {
+		t.Fatalf("expected failure exit for missing args file, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "args file "+missing) {
+		t.Fatalf("expected error naming the missing file, got %q", errOut.String())
+	}
+}
+
+func TestTargetDefaultArgsComeBeforeUserArgs(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"software": validSlice})
+	target, _ := controlplane.ResolveTarget("build")
+	target.DefaultArgs = []string{"--thinking", "high"}
+
+	spec, err := buildResolvedTargetSpec(globalOptions{Root: root}, target, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(spec.Args[len(spec.Args)-2:], " "); got != "--thinking high" {
+		t.Fatalf("expected target default args in spec, got %q", spec.Args)
+	}
+
+	spec, err = buildResolvedTargetSpec(globalOptions{Root: root}, target, []string{"--thinking", "low"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(spec.Args[len(spec.Args)-4:], " "); got != "--thinking high --thinking low" {
+		t.Fatalf("expected user args after target defaults so they win, got %q", spec.Args)
+	}
+}
+
+func TestRunTargetByIndex(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice, "software": validSlice})
+	record := filepath.Join(t.TempDir(), "target")
+	writeFakePi(t, `echo "$PI_WORKFLOW_TARGET" > "`+record+`"`)
+
+	_, errOut := captureOutput(t)
+	for _, args := range [][]string{{":2"}, {"--index", "2"}} {
+		if code := run(append([]string{"--root", root}, args...)); code != exitOK {
+			t.Fatalf("%v: expected exit %d, got %d", args, exitOK, code)
+		}
+		if got, _ := os.ReadFile(record); strings.TrimSpace(string(got)) != "build" {
+			t.Fatalf("%v: expected the 2nd picker target (build), got %q", args, got)
+		}
+	}
+
+	if code := run([]string{"--root", root, ":9"}); code != exitUsage {
+		t.Fatalf("expected usage exit for out-of-range index, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "out of range (1-4)") {
+		t.Fatalf("expected range in error, got %q", errOut.String())
+	}
+	if code := run([]string{"--root", root, ":x"}); code != exitUsage {
+		t.Fatalf("expected usage exit for non-numeric index, got %d", code)
+	}
+}
+
+func TestRunProfilesJSON(t *testing.T) {
+	out, _ := captureOutput(t)
+
+	if code := run([]string{"profiles", "--json"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+
+	var profiles []struct {
+		Name    string   `json:"name"`
+		Aliases []string `json:"aliases"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &profiles); err != nil {
+		t.Fatalf("invalid profiles JSON: %v\n%s", err, out.String())
+	}
+	if len(profiles) == 0 || profiles[0].Name != "ultrathink" {
+		t.Fatalf("unexpected profiles output: %+v", profiles)
+	}
+}
+
+func TestRunSlicesHidesDisabledUnlessAll(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"meta":    validSlice,
+		"retired": `{"enabled": false, "extensions": ["extensions/x.ts"]}`,
+	})
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "slices"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if strings.Contains(out.String(), "retired") {
+		t.Fatalf("did not expect disabled slice in default listing:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "slices", "--all"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if !strings.Contains(out.String(), "retired") || !strings.Contains(out.String(), "[disabled]") {
+		t.Fatalf("expected disabled slice with --all:\n%s", out.String())
+	}
+}
+
+func TestRunSlicesTagFilter(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"meta":     `{"tags": ["core"], "extensions": ["extensions/x.ts"]}`,
+		"software": `{"tags": ["core", "Experimental"], "extensions": ["extensions/x.ts"]}`,
+		"daybook":  `{"tags": ["experimental"], "extensions": ["extensions/x.ts"]}`,
+		"sysadmin": validSlice,
+	})
+	names := func(t *testing.T, args ...string) []string {
+		t.Helper()
+		out, errOut := captureOutput(t)
+		if code := run(append([]string{"--root", root, "--json", "slices"}, args...)); code != exitOK {
+			t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+		}
+		var listing []struct {
+			Name string   `json:"name"`
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &listing); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+		}
+		got := []string{}
+		for _, slice := range listing {
+			got = append(got, slice.Name)
+		}
+		return got
+	}
+
+	cases := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"single tag", []string{"--tag", "core"}, []string{"meta", "software"}},
+		{"and", []string{"--tag", "core", "--tag=experimental"}, []string{"software"}},
+		{"or", []string{"--tag", "core", "--tag", "experimental", "--tag-match", "any"}, []string{"daybook", "meta", "software"}},
+		{"no match", []string{"--tag", "nope"}, []string{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := names(t, tc.args...); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	out, _ := captureOutput(t)
+	run([]string{"--root", root, "slices", "--tag", "experimental"})
+	if !strings.Contains(out.String(), "[tags: core, Experimental]") || strings.Contains(out.String(), "meta") {
+		t.Fatalf("expected only the tagged slices, with their tags:\n%s", out.String())
+	}
+	if code := run([]string{"--root", root, "slices", "--unused", "--tag", "core"}); code != exitUsage {
+		t.Fatalf("expected --tag with --unused to be a usage error, got %d", code)
+	}
+}
+
+func TestRunSlicesSummary(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"meta":     validSlice,
+		"software": `{"defaultProfile": "build", "extensions": ["extensions/x.ts", "extensions/y.ts"]}`,
+		"scratch":  `{"extensions": ["extensions/x.ts"]}`,
+		"retired":  `{"enabled": false, "defaultProfile": "fast", "extensions": ["extensions/z.ts"]}`,
+	})
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "slices", "--summary"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	for _, want := range []string{
+		"slices: 4 (3 enabled, 1 disabled)\n",
+		"distinct extensions: 2\n",
+		"profiles: execute=1, none=1, ultrathink=1\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in summary:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "--json", "slices", "--summary"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	var report struct {
+		Summary struct {
+			Total      int            `json:"total"`
+			Enabled    int            `json:"enabled"`
+			Disabled   int            `json:"disabled"`
+			Extensions int            `json:"extensions"`
+			Profiles   map[string]int `json:"profiles"`
+		} `json:"summary"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", out.String(), err)
+	}
+	if report.Summary.Total != 4 || report.Summary.Disabled != 1 || report.Summary.Extensions != 2 || report.Summary.Profiles["execute"] != 1 {
+		t.Fatalf("unexpected summary: %+v", report.Summary)
+	}
+}
+
+func TestRunSlicesUnusedAndOrphans(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice, "scratch": validSlice})
+	orphan := filepath.Join(root, "extensions", "lonely", "index.ts")
+	if err := os.MkdirAll(filepath.Dir(orphan), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(orphan, []byte("export default function () {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "slices", "--unused"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if out.String() != "scratch\n" {
+		t.Fatalf("expected only scratch to be unused, got %q", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "--json", "slices", "--orphans"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	var orphans []string
+	if err := json.Unmarshal(out.Bytes(), &orphans); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", out.String(), err)
+	}
+	if len(orphans) != 1 || orphans[0] != "extensions/lonely/index.ts" {
+		t.Fatalf("expected one orphaned extension, got %v", orphans)
+	}
+
+	if code := run([]string{"--root", root, "slices", "--unused", "--orphans"}); code != exitUsage {
+		t.Fatalf("expected usage exit for conflicting flags, got %d", code)
+	}
+}
+
+func TestRunExtensionsInventory(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice, "software": validSlice})
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "extensions"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if !strings.HasPrefix(out.String(), "extensions/x.ts") || !strings.HasSuffix(out.String(), " meta, software\n") {
+		t.Fatalf("expected shared extension with both slices, got %q", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "--json", "extensions"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	var inventory []struct {
+		Path   string   `json:"path"`
+		Slices []string `json:"slices"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &inventory); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", out.String(), err)
+	}
+	if len(inventory) != 1 || inventory[0].Path != "extensions/x.ts" || len(inventory[0].Slices) != 2 {
+		t.Fatalf("expected one extension used by two slices, got %+v", inventory)
+	}
+}
+
+func TestRunDisabledSliceLaunchRejected(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"retired": `{"enabled": false, "extensions": ["extensions/x.ts"]}`,
+	})
+	writeFakePi(t, "exit 0")
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "slice", "retired"}); code == exitOK {
+		t.Fatalf("expected disabled slice launch to fail")
+	}
+	if !strings.Contains(errOut.String(), "slice is disabled") {
+		t.Fatalf("expected disabled message, got %q", errOut.String())
+	}
+}
+
+func TestRunExitCodeTimeout(t *testing.T) {
+	captureOutput(t)
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	writeFakePi(t, "while :; do :; done")
+
+	if code := run([]string{"--root", root, "--timeout", "100ms", "meta"}); code != exitTimeout {
+		t.Fatalf("expected exit %d, got %d", exitTimeout, code)
+	}
+	if code := run([]string{"--timeout=soon", "meta"}); code != exitUsage {
+		t.Fatalf("expected usage exit for invalid duration, got %d", code)
+	}
+}
+
+func TestRunExitCodeStartupTimeout(t *testing.T) {
+	_, errOut := captureOutput(t)
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	writeFakePi(t, "while :; do :; done")
+
+	if code := run([]string{"--root", root, "--launch-timeout-startup", "100ms", "--timeout", "1h", "meta"}); code != exitTimeout {
+		t.Fatalf("expected exit %d, got %d", exitTimeout, code)
+	}
+	if !strings.Contains(errOut.String(), "startup timeout") {
+		t.Fatalf("expected the startup timeout in the error, got %q", errOut.String())
+	}
+	if code := run([]string{"--launch-timeout-startup=-1s", "meta"}); code != exitUsage {
+		t.Fatalf("expected usage exit for a negative duration, got %d", code)
+	}
+}
+
+func TestRunLockThenCompare(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	lockPath := filepath.Join(root, "pictl.lock.json")
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "lock", "--write"}); code != exitOK {
+		t.Fatalf("expected lock --write to succeed, got %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "wrote "+lockPath) {
+		t.Fatalf("expected the lockfile path, got %q", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "doctor", "--compare-lockfile", lockPath}); code != exitOK {
+		t.Fatalf("expected a matching lockfile to pass, got %d:\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "matches "+lockPath) {
+		t.Fatalf("expected a lockfile match, got:\n%s", out.String())
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "slices", "meta.json"), []byte(`{"description": "test", "defaultProfile": "fast", "extensions": ["extensions/x.ts"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := run([]string{"--root", root, "doctor", "--compare-lockfile=" + lockPath}); code != exitFailure {
+		t.Fatalf("expected drift to fail, got %d:\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), `slice meta changed: defaultProfile "meta" -> "fast"`) {
+		t.Fatalf("expected the drifted field, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "lock", "--write"}); code != exitOK {
+		t.Fatalf("expected lock --write to regenerate, got %d", code)
+	}
+	if code := run([]string{"--root", root, "doctor", "--compare-lockfile", lockPath}); code != exitOK {
+		t.Fatalf("expected the regenerated lockfile to match, got %d", code)
+	}
+}
+
+func TestRunDoctorStrictPromotesWarnings(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice, "scratch": validSlice})
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "doctor"}); code != exitOK {
+		t.Fatalf("expected warnings to pass without --strict, got %d", code)
+	}
+	if code := run([]string{"--root", root, "doctor", "--strict"}); code != exitFailure {
+		t.Fatalf("expected warnings to fail with --strict, got %d", code)
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "--json", "doctor", "--strict"}); code != exitFailure {
+		t.Fatalf("expected --strict --json to fail, got %d", code)
+	}
+	var report struct {
+		OK     bool `json:"ok"`
+		Checks []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("expected JSON report, got %q: %v", out.String(), err)
+	}
+	if report.OK {
+		t.Fatalf("expected ok=false under --strict")
+	}
+	for _, check := range report.Checks {
+		if check.Name == "unused-slices" && check.Status != "fail" {
+			t.Fatalf("expected unused-slices to be promoted to fail, got %q", check.Status)
+		}
+	}
+}
+
+func TestRunDoctorCheckPiFlags(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice, "software": validSlice, "sysadmin": validSlice, "daybook": validSlice})
+	writeFakePi(t, "echo '-e <path> --no-extensions --no-skills --no-themes'")
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "doctor", "--check-pi-flags"}); code != exitFailure {
+		t.Fatalf("expected exit %d, got %d", exitFailure, code)
+	}
+	if !strings.Contains(out.String(), "pi-flags: pi --help does not list --no-prompt-templates") {
+		t.Fatalf("expected missing flag to be reported:\n%s", out.String())
+	}
+}
+
+func TestRunDoctorRootTrace(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	nested := filepath.Join(root, "extensions")
+	t.Setenv("PI_AGENT_CONFIG_ROOT", "")
+	t.Chdir(nested)
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"doctor", "--root-trace"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	for _, want := range []string{"root markers (all of): ", "--root not given", "no root markers in " + nested, "root: " + root} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in trace:\n%s", want, out.String())
+		}
+	}
+
+	if code := run([]string{"--root", t.TempDir(), "doctor", "--root-trace"}); code != exitRootNotFound {
+		t.Fatalf("expected root-not-found exit for bad --root, got %d", code)
+	}
+	if code := run([]string{"doctor", "--root-trace", "--fix"}); code != exitUsage {
+		t.Fatalf("expected usage exit when combined with --fix, got %d", code)
+	}
+}
+
+func TestRunDoctorFixPreviewsThenWrites(t *testing.T) {
+	raw := `{"extensions": [" extensions/x.ts ", ""]}`
+	root := writeFixtureRoot(t, map[string]string{"meta": raw})
+	manifestPath := filepath.Join(root, "slices", "meta.json")
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "doctor", "--fix"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if !strings.Contains(out.String(), `+  "schemaVersion": 1`) {
+		t.Fatalf("expected diff preview, got:\n%s", out.String())
+	}
+	if current, _ := os.ReadFile(manifestPath); string(current) != raw {
+		t.Fatalf("expected preview to leave manifest untouched, got %s", current)
+	}
+
+	if err := os.Chmod(manifestPath, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"--root", root, "doctor", "--fix", "--write"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	fixed, _ := os.ReadFile(manifestPath)
+	info, err := os.Stat(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected --write to keep file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "doctor", "--fix", "--write"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	again, _ := os.ReadFile(manifestPath)
+	if string(fixed) != string(again) || out.Len() != 0 {
+		t.Fatalf("expected second fix to be a no-op, got output %q", out.String())
+	}
+}
+
+func TestRunDoctorFixRefusesUnparseableManifest(t *testing.T) {
+	raw := `{"extensions": [`
+	root := writeFixtureRoot(t, map[string]string{"broken": raw})
+
+	captureOutput(t)
+	if code := run([]string{"--root", root, "doctor", "--fix", "--write"}); code != exitFailure {
+		t.Fatalf("expected exit %d, got %d", exitFailure, code)
+	}
+	if current, _ := os.ReadFile(filepath.Join(root, "slices", "broken.json")); string(current) != raw {
+		t.Fatalf("expected broken manifest to be left alone, got %s", current)
+	}
+}
+
+func TestRunDoctorFixSkipsCommentedManifest(t *testing.T) {
+	raw := "{\n  // keep me\n  \"extensions\": [\"extensions/x.ts\",],\n}\n"
+	root := writeFixtureRoot(t, map[string]string{"meta": raw})
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "doctor", "--fix", "--write"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if !strings.Contains(out.String(), "skipped") {
+		t.Fatalf("expected skip notice, got %q", out.String())
+	}
+	if current, _ := os.ReadFile(filepath.Join(root, "slices", "meta.json")); string(current) != raw {
+		t.Fatalf("expected commented manifest to be left alone, got %s", current)
+	}
+}
+
+func TestRunArgsPrintsArgv(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	extPath := filepath.Join(root, "extensions", "x.ts")
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "--strict", "args", "meta", "--", "--model", "a b"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	want := strings.Join([]string{"--no-extensions", "--no-skills", "--no-prompt-templates", "--no-themes", "-e", extPath, "--model", "a b"}, "\n") + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected argv:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "args", "--line", "meta", "--", "--model", "a b"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if out.String() != "--no-extensions -e "+extPath+" --model 'a b'\n" {
+		t.Fatalf("unexpected single-line argv: %q", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "args", "meta", "--line"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if out.String() != strings.Join([]string{"--no-extensions", "-e", extPath, "--line"}, "\n")+"\n" {
+		t.Fatalf("expected --line after the target to be forwarded, got %q", out.String())
+	}
+}
+
+func TestRunArgsPrintsExtensionWarnings(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": `{"extensions": ["extensions/empty.ts"]}`})
+	if err := os.WriteFile(filepath.Join(root, "extensions", "empty.ts"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "args", "meta"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if !strings.Contains(errOut.String(), "warning: ") {
+		t.Fatalf("expected extension warning on stderr, got %q", errOut.String())
+	}
+}
+
+func TestRunArgsReportsLaunchErrors(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": `{"extensions": ["extensions/missing.ts"]}`})
+	captureOutput(t)
+
+	if code := run([]string{"--root", root, "args", "meta"}); code != exitMissing {
+		t.Fatalf("expected exit %d, got %d", exitMissing, code)
+	}
+	if code := run([]string{"--root", root, "args", "nope"}); code != exitUsage {
+		t.Fatalf("expected exit %d, got %d", exitUsage, code)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	cases := map[string]string{
+		"plain/path.ts": "plain/path.ts",
+		"a b":           "'a b'",
+		"it's":          `'it'\''s'`,
+		"":              "''",
+	}
+	for in, want := range cases {
+		if got := shellQuote(in); got != want {
+			t.Fatalf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRunPrintCmdEchoesCommandBeforeLaunch(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	writeFakePi(t, "exit 0")
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--print-cmd", "meta", "--", "--model", "a b"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+
+	extPath := filepath.Join(root, "extensions", "x.ts")
+	want := "PI_DEFAULT_PROFILE=meta PI_WORKFLOW_TARGET=meta PI_WORKFLOW_SLICE=meta pi --no-extensions -e " + extPath + " --model 'a b'\n"
+	if errOut.String() != want {
+		t.Fatalf("unexpected printed command:\n got %q\nwant %q", errOut.String(), want)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected nothing on stdout, got %q", out.String())
+	}
+}
+
+func TestRunPrintCmdUnsetsFilteredEnv(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	writeFakePi(t, "exit 0")
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+	t.Setenv("PICTL_TEST_SECRET", "hunter2")
+	t.Setenv("PICTL_BLOCK_ENV", "PICTL_TEST_SECRET")
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--print-cmd", "meta"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if !strings.HasPrefix(errOut.String(), "env -u PICTL_TEST_SECRET PI_DEFAULT_PROFILE=meta ") {
+		t.Fatalf("expected env -u prefix for blocked variable, got %q", errOut.String())
+	}
+	if strings.Contains(errOut.String(), "hunter2") {
+		t.Fatalf("expected blocked value not to be printed, got %q", errOut.String())
+	}
+}
+
+func TestRunPrintCmdAppliesEnvProfile(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	writeFakePi(t, "exit 0")
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+	if err := os.WriteFile(filepath.Join(root, "env-profiles.json"), []byte(`{"dev": {"PICTL_TEST_API": "http://localhost"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--env-profile", "dev", "--print-cmd", "meta"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "PICTL_TEST_API=http://localhost ") {
+		t.Fatalf("expected env profile variable in printed command, got %q", errOut.String())
+	}
+
+	errOut.Reset()
+	if code := run([]string{"--root", root, "--env-profile", "prod", "--print-cmd", "meta"}); code != exitUsage {
+		t.Fatalf("expected unknown env profile to exit %d, got %d", exitUsage, code)
+	}
+	if !strings.Contains(errOut.String(), `unknown env profile "prod"`) {
+		t.Fatalf("unexpected error output %q", errOut.String())
+	}
+}
+
+func TestRunDoctorSinceValidatesRecentSlices(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"meta":   validSlice,
+		"broken": `{"description": "old", "extensions": ["extensions/missing.ts"]}`,
+	})
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "slices", "broken.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "doctor", "--since", "1h"}); code != exitOK {
+		t.Fatalf("expected only the recent slice to be validated, got %d: %s%s", code, out.String(), errOut.String())
+	}
+	if !strings.Contains(out.String(), "changed-slices: meta (by mtime)") || strings.Contains(out.String(), "broken") {
+		t.Fatalf("unexpected doctor output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "doctor", "--since=72h"}); code != exitFailure {
+		t.Fatalf("expected the older broken slice to fail in a wider window, got %d", code)
+	}
+}
+
+func TestRunProfileCatalog(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	writeFakePi(t, "exit 0")
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+	t.Cleanup(func() { controlplane.UseProfileCatalog("", nil) })
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--print-cmd", "--profile", "turbo", "meta"}); code != exitOK {
+		t.Fatalf("expected unknown profile to pass through without a catalog, got %d: %s", code, errOut.String())
+	}
+
+	catalog := `[{"name": "ultrathink", "aliases": ["meta"], "description": "Deep"}, {"name": "research", "aliases": ["dig"], "description": "Read-heavy"}]`
+	if err := os.WriteFile(filepath.Join(root, "profiles.json"), []byte(catalog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	errOut.Reset()
+	if code := run([]string{"--root", root, "--print-cmd", "--profile", "turbo", "meta"}); code != exitUsage {
+		t.Fatalf("expected unknown profile to be rejected with a catalog, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), `unknown profile "turbo"`) {
+		t.Fatalf("unexpected error output %q", errOut.String())
+	}
+	if code := run([]string{"--root", root, "--print-cmd", "--profile", "dig", "meta"}); code != exitOK {
+		t.Fatalf("expected catalog alias to launch, got %d: %s", code, errOut.String())
+	}
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "profiles"}); code != exitOK {
+		t.Fatalf("expected profiles to succeed, got %d", code)
+	}
+	if !strings.Contains(out.String(), "research") || strings.Contains(out.String(), "execute") {
+		t.Fatalf("expected profiles to list the catalog, got:\n%s", out.String())
+	}
+
+	override := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(override, []byte(`[{"name": "meta"}, {"name": "turbo"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, errOut = captureOutput(t)
+	if code := run([]string{"--root", root, "--profile-file", override, "--print-cmd", "--profile", "turbo", "meta"}); code != exitOK {
+		t.Fatalf("expected --profile-file catalog to accept turbo, got %d: %s", code, errOut.String())
+	}
+	if code := run([]string{"--root", root, "--profile-file", filepath.Join(root, "missing.json"), "profiles"}); code != exitFailure {
+		t.Fatalf("expected a missing --profile-file to fail, got %d", code)
+	}
+}
+
+func TestRunDoctorReportsMissingSlicesDir(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	t.Setenv("PICTL_ROOT_MARKERS", "settings.json")
+	if err := os.RemoveAll(filepath.Join(root, "slices")); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "--no-color", "doctor"}); code != exitFailure {
+		t.Fatalf("expected exit %d, got %d", exitFailure, code)
+	}
+	if !strings.Contains(out.String(), "slices-dir: no slices/ directory found under "+root) {
+		t.Fatalf("expected a distinct slices-dir failure, got:\n%s", out.String())
+	}
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "slices"}); code != exitMissing {
+		t.Fatalf("expected exit %d, got %d", exitMissing, code)
+	}
+	if !strings.Contains(errOut.String(), "create one or check --root") {
+		t.Fatalf("expected guidance, got %q", errOut.String())
+	}
+}
+
+func TestRunDoctorWriteReport(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"meta":   validSlice,
+		"broken": `{"description": "x", "extensions": ["extensions/missing.ts"]}`,
+	})
+	path := filepath.Join(t.TempDir(), "report.json")
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "--json", "doctor", "--write-report", path}); code != exitFailure {
+		t.Fatalf("expected the failing doctor exit code to survive --write-report, got %d", code)
+	}
+	var console, file map[string]any
+	if err := json.Unmarshal(out.Bytes(), &console); err != nil {
+		t.Fatalf("invalid console JSON: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected report file: %v", err)
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		t.Fatalf("invalid report JSON: %v", err)
+	}
+
+	if _, err := time.Parse(time.RFC3339, fmt.Sprint(file["generatedAt"])); err != nil {
+		t.Fatalf("expected an RFC 3339 timestamp, got %v", file["generatedAt"])
+	}
+	if file["pictlVersion"] == "" || file["pictlVersion"] == nil {
+		t.Fatalf("expected the pictl version in the report")
+	}
+	delete(file, "generatedAt")
+	delete(file, "pictlVersion")
+	if !reflect.DeepEqual(console, file) {
+		t.Fatalf("expected report to match console JSON:\nconsole %v\nfile    %v", console, file)
+	}
+
+	captureOutput(t)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"--root", root, "doctor", "--write-report=" + path}); code != exitFailure {
+		t.Fatalf("expected exit %d with text output, got %d", exitFailure, code)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected report alongside text output: %v", err)
+	}
+}
+
+func TestRunRepairAliases(t *testing.T) {
+	out, _ := captureOutput(t)
+	if code := run([]string{"--no-color", "doctor", "--repair-aliases"}); code != exitOK {
+		t.Fatalf("expected the built-in catalog to need no repairs, got %d", code)
+	}
+	if !strings.Contains(out.String(), "nothing to repair") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	colorEnabled = false
+	out.Reset()
+	targets := []controlplane.Target{
+		{Name: "build", Aliases: []string{"admin"}},
+		{Name: "ops", Aliases: []string{"admin"}},
+	}
+	if code := runRepairAliases(globalOptions{}, targets); code != exitFailure {
+		t.Fatalf("expected collisions to exit %d, got %d", exitFailure, code)
+	}
+	want := "✗ alias \"admin\" resolves to build; also claimed by ops\n" +
+		"  suggest: rename ops's alias \"admin\" to \"ops-admin\" (build keeps \"admin\")\n" +
+		"targets are built into pictl, so apply these in the target catalog source\n"
+	if out.String() != want {
+		t.Fatalf("unexpected suggestions:\n got %q\nwant %q", out.String(), want)
+	}
+}
+
+func TestRunSkipExtLogsUnderVerbose(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": `{"defaultProfile": "meta", "extensions": ["extensions/x.ts", "extensions/y.ts"]}`})
+	if err := os.WriteFile(filepath.Join(root, "extensions", "y.ts"), []byte("export default function () {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeFakePi(t, "exit 0")
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--verbose", "--skip-ext", "y.ts", "--print-cmd", "meta"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "skipped extension "+filepath.Join(root, "extensions", "y.ts")) {
+		t.Fatalf("expected verbose skip note, got %q", errOut.String())
+	}
+	if strings.Contains(errOut.String(), "-e "+filepath.Join(root, "extensions", "y.ts")) {
+		t.Fatalf("expected y.ts to be left out of the command, got %q", errOut.String())
+	}
+}
+
+func TestRunVersion(t *testing.T) {
+	out, _ := captureOutput(t)
+
+	if code := run([]string{"--version"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	if !strings.HasPrefix(out.String(), "pictl ") {
+		t.Fatalf("unexpected version output: %q", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"version", "--json"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	var info map[string]string
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("invalid version JSON: %v\n%s", err, out.String())
+	}
+	for _, key := range []string{"version", "commit", "date", "goVersion"} {
+		if info[key] == "" {
+			t.Fatalf("expected %q in version JSON, got %v", key, info)
+		}
+	}
+}
+
+func TestTargetProfilePrecedence(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": `{"description": "test", "extensions": ["extensions/x.ts"]}`})
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+	os.Unsetenv("PI_DEFAULT_PROFILE")
+
+	profileOf := func(opts globalOptions) string {
+		t.Helper()
+		spec, err := buildTargetSpec(opts, "meta", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, entry := range spec.Env {
+			if value, ok := strings.CutPrefix(entry, "PI_DEFAULT_PROFILE="); ok {
+				return value
+			}
+		}
+		return ""
+	}
+
+	if got := profileOf(globalOptions{Root: root}); got != "meta" {
+		t.Fatalf("expected built-in default meta, got %q", got)
+	}
+
+	captureOutput(t)
+	if code := run([]string{"set-profile", "meta", "quick"}); code != exitOK {
+		t.Fatalf("expected set-profile to succeed, got %d", code)
+	}
+	if got := profileOf(globalOptions{Root: root}); got != "fast" {
+		t.Fatalf("expected saved default fast to beat built-in default, got %q", got)
+	}
+	if got := profileOf(globalOptions{Root: root, Profile: "execute"}); got != "execute" {
+		t.Fatalf("expected --profile to beat saved default, got %q", got)
+	}
+
+	t.Setenv("PI_DEFAULT_PROFILE", "ship")
+	if got := profileOf(globalOptions{Root: root}); got != "ship" {
+		t.Fatalf("expected inherited env to beat saved default, got %q", got)
+	}
+	os.Unsetenv("PI_DEFAULT_PROFILE")
+
+	if code := run([]string{"unset-profile", "meta"}); code != exitOK {
+		t.Fatalf("expected unset-profile to succeed, got %d", code)
+	}
+	if got := profileOf(globalOptions{Root: root}); got != "meta" {
+		t.Fatalf("expected built-in default after unset, got %q", got)
+	}
+
+	if code := run([]string{"set-profile", "meta", "turbo"}); code != exitUsage {
+		t.Fatalf("expected unknown profile to be a usage error, got %d", code)
+	}
+	if code := run([]string{"set-profile", "nope", "fast"}); code != exitUsage {
+		t.Fatalf("expected unknown target to be a usage error, got %d", code)
+	}
+}
+
+func TestRunDoctorBenchmark(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "doctor", "--benchmark"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d:\n%s", exitOK, code, out.String())
+	}
+	for _, want := range []string{"5 runs each", "determine-root", "load-slices (1 slices)", "build-launch-spec (meta)", "median"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in benchmark output:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", root, "--json", "doctor", "--benchmark"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	var report benchmarkReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	var names []string
+	for _, phase := range report.Phases {
+		names = append(names, phase.Name)
+		if phase.Error != "" || phase.MinMS > phase.MedianMS {
+			t.Fatalf("unexpected phase %+v", phase)
+		}
+	}
+	if strings.Join(names, ",") != "determine-root,load-slices,build-launch-spec" || report.Runs != benchmarkRuns {
+		t.Fatalf("unexpected phases %v over %d runs", names, report.Runs)
+	}
+
+	if code := run([]string{"--root", root, "doctor", "--benchmark", "--fix"}); code != exitUsage {
+		t.Fatalf("expected usage exit when combined with --fix, got %d", code)
+	}
+}
+
+func TestRunDoctorRejectsModeCombinations(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+
+	for _, args := range [][]string{
+		{"--fix", "--compare-lockfile", "pictl.lock.json"},
+		{"--fix", "--network"},
+		{"--fix-extensions", "--check-extensions"},
+		{"--fix-schema-version", "--typecheck"},
+		{"--deps", "--check-cwd"},
+		{"--fix", "--deps"},
+		{"--root-trace", "--write-report", "report.json"},
+	} {
+		_, errOut := captureOutput(t)
+		if code := run(append([]string{"--root", root, "doctor"}, args...)); code != exitUsage {
+			t.Fatalf("expected usage exit for doctor %v, got %d", args, code)
+		}
+		if !strings.Contains(errOut.String(), "error: "+args[0]+" cannot be combined with "+args[1]) {
+			t.Fatalf("expected a combination error for doctor %v, got %q", args, errOut.String())
+		}
+	}
+}
+
+func TestRunDoctorCheckPermissionsStrict(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	if err := os.Chmod(filepath.Join(root, "extensions", "x.ts"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "doctor", "--check-permissions"}); code != exitOK {
+		t.Fatalf("expected a warning only, got exit %d:\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "x.ts: mode 0666 is world-writable") {
+		t.Fatalf("expected the octal mode in the warning:\n%s", out.String())
+	}
+	if code := run([]string{"--root", root, "--strict", "doctor", "--check-permissions"}); code != exitFailure {
+		t.Fatalf("expected --strict to fail, got %d", code)
+	}
+}
+
+func TestRunTargetFallsBackToFallbackSlice(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"scratch": validSlice})
+	t.Setenv("PICTL_FALLBACK_SLICE", "scratch")
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "args", "meta"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if !strings.Contains(out.String(), filepath.Join(root, "extensions", "x.ts")) {
+		t.Fatalf("expected the fallback slice's extensions, got %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), "warn: slice missing; launching the fallback slice instead") || !strings.Contains(errOut.String(), "fallback=scratch") {
+		t.Fatalf("expected a fallback warning, got %q", errOut.String())
+	}
+
+	trace := filepath.Join(t.TempDir(), "trace.json")
+	writeFakePi(t, "exit 0")
+	if code := run([]string{"--root", root, "--trace-launch", trace, "meta"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	raw, err := os.ReadFile(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"PI_WORKFLOW_SLICE": "scratch"`) || !strings.Contains(string(raw), `"slice": "scratch"`) {
+		t.Fatalf("expected pi's env to name the fallback slice, got:\n%s", raw)
+	}
+
+	t.Setenv("PICTL_FALLBACK_SLICE", "gone")
+	if code := run([]string{"--root", root, "args", "meta"}); code != exitMissing {
+		t.Fatalf("expected exit %d when the fallback is missing too, got %d", exitMissing, code)
+	}
+}
+
+func TestRunWatchUsage(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "watch"}); code != exitUsage {
+		t.Fatalf("expected usage exit without a target, got %d", code)
+	}
+	if code := run([]string{"--root", root, "watch", "nope"}); code != exitUsage {
+		t.Fatalf("expected usage exit for an unknown target, got %d", code)
+	}
+	if code := run([]string{"--root", root, "watch", "build"}); code != exitMissing {
+		t.Fatalf("expected missing exit when the slice cannot be watched, got %d: %s", code, errOut.String())
+	}
+}
+
+func TestRunSliceDirFlag(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"software": validSlice})
+	shared := t.TempDir()
+	if err := os.WriteFile(filepath.Join(shared, "meta.json"), []byte(validSlice), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--slice-dir", shared, "args", "meta"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if !strings.Contains(out.String(), filepath.Join(root, "extensions", "x.ts")) {
+		t.Fatalf("expected the extension to resolve under root, got %q", out.String())
+	}
+	if code := run([]string{"--root", root, "--slice-dir", shared, "args", "build"}); code != exitMissing {
+		t.Fatalf("expected root slices to be ignored under --slice-dir, got %d", code)
+	}
+	if code := run([]string{"--root", root, "args", "build"}); code != exitOK {
+		t.Fatalf("expected the override to reset between runs, got %d", code)
+	}
+}
+
+func TestRunExitCodeFile(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	codeFile := filepath.Join(t.TempDir(), "pi.exit")
+	captureOutput(t)
+
+	for _, want := range []int{0, 7} {
+		writeFakePi(t, fmt.Sprintf("exit %d", want))
+		code := run([]string{"--root", root, "--exit-code-file", codeFile, "meta"})
+		if code != want {
+			t.Fatalf("expected exit %d, got %d", want, code)
+		}
+		raw, err := os.ReadFile(codeFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(raw) != fmt.Sprintf("%d\n", code) {
+			t.Fatalf("expected the file to hold %d, got %q", code, raw)
+		}
+	}
+}
+
+func TestRunSlicesRendersDescriptionPlaceholders(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"software": `{"description": "{{.Slice}} for {{.Target}} ({{.Profile}})", "defaultProfile": "execute", "extensions": ["extensions/x.ts"]}`,
+		"meta":     `{"description": "Plain {{ text", "extensions": ["extensions/x.ts"]}`,
+	})
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "slices"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	for _, want := range []string{"software for build (execute)", "Plain {{ text"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunDoctorFixExtensionsPreviewsThenWrites(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"meta":  "{\n  \"extensions\": [\n    \"extensions/x.ts\",\n    \"extensions/gone.ts\"\n  ]\n}\n",
+		"empty": `{"extensions": ["extensions/gone.ts"]}`,
+	})
+	metaPath := filepath.Join(root, "slices", "meta.json")
+	original, err := os.ReadFile(metaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "doctor", "--fix-extensions"}); code != exitFailure {
+		t.Fatalf("expected exit %d for the slice that would be emptied, got %d", exitFailure, code)
+	}
+	for _, want := range []string{"slice meta: missing extensions/gone.ts", `-    "extensions/gone.ts"`, "1 manifest(s) would change"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in preview:\n%s", want, out.String())
+		}
+	}
+	if !strings.Contains(errOut.String(), "refusing to prune slice empty") {
+		t.Fatalf("expected the empty guard, got %q", errOut.String())
+	}
+	if raw, _ := os.ReadFile(metaPath); string(raw) != string(original) {
+		t.Fatalf("expected the preview to leave the manifest alone, got %s", raw)
+	}
+
+	run([]string{"--root", root, "doctor", "--fix-extensions", "--write"})
+	raw, err := os.ReadFile(metaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "gone.ts") || !strings.Contains(string(raw), "extensions/x.ts") {
+		t.Fatalf("expected only the missing entry to be pruned, got %s", raw)
+	}
+	if raw, _ := os.ReadFile(filepath.Join(root, "slices", "empty.json")); !strings.Contains(string(raw), "gone.ts") {
+		t.Fatalf("expected the guarded slice to be left alone, got %s", raw)
+	}
+}
+
+func TestRunDoctorFixSchemaVersion(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"meta":  `{"extensions": ["extensions/x.ts"]}`,
+		"daily": `{"schemaVersion": 1, "extensions": ["extensions/x.ts"]}`,
+	})
+	metaPath := filepath.Join(root, "slices", "meta.json")
+	dailyPath := filepath.Join(root, "slices", "daily.json")
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "doctor", "--fix-schema-version"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "would stamp "+metaPath) || strings.Contains(out.String(), dailyPath) || !strings.Contains(out.String(), "1 manifest(s) lack schemaVersion") {
+		t.Fatalf("expected only meta to be listed:\n%s", out.String())
+	}
+	if raw, _ := os.ReadFile(metaPath); strings.Contains(string(raw), "schemaVersion") {
+		t.Fatalf("expected the preview to leave the manifest alone, got %s", raw)
+	}
+
+	if code := run([]string{"--root", root, "doctor", "--fix-schema-version", "--write"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if raw, _ := os.ReadFile(metaPath); !strings.Contains(string(raw), `"schemaVersion": 1`) {
+		t.Fatalf("expected meta to gain schemaVersion, got %s", raw)
+	}
+	if raw, _ := os.ReadFile(dailyPath); string(raw) != `{"schemaVersion": 1, "extensions": ["extensions/x.ts"]}` {
+		t.Fatalf("expected the stamped manifest to stay byte-identical, got %s", raw)
+	}
+}
+
+func TestRunTargetCatalogFile(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"daybook": validSlice, "meta": validSlice})
+	catalog := filepath.Join(root, "targets.json")
+	if err := os.WriteFile(catalog, []byte(`[{"name": "research", "slice": "daybook", "aliases": ["dig"]}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "args", "dig"}); code != exitOK {
+		t.Fatalf("expected the catalog alias to launch, got %d: %s", code, errOut.String())
+	}
+	if code := run([]string{"--root", root, "args", "meta"}); code != exitUsage {
+		t.Fatalf("expected built-in targets to be replaced, got %d", code)
+	}
+
+	if err := os.WriteFile(catalog, []byte(`[{"name": "research"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	errOut.Reset()
+	if code := run([]string{"--root", root, "args", "meta"}); code != exitOK {
+		t.Fatalf("expected a malformed catalog to fall back to the built-ins, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "warn: ignoring target catalog; using the built-in targets") {
+		t.Fatalf("expected a fallback warning, got %q", errOut.String())
+	}
+}
+
+func TestRunCwd(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	work := filepath.Join(root, "work")
+	if err := os.Mkdir(work, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	record := filepath.Join(t.TempDir(), "pwd")
+	writeFakePi(t, `pwd > "`+record+`"`)
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--cwd", "work", "--print-cmd", "meta"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if got, _ := os.ReadFile(record); strings.TrimSpace(string(got)) != work {
+		t.Fatalf("expected pi to run in %s, got %q", work, got)
+	}
+	if !strings.Contains(errOut.String(), "cd "+shellQuote(work)+" && ") {
+		t.Fatalf("expected --print-cmd to include the directory, got %q", errOut.String())
+	}
+
+	if code := run([]string{"--root", root, "--cwd", "nowhere", "meta"}); code != exitFailure {
+		t.Fatalf("expected a missing --cwd to fail, got %d", code)
+	}
+}
+
+func TestRunStdinFile(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	input := filepath.Join(t.TempDir(), "prompt.txt")
+	if err := os.WriteFile(input, []byte("summarize the diff\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	record := filepath.Join(t.TempDir(), "stdin")
+	writeFakePi(t, `read -r line; echo "$line" > "`+record+`"`)
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--stdin-file", input, "--print-cmd", "meta"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if got, _ := os.ReadFile(record); string(got) != "summarize the diff\n" {
+		t.Fatalf("expected pi to read the file on stdin, got %q", got)
+	}
+	if !strings.Contains(errOut.String(), " < "+shellQuote(input)) {
+		t.Fatalf("expected --print-cmd to include the redirect, got %q", errOut.String())
+	}
+
+	if code := run([]string{"--root", root, "--stdin-file", filepath.Join(root, "missing.txt"), "meta"}); code != exitFailure {
+		t.Fatalf("expected a missing --stdin-file to fail, got %d", code)
+	}
+}
+
+func TestRunSliceValidateOnly(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"meta":   validSlice,
+		"broken": `{"extensions": [`,
+	})
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "slice", "--validate-only", "meta"}); code != exitOK {
+		t.Fatalf("expected meta to validate despite the broken slice, got %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "slice meta is valid") {
+		t.Fatalf("expected a confirmation, got %q", out.String())
+	}
+
+	if code := run([]string{"--root", root, "slice", "--validate-only", "broken"}); code != exitFailure {
+		t.Fatalf("expected broken to fail validation, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "error: slice broken (") {
+		t.Fatalf("expected the broken slice's error, got %q", errOut.String())
+	}
+	if code := run([]string{"--root", root, "slice", "--validate-only", "nope"}); code != exitMissing {
+		t.Fatalf("expected exit %d for an unknown slice, got %d", exitMissing, code)
+	}
+	if code := run([]string{"--root", root, "slice", "--validate-only"}); code != exitUsage {
+		t.Fatalf("expected a usage error without a name, got %d", code)
+	}
+}
+
+func TestRunTargetsResolve(t *testing.T) {
+	out, errOut := captureOutput(t)
+	if code := run([]string{"targets", "--resolve", "pidev,ship,nope", "--resolve=journal"}); code != exitFailure {
+		t.Fatalf("expected an unresolved name to fail, got %d", code)
+	}
+	for _, want := range []string{"pidev      -> meta\n", "ship       -> build\n", "nope       -> unresolved\n", "journal    -> daybook\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+	if !strings.Contains(errOut.String(), "1 of 4 name(s) unresolved: nope") {
+		t.Fatalf("expected the unresolved names on stderr, got %q", errOut.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"--json", "targets", "--resolve", "PiDev,nope", "--allow-unresolved"}); code != exitOK {
+		t.Fatalf("expected --allow-unresolved to exit %d, got %d", exitOK, code)
+	}
+	var results []resolvedTarget
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	want := []resolvedTarget{{Input: "PiDev", Target: "meta", Slice: "meta", Resolved: true}, {Input: "nope"}}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("expected %+v, got %+v", want, results)
+	}
+	if !strings.Contains(out.String(), `"resolved": false`) || strings.Contains(out.String(), `"target": ""`) {
+		t.Fatalf("expected unresolved entries to carry only input and resolved, got:\n%s", out.String())
+	}
+}
+
+func TestRunHiddenTarget(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"daybook": validSlice, "meta": validSlice})
+	catalog := `[{"name": "research", "slice": "daybook", "category": "Work"}, {"name": "legacy", "slice": "meta", "category": "Work", "hidden": true}]`
+	if err := os.WriteFile(filepath.Join(root, "targets.json"), []byte(catalog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	record := filepath.Join(t.TempDir(), "target")
+	writeFakePi(t, `echo "$PI_WORKFLOW_TARGET" > "`+record+`"`)
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "list"}); code != exitOK {
+		t.Fatalf("expected list to succeed, got %d", code)
+	}
+	if !strings.Contains(out.String(), "research") || strings.Contains(out.String(), "legacy") {
+		t.Fatalf("expected the hidden target to be left out:\n%s", out.String())
+	}
+	for _, args := range [][]string{{"list", "--all"}, {"--show-hidden", "list"}} {
+		out.Reset()
+		run(append([]string{"--root", root}, args...))
+		if !strings.Contains(out.String(), "legacy") {
+			t.Fatalf("%v: expected the hidden target to be listed:\n%s", args, out.String())
+		}
+	}
+
+	if code := run([]string{"--root", root, "legacy"}); code != exitOK {
+		t.Fatalf("expected the hidden target to launch by name, got %d: %s", code, errOut.String())
+	}
+	if got, _ := os.ReadFile(record); strings.TrimSpace(string(got)) != "legacy" {
+		t.Fatalf("expected legacy to launch, got %q", got)
+	}
+
+	if code := run([]string{"--root", root, ":2"}); code != exitUsage {
+		t.Fatalf("expected :2 to be out of range without the hidden target, got %d", code)
+	}
+	if code := run([]string{"--root", root, "--show-hidden", ":2"}); code != exitOK {
+		t.Fatalf("expected --show-hidden to number the hidden target, got %d", code)
+	}
+}
+
+func TestRunDoctorDepsTreeAndDot(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"meta": `{"include": ["base"], "extensions": ["extensions/x.ts"]}`,
+		"base": validSlice,
+		"a":    `{"include": ["b"]}`,
+		"b":    `{"include": ["a"]}`,
+	})
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "doctor", "--deps"}); code != exitFailure {
+		t.Fatalf("expected exit %d for the cycle, got %d", exitFailure, code)
+	}
+	for _, want := range []string{"meta\n  base\n", "a\n  b\n    a ✗ cycle\n", "include cycle: a -> b -> a"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in tree:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	run([]string{"--root", root, "doctor", "--deps", "--format", "dot"})
+	for _, want := range []string{"digraph slices {", `"meta" -> "base";`, `"a" -> "b" [color=red, label="cycle"];`} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in dot output:\n%s", want, out.String())
+		}
+	}
+
+	if code := run([]string{"--root", root, "doctor", "--format", "dot"}); code != exitUsage {
+		t.Fatalf("expected --format without --deps to be a usage error, got %d", code)
+	}
+}
+
+func TestRunAppendProfileConflictsWithProfile(t *testing.T) {
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--profile", "fast", "--append-profile", "ship", "list"}); code != exitUsage {
+		t.Fatalf("expected usage exit, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "--profile and --append-profile are mutually exclusive") {
+		t.Fatalf("expected conflict error, got %q", errOut.String())
+	}
+}
+
+func TestRunOpenReadsTargetFromStdin(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"software": validSlice})
+	writeFakePi(t, "exit 0")
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+	prevStdin := stdin
+	t.Cleanup(func() { stdin = prevStdin })
+
+	stdin = strings.NewReader("  build\n")
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--print-cmd", "open", "-"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "PI_WORKFLOW_TARGET=build") {
+		t.Fatalf("expected the build target to launch, got %q", errOut.String())
+	}
+
+	rest := strings.NewReader("build\nprompt for pi\n")
+	stdin = rest
+	if code := run([]string{"--root", root, "--print-cmd", "open", "-"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if left, _ := io.ReadAll(rest); string(left) != "prompt for pi\n" {
+		t.Fatalf("expected stdin after the target line to be left for pi, got %q", left)
+	}
+
+	for input, want := range map[string]string{"nope\n": `unknown target "nope" (read from stdin)`, "\n": "no target name on stdin", "": "no target name on stdin"} {
+		stdin = strings.NewReader(input)
+		errOut.Reset()
+		if code := run([]string{"--root", root, "open", "-"}); code != exitUsage {
+			t.Fatalf("expected exit %d for stdin %q, got %d", exitUsage, input, code)
+		}
+		if !strings.Contains(errOut.String(), want) {
+			t.Fatalf("expected %q for stdin %q, got %q", want, input, errOut.String())
+		}
+	}
+}
+
+func TestRunDoctorCheckCwd(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+
+	t.Chdir(filepath.Join(root, "slices"))
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "--strict", "doctor", "--check-cwd"}); code != exitOK {
+		t.Fatalf("expected a cwd inside the root to pass, got %d:\n%s", code, out.String())
+	}
+
+	t.Chdir(t.TempDir())
+	out.Reset()
+	if code := run([]string{"--root", root, "--strict", "doctor", "--check-cwd"}); code != exitFailure {
+		t.Fatalf("expected an unrelated cwd to fail under --strict, got %d", code)
+	}
+	if !strings.Contains(out.String(), "is unrelated to root") {
+		t.Fatalf("expected the relationship in the output:\n%s", out.String())
+	}
+}
+
+func TestRunEnvFlag(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	writeFakePi(t, "exit 0")
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--env", "PICTL_A=1", "--env=PICTL_B=x y", "--print-cmd", "meta"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	for _, want := range []string{"PI_DEFAULT_PROFILE=meta", "PICTL_A=1", "PICTL_B='x y'"} {
+		if !strings.Contains(errOut.String(), want) {
+			t.Fatalf("expected %q in the printed command, got %q", want, errOut.String())
+		}
+	}
+
+	errOut.Reset()
+	if code := run([]string{"--root", root, "--env", "NOVALUE", "meta"}); code != exitUsage {
+		t.Fatalf("expected a malformed --env to be a usage error, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), `invalid --env "NOVALUE"`) {
+		t.Fatalf("expected the malformed entry in the error, got %q", errOut.String())
+	}
+}
+
+func TestRunRepeatedEnvFile(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	writeFakePi(t, "exit 0")
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+	dir := t.TempDir()
+	base, override := filepath.Join(dir, "base.env"), filepath.Join(dir, "local.env")
+	if err := os.WriteFile(base, []byte("PICTL_A=base\nPICTL_B=base\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(override, []byte("PICTL_B=local\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--env-file", base, "--env-file", override, "--env", "PICTL_A=flag", "--print-cmd", "meta"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "PICTL_A=flag") || !strings.Contains(errOut.String(), "PICTL_B=local") || strings.Contains(errOut.String(), "=base") {
+		t.Fatalf("expected the later file and --env to win, got %q", errOut.String())
+	}
+}
+
+func TestRunTraceLaunchThenReplay(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"meta": validSlice})
+	argsFile := filepath.Join(t.TempDir(), "args")
+	writeFakePi(t, `echo "$@" >> `+argsFile)
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+	trace := filepath.Join(t.TempDir(), "trace.json")
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "--trace-launch", trace, "meta", "--", "--model", "sonnet"}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	raw, err := os.ReadFile(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"root": "` + root + `"`, `"target": "meta"`, `"profile": "meta"`, `"PI_WORKFLOW_SLICE": "meta"`} {
+		if !strings.Contains(string(raw), want) {
+			t.Fatalf("expected %s in trace:\n%s", want, raw)
+		}
+	}
+
+	if code := run([]string{"replay", trace}); code != exitOK {
+		t.Fatalf("expected replay to exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	calls, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(calls)), "\n")
+	if len(lines) != 2 || lines[0] != lines[1] || !strings.HasSuffix(lines[0], "--model sonnet") {
+		t.Fatalf("expected replay to rerun the same args, got %q", lines)
+	}
+	if !strings.Contains(errOut.String(), "replaying meta (profile meta, strict=false)") {
+		t.Fatalf("expected the replay summary, got %q", errOut.String())
+	}
+
+	if code := run([]string{"replay"}); code != exitUsage {
+		t.Fatalf("expected replay without a file to be a usage error, got %d", code)
+	}
+}
+
+func TestRunSliceRequires(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{
+		"meta": `{"requires": ["pi", "definitely-not-installed"], "extensions": ["extensions/x.ts"]}`,
+	})
+	writeFakePi(t, "exit 0")
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "meta"}); code != exitMissing {
+		t.Fatalf("expected exit %d for a missing tool, got %d", exitMissing, code)
+	}
+	if !strings.Contains(errOut.String(), "definitely-not-installed not found on PATH") {
+		t.Fatalf("expected the missing tool in the error, got %q", errOut.String())
+	}
+
+	if code := run([]string{"--root", root, "--skip-requires", "meta"}); code != exitOK {
+		t.Fatalf("expected --skip-requires to launch anyway, got %d", code)
+	}
+
+	out, _ := captureOutput(t)
+	run([]string{"--root", root, "doctor"})
+	if !strings.Contains(out.String(), "slice meta: definitely-not-installed not found on PATH") {
+		t.Fatalf("expected doctor to report the unmet requirement:\n%s", out.String())
+	}
+}
+
+func TestRunSlicesDiff(t *testing.T) {
+	mine := writeFixtureRoot(t, map[string]string{
+		"meta":    validSlice,
+		"retired": validSlice,
+	})
+	theirs := writeFixtureRoot(t, map[string]string{
+		"meta":  `{"description": "test", "defaultProfile": "fast", "extensions": ["extensions/x.ts", "extensions/y.ts"]}`,
+		"fresh": validSlice,
+	})
+	if err := os.WriteFile(filepath.Join(theirs, "extensions", "y.ts"), []byte("export default function () {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errOut := captureOutput(t)
+	if code := run([]string{"--root", mine, "slices", "--diff", theirs}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, errOut.String())
+	}
+	for _, want := range []string{"+ fresh\n", "- retired\n", "~ meta\n    + extensions/y.ts\n    defaultProfile: meta -> fast\n", "1 added, 1 removed, 1 changed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in diff:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if code := run([]string{"--root", mine, "--json", "slices", "--diff=" + mine}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	var diff controlplane.SliceCatalogDiff
+	if err := json.Unmarshal(out.Bytes(), &diff); err != nil || !diff.Empty() {
+		t.Fatalf("expected an empty JSON diff against itself, got %v:\n%s", err, out.String())
+	}
+}
+
+func TestRunConfirmTarget(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"sysadmin": validSlice})
+	calls := filepath.Join(t.TempDir(), "calls")
+	writeFakePi(t, "echo launched >> "+calls)
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+	prevStdin, prevInteractive := stdin, isInteractive
+	t.Cleanup(func() { stdin, isInteractive = prevStdin, prevInteractive })
+	isInteractive = func() bool { return true }
+	launches := func() int {
+		raw, _ := os.ReadFile(calls)
+		return strings.Count(string(raw), "launched")
+	}
+
+	_, errOut := captureOutput(t)
+	stdin = strings.NewReader("n\n")
+	if code := run([]string{"--root", root, "ops"}); code != exitFailure {
+		t.Fatalf("expected declining to exit %d, got %d", exitFailure, code)
+	}
+	if !strings.Contains(errOut.String(), "Launch ops? [y/N] ") || !strings.Contains(errOut.String(), "not launching ops") || launches() != 0 {
+		t.Fatalf("expected the prompt and no launch, got %q (%d launches)", errOut.String(), launches())
+	}
+
+	stdin = strings.NewReader("yes\n")
+	if code := run([]string{"--root", root, "ops"}); code != exitOK || launches() != 1 {
+		t.Fatalf("expected yes to launch, got exit %d (%d launches)", code, launches())
+	}
+
+	errOut.Reset()
+	stdin = strings.NewReader("")
+	if code := run([]string{"--root", root, "--yes", "ops"}); code != exitOK || launches() != 2 {
+		t.Fatalf("expected --yes to launch, got exit %d (%d launches)", code, launches())
+	}
+	if strings.Contains(errOut.String(), "[y/N]") {
+		t.Fatalf("expected --yes to skip the prompt, got %q", errOut.String())
+	}
+
+	isInteractive = func() bool { return false }
+	if code := run([]string{"--root", root, "ops"}); code != exitOK || launches() != 3 {
+		t.Fatalf("expected a non-TTY launch to skip the prompt, got exit %d (%d launches)", code, launches())
+	}
+}
+
+func TestRunDoctorJSONSchema(t *testing.T) {
+	out, _ := captureOutput(t)
+	path := filepath.Join(t.TempDir(), "slice.schema.json")
+	if code := run([]string{"doctor", "--json-schema", "--out", path}); code != exitOK {
+		t.Fatalf("expected exit %d, got %d", exitOK, code)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("invalid schema JSON: %v", err)
+	}
+	for _, field := range []string{"$schema", "extensions", "include", "enabled", "tags", "requires"} {
+		if _, ok := schema.Properties[field]; !ok {
+			t.Fatalf("expected %q in the schema properties", field)
+		}
+	}
+	if !strings.Contains(out.String(), "wrote "+path) {
+		t.Fatalf("expected a confirmation, got %q", out.String())
+	}
+
+	if code := run([]string{"doctor", "--out", path}); code != exitUsage {
+		t.Fatalf("expected --out without --json-schema to be a usage error, got %d", code)
+	}
+}
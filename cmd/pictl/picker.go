@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+const pickerEnv = "PICTL_PICKER"
+
+type targetPicker interface {
+	Pick(targets []controlplane.Target) (string, error)
+}
+
+type numericPicker struct{}
+
+func (numericPicker) Pick(targets []controlplane.Target) (string, error) {
+	return pickTargetInteractive(targets)
+}
+
+type fzfPicker struct {
+	path string
+}
+
+func (p fzfPicker) Pick(targets []controlplane.Target) (string, error) {
+	if !controlplane.IsTTY() {
+		return "", errors.New("no target specified and no interactive TTY available")
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(p.path, "--delimiter", "\t", "--prompt", "target> ", "--height", "40%", "--reverse")
+	cmd.Stdin = strings.NewReader(fzfLines(targets))
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && (exitErr.ExitCode() == 1 || exitErr.ExitCode() == 130) {
+			return "", errors.New("no target selected")
+		}
+		return "", fmt.Errorf("fzf: %w", err)
+	}
+	return parseFzfSelection(out.String())
+}
+
+func pickerName(name string) string {
+	if name == "" {
+		name = strings.ToLower(strings.TrimSpace(os.Getenv(pickerEnv)))
+	}
+	if name != "fzf" {
+		return "numeric"
+	}
+	return name
+}
+
+func newTargetPicker(name string) targetPicker {
+	if pickerName(name) != "fzf" {
+		return numericPicker{}
+	}
+
+	path, err := exec.LookPath("fzf")
+	if err != nil {
+		logger.Warn("fzf not found in PATH; using the numeric picker")
+		return numericPicker{}
+	}
+	return fzfPicker{path: path}
+}
+
+func pickTarget(opts globalOptions) (string, error) {
+	return newTargetPicker(opts.Picker).Pick(controlplane.PickerOrder(controlplane.VisibleTargets(controlplane.CanonicalTargets(), opts.ShowHidden)))
+}
+
+func fzfLines(targets []controlplane.Target) string {
+	var out strings.Builder
+	for _, target := range targets {
+		fmt.Fprintf(&out, "%s\t%s\n", target.Name, controlplane.TargetDescription(target))
+	}
+	return out.String()
+}
+
+func parseFzfSelection(output string) (string, error) {
+	line, _, _ := strings.Cut(output, "\n")
+	name, _, _ := strings.Cut(line, "\t")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("no target selected")
+	}
+	target, ok := controlplane.ResolveTarget(name)
+	if !ok {
+		return "", fmt.Errorf("%w %q", controlplane.ErrUnknownTarget, name)
+	}
+	return target.Name, nil
+}
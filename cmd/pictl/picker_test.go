@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+func TestParseFzfSelection(t *testing.T) {
+	cases := map[string]struct {
+		output string
+		want   string
+		err    error
+	}{
+		"name and description": {output: "build\tImplementation work\n", want: "build"},
+		"bare name":            {output: "meta\n", want: "meta"},
+		"alias":                {output: "sysadmin\t\n", want: "ops"},
+		"multiple lines":       {output: "daybook\tx\nbuild\ty\n", want: "daybook"},
+		"empty":                {output: "", err: nil},
+		"unknown":              {output: "nope\tx\n", err: controlplane.ErrUnknownTarget},
+	}
+	for name, tc := range cases {
+		got, err := parseFzfSelection(tc.output)
+		if tc.want == "" {
+			if err == nil || (tc.err != nil && !errors.Is(err, tc.err)) {
+				t.Fatalf("%s: expected error, got %q (%v)", name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Fatalf("%s: expected %q, got %q (%v)", name, tc.want, got, err)
+		}
+	}
+}
+
+func TestFzfLinesRoundTrip(t *testing.T) {
+	targets := controlplane.PickerOrder(controlplane.CanonicalTargets())
+	lines := strings.Split(strings.TrimSuffix(fzfLines(targets), "\n"), "\n")
+	if len(lines) != len(targets) {
+		t.Fatalf("expected one line per target, got %d", len(lines))
+	}
+	for i, line := range lines {
+		got, err := parseFzfSelection(line + "\n")
+		if err != nil || got != targets[i].Name {
+			t.Fatalf("expected line %q to select %s, got %q (%v)", line, targets[i].Name, got, err)
+		}
+	}
+}
+
+func TestNewTargetPicker(t *testing.T) {
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir)
+	t.Setenv(pickerEnv, "")
+
+	if _, ok := newTargetPicker("").(numericPicker); !ok {
+		t.Fatalf("expected numeric picker by default")
+	}
+	if _, ok := newTargetPicker("fzf").(numericPicker); !ok {
+		t.Fatalf("expected fallback to numeric when fzf is not on PATH")
+	}
+
+	if err := os.WriteFile(filepath.Join(binDir, "fzf"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := newTargetPicker("fzf").(fzfPicker); !ok {
+		t.Fatalf("expected fzf picker when fzf is on PATH")
+	}
+	t.Setenv(pickerEnv, "fzf")
+	if _, ok := newTargetPicker("").(fzfPicker); !ok {
+		t.Fatalf("expected %s to select fzf", pickerEnv)
+	}
+	if _, ok := newTargetPicker("numeric").(numericPicker); !ok {
+		t.Fatalf("expected --picker to beat %s", pickerEnv)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+func targetDefaultProfile(target controlplane.Target) (string, string, error) {
+	path, err := controlplane.StatePath()
+	if err != nil {
+		return "", "", err
+	}
+	state, err := controlplane.LoadUserState(path)
+	if err != nil {
+		return "", "", err
+	}
+	if profile := state.TargetProfile(target.Name); profile != "" {
+		return profile, fmt.Sprintf("your saved default for %q (pictl set-profile)", target.Name), nil
+	}
+	return target.DefaultProfile, fmt.Sprintf("target %q's default", target.Name), nil
+}
+
+func loadProfileCatalog(opts globalOptions) error {
+	path := opts.ProfileFile
+	if path == "" {
+		root, err := controlplane.DetermineRoot(opts.Root)
+		if err != nil {
+			controlplane.UseProfileCatalog("", nil)
+			return nil
+		}
+		path = controlplane.DefaultProfileCatalogPath(root)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			controlplane.UseProfileCatalog("", nil)
+			return nil
+		}
+	}
+
+	profiles, err := controlplane.LoadProfileCatalog(path)
+	if err != nil {
+		return err
+	}
+	controlplane.UseProfileCatalog(path, profiles)
+	logger.Debug("loaded profile catalog", "path", path, "profiles", len(profiles))
+	return nil
+}
+
+func loadBranchProfileRules(opts globalOptions) error {
+	controlplane.UseBranchProfileRules("", nil)
+	if !opts.BranchProfile {
+		return nil
+	}
+	root, err := controlplane.DetermineRoot(opts.Root)
+	if err != nil {
+		return nil
+	}
+	path := controlplane.DefaultBranchProfilesPath(root)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	rules, err := controlplane.LoadBranchProfileRules(path)
+	if err != nil {
+		return err
+	}
+	controlplane.UseBranchProfileRules(path, rules)
+	logger.Debug("loaded branch profile rules", "path", path, "rules", len(rules))
+	return nil
+}
+
+func loadTargetCatalog(opts globalOptions) {
+	controlplane.UseTargetCatalog("", nil)
+	root, err := controlplane.DetermineRoot(opts.Root)
+	if err != nil {
+		return
+	}
+	path := controlplane.DefaultTargetCatalogPath(root)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return
+	}
+
+	targets, err := controlplane.LoadTargetCatalog(path)
+	if err != nil {
+		logger.Warn("ignoring target catalog; using the built-in targets", "error", err)
+		return
+	}
+	controlplane.UseTargetCatalog(path, targets)
+	logger.Debug("loaded target catalog", "path", path, "targets", len(targets))
+}
+
+func runSetProfile(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(stderr, "error: set-profile requires a target and a profile")
+		return exitUsage
+	}
+
+	target, ok := controlplane.ResolveTarget(args[0])
+	if !ok {
+		return exitCodeForError(fmt.Errorf("%w %q", controlplane.ErrUnknownTarget, args[0]))
+	}
+	profile, ok := controlplane.ResolveProfile(args[1])
+	if !ok {
+		fmt.Fprintf(stderr, "error: unknown profile %q (see pictl profiles)\n", args[1])
+		return exitUsage
+	}
+
+	return updateUserState(func(state *controlplane.UserState) string {
+		state.SetTargetProfile(target.Name, profile.Name)
+		return fmt.Sprintf("%s now defaults to profile %s", target.Name, profile.Name)
+	})
+}
+
+func runUnsetProfile(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "error: unset-profile requires a target")
+		return exitUsage
+	}
+
+	target, ok := controlplane.ResolveTarget(args[0])
+	if !ok {
+		return exitCodeForError(fmt.Errorf("%w %q", controlplane.ErrUnknownTarget, args[0]))
+	}
+
+	return updateUserState(func(state *controlplane.UserState) string {
+		if !state.UnsetTargetProfile(target.Name) {
+			return fmt.Sprintf("%s has no saved profile", target.Name)
+		}
+		return fmt.Sprintf("%s uses its built-in default profile %s again", target.Name, target.DefaultProfile)
+	})
+}
+
+func updateUserState(update func(*controlplane.UserState) string) int {
+	path, err := controlplane.StatePath()
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	var message string
+	if err := controlplane.UpdateUserState(path, func(state *controlplane.UserState) {
+		message = update(state)
+	}); err != nil {
+		return exitCodeForError(err)
+	}
+	fmt.Fprintln(stdout, message)
+	return exitOK
+}
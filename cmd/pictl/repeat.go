@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"time"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+type repeatRun struct {
+	Run      int
+	Code     int
+	Duration time.Duration
+}
+
+type repeatSummary struct {
+	Runs   int
+	Failed []repeatRun
+	Min    time.Duration
+	Median time.Duration
+	Max    time.Duration
+}
+
+func runRepeat(opts globalOptions, batchOpts batchOptions, forwarded []string) int {
+	spec, err := buildTargetSpec(opts, batchOpts.Target, forwarded)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	if batchOpts.DryRun {
+		fmt.Fprintf(chatter(opts, stdout), "would run %d times: %s\n", batchOpts.Repeat, formatCommand(spec))
+		return exitOK
+	}
+	if target, _ := controlplane.ResolveTarget(batchOpts.Target); !confirmTarget(opts, target) {
+		return exitFailure
+	}
+
+	printDiagnostics(opts, spec)
+	if opts.PrintCmd {
+		fmt.Fprintln(chatter(opts, stderr), formatCommand(spec))
+	}
+	if opts.Timeout > 0 {
+		spec.Timeout = opts.Timeout
+	}
+	spec.StartupTimeout = opts.StartupTimeout
+	if err := writeLaunchTrace(opts, spec); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return recordExitCode(opts, exitFailure)
+	}
+
+	var runs []repeatRun
+	for i := 1; i <= batchOpts.Repeat; i++ {
+		start := time.Now()
+		_, errText, err := controlplane.LaunchPiCaptured(spec)
+		result := repeatRun{Run: i, Duration: time.Since(start)}
+
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+		case errors.As(err, &exitErr):
+			result.Code = exitErr.ExitCode()
+		case errors.Is(err, controlplane.ErrLaunchTimeout):
+			result.Code = exitTimeout
+		default:
+			return recordExitCode(opts, exitCodeForError(err))
+		}
+		runs = append(runs, result)
+
+		status := "ok"
+		if result.Code != exitOK {
+			status = fmt.Sprintf("exit %d", result.Code)
+			io.WriteString(stderr, errText)
+		}
+		fmt.Fprintf(chatter(opts, stderr), "pictl: run %d/%d %s in %s\n", i, batchOpts.Repeat, status, result.Duration.Round(time.Millisecond))
+
+		if batchOpts.StopOnError && result.Code != exitOK {
+			break
+		}
+	}
+
+	summary := summarizeRuns(runs)
+	printRepeatSummary(chatter(opts, stdout), summary, batchOpts.Repeat)
+	if len(summary.Failed) > 0 {
+		return recordExitCode(opts, exitFailure)
+	}
+	return recordExitCode(opts, exitOK)
+}
+
+func summarizeRuns(runs []repeatRun) repeatSummary {
+	summary := repeatSummary{Runs: len(runs)}
+	if len(runs) == 0 {
+		return summary
+	}
+
+	durations := make([]time.Duration, 0, len(runs))
+	for _, run := range runs {
+		durations = append(durations, run.Duration)
+		if run.Code != exitOK {
+			summary.Failed = append(summary.Failed, run)
+		}
+	}
+	slices.Sort(durations)
+
+	summary.Min = durations[0]
+	summary.Max = durations[len(durations)-1]
+	middle := len(durations) / 2
+	summary.Median = durations[middle]
+	if len(durations)%2 == 0 {
+		summary.Median = (durations[middle-1] + durations[middle]) / 2
+	}
+	return summary
+}
+
+func printRepeatSummary(out io.Writer, summary repeatSummary, total int) {
+	round := func(d time.Duration) time.Duration { return d.Round(time.Millisecond) }
+	fmt.Fprintln(out, "summary:")
+	fmt.Fprintf(out, "  min %s  median %s  max %s\n", round(summary.Min), round(summary.Median), round(summary.Max))
+	for _, run := range summary.Failed {
+		fmt.Fprintf(out, "  run %-4d exit %d\n", run.Run, run.Code)
+	}
+	if skipped := total - summary.Runs; skipped > 0 {
+		fmt.Fprintf(out, "  (%d not run after --stop-on-error)\n", skipped)
+	}
+	fmt.Fprintf(out, "%d/%d runs succeeded\n", summary.Runs-len(summary.Failed), total)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+func writeLaunchTrace(opts globalOptions, spec controlplane.LaunchSpec) error {
+	if opts.TraceLaunch == "" {
+		return nil
+	}
+	trace := controlplane.NewLaunchTrace(spec, os.Environ())
+	trace.Strict = opts.Strict
+	if root, err := determineRoot(opts); err == nil {
+		trace.Root = root
+	}
+	if err := controlplane.WriteLaunchTrace(opts.TraceLaunch, trace); err != nil {
+		return fmt.Errorf("write launch trace: %w", err)
+	}
+	logger.Debug("wrote launch trace", "path", opts.TraceLaunch)
+	return nil
+}
+
+func runReplay(opts globalOptions, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "error: replay requires a trace file (written by --trace-launch)")
+		return exitUsage
+	}
+	trace, err := controlplane.LoadLaunchTrace(args[0])
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	spec, err := trace.Spec(os.Environ())
+	if err != nil {
+		return exitCodeForError(fmt.Errorf("%s: %w", args[0], err))
+	}
+	if trace.Root != "" {
+		if _, err := os.Stat(trace.Root); err != nil {
+			spec.Warnings = append(spec.Warnings, fmt.Sprintf("recorded root %s is not on this machine; extension paths may not resolve", trace.Root))
+		}
+	}
+
+	name := trace.Target
+	if name == "" || name == "slice" {
+		name = "slice " + trace.Slice
+	}
+	profile := trace.Profile
+	if profile == "" {
+		profile = "(none)"
+	}
+	fmt.Fprintf(chatter(opts, stderr), "pictl: replaying %s (profile %s, strict=%t) from %s\n", name, profile, trace.Strict, args[0])
+	return launch(opts, spec)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+type sliceCommandOptions struct {
+	Name   string
+	Force  bool
+	DryRun bool
+}
+
+func parseSliceCommandArgs(command string, args []string, allowDryRun bool) (sliceCommandOptions, error) {
+	opts := sliceCommandOptions{}
+	for _, arg := range args {
+		switch {
+		case arg == "--force":
+			opts.Force = true
+		case arg == "--dry-run" && allowDryRun:
+			opts.DryRun = true
+		case strings.HasPrefix(arg, "-"):
+			return opts, fmt.Errorf("unknown %s flag %q", command, arg)
+		case opts.Name != "":
+			return opts, fmt.Errorf("%s takes exactly one slice name", command)
+		default:
+			opts.Name = arg
+		}
+	}
+	if opts.Name == "" {
+		return opts, fmt.Errorf("%s requires a slice name", command)
+	}
+	return opts, nil
+}
+
+func locateSlice(opts globalOptions, cmdOpts sliceCommandOptions, action string) (controlplane.SliceFile, int) {
+	root, err := determineRoot(opts)
+	if err != nil {
+		return controlplane.SliceFile{}, exitCodeForError(err)
+	}
+	slices, err := controlplane.LoadSlices(root)
+	if err != nil {
+		return controlplane.SliceFile{}, exitCodeForError(err)
+	}
+	if _, ok := slices[cmdOpts.Name]; !ok {
+		return controlplane.SliceFile{}, exitCodeForError(fmt.Errorf("%w %q", controlplane.ErrSliceNotFound, cmdOpts.Name))
+	}
+
+	if refs := controlplane.SliceReferences(cmdOpts.Name, slices, controlplane.CanonicalTargets()); len(refs) > 0 && !cmdOpts.Force {
+		fmt.Fprintf(stderr, "error: refusing to %s slice %s: used by %s (use --force)\n", action, cmdOpts.Name, strings.Join(refs, ", "))
+		return controlplane.SliceFile{}, exitFailure
+	}
+
+	files, err := controlplane.SliceFiles(root)
+	if err != nil {
+		return controlplane.SliceFile{}, exitCodeForError(err)
+	}
+	for _, file := range files {
+		if file.Name == cmdOpts.Name {
+			return file, exitOK
+		}
+	}
+	return controlplane.SliceFile{}, exitCodeForError(fmt.Errorf("%w %q", controlplane.ErrSliceNotFound, cmdOpts.Name))
+}
+
+func runRmSlice(opts globalOptions, args []string) int {
+	cmdOpts, err := parseSliceCommandArgs("rm-slice", args, true)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	file, code := locateSlice(opts, cmdOpts, "remove")
+	if code != exitOK {
+		return code
+	}
+
+	if cmdOpts.DryRun {
+		fmt.Fprintf(stdout, "would remove %s\n", file.Path)
+		return exitOK
+	}
+	if err := os.Remove(file.Path); err != nil {
+		return exitCodeForError(err)
+	}
+	fmt.Fprintf(chatter(opts, stdout), "removed %s\n", file.Path)
+	return exitOK
+}
+
+func runSetSliceEnabled(opts globalOptions, args []string, enabled bool) int {
+	command, action := "enable-slice", "enable"
+	if !enabled {
+		command, action = "disable-slice", "disable"
+	}
+	cmdOpts, err := parseSliceCommandArgs(command, args, false)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+	if enabled {
+		cmdOpts.Force = true
+	}
+	file, code := locateSlice(opts, cmdOpts, action)
+	if code != exitOK {
+		return code
+	}
+
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	raw, err := os.ReadFile(file.Path)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	updated, err := controlplane.SetManifestEnabled(raw, enabled)
+	if err != nil {
+		return exitCodeForError(fmt.Errorf("slice %s: %w", file.Name, err))
+	}
+	if bytes.Equal(updated, raw) {
+		fmt.Fprintf(chatter(opts, stdout), "slice %s is already %sd\n", file.Name, action)
+		return exitOK
+	}
+	if err := os.WriteFile(file.Path, updated, info.Mode().Perm()); err != nil {
+		return exitCodeForError(err)
+	}
+	fmt.Fprintf(chatter(opts, stdout), "%sd slice %s (%s)\n", action, file.Name, file.Path)
+	return exitOK
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+func TestSliceCommandsRefuseReferencedSlice(t *testing.T) {
+	root := writeFixtureRoot(t, map[string]string{"software": validSlice})
+	path := filepath.Join(root, "slices", "software.json")
+
+	for _, command := range []string{"rm-slice", "disable-slice"} {
+		_, errOut := captureOutput(t)
+		if code := run([]string{"--root", root, command, "software"}); code != exitFailure {
+			t.Fatalf("%s: expected exit %d, got %d", command, exitFailure, code)
+		}
+		if !strings.Contains(errOut.String(), "target build") || !strings.Contains(errOut.String(), "--force") {
+			t.Fatalf("%s: expected reference guard, got %q", command, errOut.String())
+		}
+	}
+	if raw, _ := os.ReadFile(path); string(raw) != validSlice {
+		t.Fatalf("expected guarded slice to be untouched, got %s", raw)
+	}
+
+	out, _ := captureOutput(t)
+	if code := run([]string{"--root", root, "rm-slice", "software", "--force", "--dry-run"}); code != exitOK {
+		t.Fatalf("expected forced dry run to succeed, got %d", code)
+	}
+	if out.String() != "would remove "+path+"\n" {
+		t.Fatalf("unexpected dry-run output %q", out.String())
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected dry run to keep the file: %v", err)
+	}
+
+	captureOutput(t)
+	if code := run([]string{"--root", root, "rm-slice", "software", "--force"}); code != exitOK {
+		t.Fatalf("expected forced removal to succeed, got %d", code)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected file to be removed, got %v", err)
+	}
+}
+
+func TestDisableEnableSliceRoundTrip(t *testing.T) {
+	original := "{\n  \"description\": \"test\",\n  \"extensions\": [\n    \"extensions/x.ts\"\n  ]\n}\n"
+	root := writeFixtureRoot(t, map[string]string{"scratch": original})
+	path := filepath.Join(root, "slices", "scratch.json")
+
+	_, errOut := captureOutput(t)
+	if code := run([]string{"--root", root, "disable-slice", "scratch"}); code != exitOK {
+		t.Fatalf("expected disable to succeed, got %d: %s", code, errOut.String())
+	}
+	slices, err := controlplane.LoadSlices(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if slices["scratch"].IsEnabled() {
+		t.Fatalf("expected slice to be disabled")
+	}
+
+	if code := run([]string{"--root", root, "enable-slice", "scratch"}); code != exitOK {
+		t.Fatalf("expected enable to succeed, got %d: %s", code, errOut.String())
+	}
+	if raw, _ := os.ReadFile(path); string(raw) != original {
+		t.Fatalf("expected round trip to restore the original bytes, got %q", raw)
+	}
+
+	if code := run([]string{"--root", root, "enable-slice", "missing"}); code != exitMissing {
+		t.Fatalf("expected missing slice to exit %d, got %d", exitMissing, code)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+func runSliceDiff(opts globalOptions, slices map[string]controlplane.SliceManifest, otherRoot string) int {
+	if controlplane.SliceDirOverride() != "" {
+		fmt.Fprintf(stderr, "error: --diff compares <root>/slices in both checkouts; unset --slice-dir and %s\n", controlplane.SliceDirEnv)
+		return exitUsage
+	}
+	other, _, err := controlplane.ResolveRoot(otherRoot, nil)
+	if err != nil {
+		return exitCodeForError(err)
+	}
+	otherSlices, err := controlplane.LoadSlices(other)
+	if err != nil {
+		return exitCodeForError(fmt.Errorf("%s: %w", other, err))
+	}
+
+	diff := controlplane.DiffSlices(slices, otherSlices)
+	if opts.JSON {
+		return writeJSON(diff)
+	}
+	if diff.Empty() {
+		fmt.Fprintf(stdout, "no differences from %s\n", other)
+		return exitOK
+	}
+	for _, name := range diff.Added {
+		fmt.Fprintf(stdout, "+ %s\n", name)
+	}
+	for _, name := range diff.Removed {
+		fmt.Fprintf(stdout, "- %s\n", name)
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(stdout, "~ %s\n", change.Name)
+		for _, path := range change.AddedExtensions {
+			fmt.Fprintf(stdout, "    + %s\n", path)
+		}
+		for _, path := range change.RemovedExtensions {
+			fmt.Fprintf(stdout, "    - %s\n", path)
+		}
+		if change.ExtensionsReordered {
+			fmt.Fprintln(stdout, "    extensions reordered")
+		}
+		for _, field := range change.Fields {
+			fmt.Fprintf(stdout, "    %s: %s -> %s\n", field.Field, diffValue(field.From), diffValue(field.To))
+		}
+	}
+	fmt.Fprintf(stdout, "%d added, %d removed, %d changed in %s\n", len(diff.Added), len(diff.Removed), len(diff.Changed), other)
+	return exitOK
+}
+
+func diffValue(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/phaedrus/pi-agent-config/internal/controlplane"
+)
+
+const (
+	watchPoll     = 250 * time.Millisecond
+	watchDebounce = 300 * time.Millisecond
+)
+
+func runWatch(opts globalOptions, args []string, forwardedAfterSeparator []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "error: watch requires a target")
+		return exitUsage
+	}
+	target, ok := controlplane.ResolveTarget(args[0])
+	if !ok {
+		return exitCodeForError(fmt.Errorf("%w %q", controlplane.ErrUnknownTarget, args[0]))
+	}
+	forwarded := concatArgs(args[1:], forwardedAfterSeparator)
+	if !confirmTarget(opts, target) {
+		return exitFailure
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	info := chatter(opts, stderr)
+	var paths []string
+	for {
+		watched, err := watchedTargetPaths(opts, target, forwarded)
+		if err == nil {
+			paths = watched
+		} else if len(paths) == 0 {
+			return exitCodeForError(err)
+		}
+
+		if code, stopped := watchOnce(ctx, opts, target, forwarded, paths, err); stopped {
+			return code
+		}
+		if ctx.Err() != nil {
+			fmt.Fprintln(info, "pictl: watch stopped")
+			return exitOK
+		}
+	}
+}
+
+func watchOnce(ctx context.Context, opts globalOptions, target controlplane.Target, forwarded []string, paths []string, pathErr error) (int, bool) {
+	info := chatter(opts, stderr)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changes := make(chan []string, 1)
+	go func() {
+		if changed, err := controlplane.WaitForChange(runCtx, paths, watchPoll, watchDebounce); err == nil {
+			changes <- changed
+		}
+	}()
+
+	done := make(chan error, 1)
+	launched := false
+	spec, err := buildResolvedTargetSpec(opts, target, forwarded)
+	if err == nil {
+		err = pathErr
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		fmt.Fprintf(info, "pictl: waiting for changes to %d file(s) (Ctrl-C to stop)\n", len(paths))
+	} else {
+		printDiagnostics(opts, spec)
+		spec.Timeout = opts.Timeout
+		spec.StartupTimeout = opts.StartupTimeout
+		fmt.Fprintf(info, "pictl: watching %d file(s) for %s\n", len(paths), target.Name)
+		launched = true
+		go func() { done <- controlplane.LaunchPiContext(runCtx, spec) }()
+	}
+
+	select {
+	case changed := <-changes:
+		fmt.Fprintf(info, "pictl: %s changed; relaunching\n", strings.Join(changed, ", "))
+		cancel()
+		if launched {
+			<-done
+		}
+		return exitOK, false
+	case launchErr := <-done:
+		if errors.Is(launchErr, controlplane.ErrPiNotFound) {
+			return exitCodeForError(launchErr), true
+		}
+		if ctx.Err() != nil {
+			return exitOK, false
+		}
+		fmt.Fprintf(info, "pictl: pi exited; relaunching when a watched file changes (Ctrl-C to stop)\n")
+		select {
+		case changed := <-changes:
+			fmt.Fprintf(info, "pictl: %s changed; relaunching\n", strings.Join(changed, ", "))
+		case <-ctx.Done():
+		}
+		return exitOK, false
+	case <-ctx.Done():
+		if launched {
+			<-done
+		}
+		return exitOK, false
+	}
+}
+
+func watchedTargetPaths(opts globalOptions, target controlplane.Target, forwarded []string) ([]string, error) {
+	root, err := determineRoot(opts)
+	if err != nil {
+		return nil, err
+	}
+	slices, err := controlplane.LoadSlices(root)
+	if err != nil {
+		return nil, err
+	}
+	_, sliceName, err := controlplane.LookupTargetSlice(slices, target)
+	if err != nil {
+		return nil, err
+	}
+	return controlplane.WatchedPaths(root, sliceName, slices, launchOptions(opts, forwarded))
+}
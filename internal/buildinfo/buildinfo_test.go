@@ -0,0 +1,34 @@
+package buildinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCurrentDefaults(t *testing.T) {
+	prevVersion := Version
+	t.Cleanup(func() { Version = prevVersion })
+	Version = ""
+
+	info := Current()
+	if info.Version != "dev" {
+		t.Fatalf("expected dev version when unset, got %q", info.Version)
+	}
+	if info.Commit == "" || info.Date == "" || info.GoVersion == "" {
+		t.Fatalf("expected every field to be populated, got %+v", info)
+	}
+}
+
+func TestCurrentUsesInjectedValues(t *testing.T) {
+	prevVersion, prevCommit, prevDate := Version, Commit, Date
+	t.Cleanup(func() { Version, Commit, Date = prevVersion, prevCommit, prevDate })
+	Version, Commit, Date = "v1.2.3", "abc123", "2026-01-02T03:04:05Z"
+
+	info := Current()
+	if info.Version != "v1.2.3" || info.Commit != "abc123" || info.Date != "2026-01-02T03:04:05Z" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if !strings.HasPrefix(info.String(), "v1.2.3 (commit abc123") {
+		t.Fatalf("unexpected string form: %s", info.String())
+	}
+}
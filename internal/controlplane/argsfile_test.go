@@ -0,0 +1,35 @@
+package controlplane
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseArgsFile(t *testing.T) {
+	raw := []byte("# model flags\n--model openai/gpt-5\n\n  --thinking high  \n--system-prompt 'be brief, \"really\"'\n--note \"a \\\"quoted\\\" word\" plain\\ space\n")
+
+	got, err := ParseArgsFile(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"--model", "openai/gpt-5", "--thinking", "high", "--system-prompt", `be brief, "really"`, "--note", `a "quoted" word`, "plain space"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	if _, err := ParseArgsFile([]byte("--ok\n--bad 'open\n")); err == nil || !strings.Contains(err.Error(), "line 2: unterminated ' quote") {
+		t.Fatalf("expected unterminated quote error on line 2, got %v", err)
+	}
+}
+
+func TestLoadArgsFileMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.args")
+	_, err := LoadArgsFile(path)
+	if !errors.Is(err, os.ErrNotExist) || !strings.Contains(err.Error(), "args file "+path) {
+		t.Fatalf("expected missing args file error naming the path, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const BranchProfilesFile = "branch-profiles.json"
+
+type BranchProfileRule struct {
+	Branch  string `json:"branch"`
+	Profile string `json:"profile"`
+}
+
+var DefaultBranchProfileRules = []BranchProfileRule{
+	{Branch: "main", Profile: "ship"},
+	{Branch: "master", Profile: "ship"},
+	{Branch: "*", Profile: "execute"},
+}
+
+var branchProfileRules = DefaultBranchProfileRules
+
+var branchProfilesPath string
+
+func DefaultBranchProfilesPath(root string) string {
+	return filepath.Join(root, BranchProfilesFile)
+}
+
+func LoadBranchProfileRules(path string) ([]BranchProfileRule, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []BranchProfileRule
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, fmt.Errorf("branch profiles %s: %w", path, err)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("branch profiles %s: no rules defined", path)
+	}
+	for i, rule := range rules {
+		if strings.TrimSpace(rule.Branch) == "" || strings.TrimSpace(rule.Profile) == "" {
+			return nil, fmt.Errorf("branch profiles %s: rule %d needs a branch and a profile", path, i+1)
+		}
+	}
+	return rules, nil
+}
+
+func UseBranchProfileRules(path string, rules []BranchProfileRule) {
+	if len(rules) == 0 {
+		path, rules = "", DefaultBranchProfileRules
+	}
+	branchProfilesPath = path
+	branchProfileRules = append([]BranchProfileRule(nil), rules...)
+}
+
+func BranchProfilesPath() string {
+	return branchProfilesPath
+}
+
+func ProfileForBranch(branch string, rules []BranchProfileRule) (string, bool) {
+	branch = strings.TrimSpace(branch)
+	if branch == "" {
+		return "", false
+	}
+	for _, rule := range rules {
+		if matchBranch(strings.TrimSpace(rule.Branch), branch) {
+			return strings.TrimSpace(rule.Profile), true
+		}
+	}
+	return "", false
+}
+
+func matchBranch(pattern string, branch string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == branch
+	}
+	rest, ok := strings.CutPrefix(branch, parts[0])
+	if !ok {
+		return false
+	}
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+	return len(rest) >= len(last) && strings.HasSuffix(rest, last)
+}
+
+func CurrentGitBranch(dir string) (string, bool) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", false
+	}
+	output, err := exec.Command("git", "-C", dir, "symbolic-ref", "--quiet", "--short", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+	branch := strings.TrimSpace(string(output))
+	return branch, branch != ""
+}
+
+func DecideBranchProfile(decision ProfileDecision, dir string) (ProfileDecision, string) {
+	switch decision.Source {
+	case ProfileFromForwarded, ProfileFromRequest, ProfileFromEnv:
+		return decision, fmt.Sprintf("--profile-from-branch skipped: profile %s is set explicitly", decision.Profile)
+	}
+	branch, ok := CurrentGitBranch(dir)
+	if !ok {
+		return decision, "--profile-from-branch skipped: not on a git branch"
+	}
+	profile, ok := ProfileForBranch(branch, branchProfileRules)
+	if !ok {
+		return decision, fmt.Sprintf("--profile-from-branch skipped: no rule matches branch %s", branch)
+	}
+	return ProfileDecision{Profile: profile, Source: ProfileFromBranch}, fmt.Sprintf("profile %s from git branch %s", profile, branch)
+}
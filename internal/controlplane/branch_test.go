@@ -0,0 +1,114 @@
+package controlplane
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfileForBranch(t *testing.T) {
+	custom := []BranchProfileRule{
+		{Branch: "release-*", Profile: "ship"},
+		{Branch: "spike/*/notes", Profile: "fast"},
+		{Branch: "feature/*", Profile: "execute"},
+	}
+	cases := []struct {
+		branch string
+		rules  []BranchProfileRule
+		want   string
+		ok     bool
+	}{
+		{"main", DefaultBranchProfileRules, "ship", true},
+		{"master", DefaultBranchProfileRules, "ship", true},
+		{"feature/login", DefaultBranchProfileRules, "execute", true},
+		{"mainline", DefaultBranchProfileRules, "execute", true},
+		{"", DefaultBranchProfileRules, "", false},
+		{"release-1.2", custom, "ship", true},
+		{"spike/x/y/notes", custom, "fast", true},
+		{"feature/login", custom, "execute", true},
+		{"main", custom, "", false},
+	}
+	for _, tc := range cases {
+		got, ok := ProfileForBranch(tc.branch, tc.rules)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("ProfileForBranch(%q) = %q, %t; want %q, %t", tc.branch, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestLoadBranchProfileRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), BranchProfilesFile)
+	if err := os.WriteFile(path, []byte(`[{"branch": "trunk", "profile": "ship"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadBranchProfileRules(path)
+	if err != nil || len(rules) != 1 || rules[0].Branch != "trunk" {
+		t.Fatalf("unexpected rules %+v, %v", rules, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"branch": "trunk"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBranchProfileRules(path); err == nil || !strings.Contains(err.Error(), "rule 1 needs a branch and a profile") {
+		t.Fatalf("expected an incomplete rule error, got %v", err)
+	}
+}
+
+func TestBuildLaunchSpecProfileFromBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/a.ts")
+	unsetProfileEnv(t)
+	manifest := SliceManifest{DefaultProfile: "fast", Extensions: extensionRefs("extensions/a.ts")}
+
+	work := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(work))
+	t.Chdir(work)
+	git := func(args ...string) {
+		t.Helper()
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+
+	launch := func(opts LaunchOptions) LaunchSpec {
+		t.Helper()
+		opts.BranchProfile = true
+		spec, err := BuildLaunchSpecWithOptions(root, manifest, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return spec
+	}
+
+	spec := launch(LaunchOptions{})
+	if !hasEnv(spec.Env, "PI_DEFAULT_PROFILE=fast") || !strings.Contains(strings.Join(spec.Notes, "\n"), "not on a git branch") {
+		t.Fatalf("expected the slice default outside git, got env %v notes %v", spec.Env, spec.Notes)
+	}
+
+	git("init", "--quiet")
+	git("symbolic-ref", "HEAD", "refs/heads/main")
+	if spec := launch(LaunchOptions{}); !hasEnv(spec.Env, "PI_DEFAULT_PROFILE=ship") {
+		t.Fatalf("expected ship on main, got %v", spec.Env)
+	}
+
+	git("symbolic-ref", "HEAD", "refs/heads/feature/login")
+	if spec := launch(LaunchOptions{}); !hasEnv(spec.Env, "PI_DEFAULT_PROFILE=execute") {
+		t.Fatalf("expected execute on a feature branch, got %v", spec.Env)
+	}
+
+	if spec := launch(LaunchOptions{Profile: "meta"}); !hasEnv(spec.Env, "PI_DEFAULT_PROFILE=meta") {
+		t.Fatalf("expected an explicit --profile to win, got %v", spec.Env)
+	}
+
+	other := t.TempDir()
+	git("-C", other, "init", "--quiet")
+	git("-C", other, "symbolic-ref", "HEAD", "refs/heads/main")
+	if spec := launch(LaunchOptions{Dir: other}); !hasEnv(spec.Env, "PI_DEFAULT_PROFILE=ship") {
+		t.Fatalf("expected the branch of the launch directory, got %v", spec.Env)
+	}
+}
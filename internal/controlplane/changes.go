@@ -0,0 +1,86 @@
+package controlplane
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	ChangesByMtime = "mtime"
+	ChangesByGit   = "git"
+)
+
+func ChangedSliceFiles(root string, since string, now time.Time) ([]SliceFile, string, error) {
+	files, err := SliceFiles(root)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if window, err := time.ParseDuration(since); err == nil {
+		if window < 0 {
+			return nil, "", fmt.Errorf("--since %q: duration must not be negative", since)
+		}
+		changed, err := SlicesModifiedSince(files, now.Add(-window))
+		return changed, ChangesByMtime, err
+	}
+
+	sliceDir := SliceDir(root)
+	paths, err := gitChangedPaths(sliceDir, since)
+	if err != nil {
+		return nil, "", fmt.Errorf("--since %q is not a duration and cannot be used as a git ref: %w", since, err)
+	}
+	var changed []SliceFile
+	for _, file := range files {
+		rel, err := filepath.Rel(sliceDir, file.Path)
+		if err == nil && paths[filepath.ToSlash(rel)] {
+			changed = append(changed, file)
+		}
+	}
+	return changed, ChangesByGit, nil
+}
+
+func SlicesModifiedSince(files []SliceFile, cutoff time.Time) ([]SliceFile, error) {
+	var changed []SliceFile
+	for _, file := range files {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.ModTime().Before(cutoff) {
+			changed = append(changed, file)
+		}
+	}
+	return changed, nil
+}
+
+func gitChangedPaths(dir string, ref string) (map[string]bool, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git not found in PATH")
+	}
+
+	paths := make(map[string]bool)
+	commands := [][]string{
+		{"diff", "--name-only", "--relative", ref, "--", "."},
+		{"ls-files", "--others", "--exclude-standard", "--", "."},
+	}
+	for _, args := range commands {
+		var errOut bytes.Buffer
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Stderr = &errOut
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("git %s: %s", args[0], firstLine(errOut.String(), err))
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				paths[line] = true
+			}
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,61 @@
+package controlplane
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSlicesModifiedSince(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ages := map[string]time.Duration{"fresh": time.Hour, "edge": 24 * time.Hour, "stale": 72 * time.Hour}
+	for name, age := range ages {
+		path := filepath.Join(root, "slices", name+".json")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(`{"extensions": ["extensions/x.ts"]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, method, err := ChangedSliceFiles(root, "24h", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != ChangesByMtime {
+		t.Fatalf("expected a duration to select by mtime, got %q", method)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	if len(names) != 2 || names[0] != "edge" || names[1] != "fresh" {
+		t.Fatalf("expected edge and fresh, got %v", names)
+	}
+
+	files, _, err = ChangedSliceFiles(root, "30m", now)
+	if err != nil || len(files) != 0 {
+		t.Fatalf("expected no slices in a 30m window, got %v (%v)", files, err)
+	}
+	if _, _, err := ChangedSliceFiles(root, "-1h", now); err == nil {
+		t.Fatalf("expected negative duration to fail")
+	}
+}
+
+func TestChangedSliceFilesGitRefOutsideRepo(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "slices"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(root))
+
+	if _, _, err := ChangedSliceFiles(root, "main", time.Now()); err == nil {
+		t.Fatalf("expected a git ref outside a repository to fail")
+	}
+}
@@ -1,33 +1,162 @@
 package controlplane
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"syscall"
+	"time"
 )
 
+var (
+	ErrRootNotFound      = errors.New("unable to locate pi-agent-config root")
+	ErrInvalidRoot       = errors.New("not a valid pi-agent-config root")
+	ErrUnknownTarget     = errors.New("unknown target")
+	ErrSliceNotFound     = errors.New("unknown slice")
+	ErrNoSlicesDir       = errors.New("no slices/ directory found")
+	ErrSliceDisabled     = errors.New("slice is disabled")
+	ErrExtensionMissing  = errors.New("extension path missing")
+	ErrPiNotFound        = errors.New("pi executable not found in PATH")
+	ErrInvalidExtension  = errors.New("invalid extension file")
+	ErrProfileNotAllowed = errors.New("profile not allowed for slice")
+	ErrLaunchTimeout     = errors.New("pi launch timed out")
+)
+
+const launchKillGrace = 5 * time.Second
+
+var extensionFileSuffixes = []string{".ts", ".js", ".mjs"}
+
+type MarkerMode int
+
+const (
+	MarkersAll MarkerMode = iota
+	MarkersAny
+)
+
+var DefaultRootMarkers = []string{"settings.json", "slices", "extensions"}
+
 type SliceManifest struct {
-	Description    string   `json:"description"`
-	DefaultProfile string   `json:"defaultProfile"`
-	Extensions     []string `json:"extensions"`
+	SchemaVersion   int            `json:"schemaVersion,omitempty"`
+	Description     string         `json:"description"`
+	DefaultProfile  string         `json:"defaultProfile"`
+	Extensions      []ExtensionRef `json:"extensions"`
+	ExtensionDirs   []string       `json:"extensionDirs,omitempty"`
+	Include         []string       `json:"include,omitempty"`
+	Enabled         *bool          `json:"enabled,omitempty"`
+	AllowedProfiles []string       `json:"allowedProfiles,omitempty"`
+	Model           string         `json:"model,omitempty"`
+	Tags            []string       `json:"tags,omitempty"`
+	Requires        []string       `json:"requires,omitempty"`
+	Cwd             string         `json:"cwd,omitempty"`
 }
 
 type Target struct {
-	Name           string
-	Slice          string
-	DefaultProfile string
-	Description    string
-	Aliases        []string
+	Name           string   `json:"name"`
+	Slice          string   `json:"slice"`
+	DefaultProfile string   `json:"defaultProfile"`
+	Description    string   `json:"description"`
+	Category       string   `json:"category"`
+	Aliases        []string `json:"aliases"`
+	DefaultArgs    []string `json:"defaultArgs,omitempty"`
+	FallbackSlice  string   `json:"fallbackSlice,omitempty"`
+	Confirm        bool     `json:"confirm,omitempty"`
+	Hidden         bool     `json:"hidden,omitempty"`
+}
+
+type TargetAlias struct {
+	Alias  string `json:"alias"`
+	Target string `json:"target"`
+}
+
+type TargetGroup struct {
+	Category string
+	Targets  []Target
+}
+
+type Profile struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases"`
+	Description string   `json:"description"`
 }
 
 type LaunchSpec struct {
-	Args []string
-	Env  []string
+	Args      []string
+	Env       []string
+	Dir       string
+	StdinFile string
+	Notes     []string
+	Warnings  []string
+	Timeout   time.Duration
+
+	StartupTimeout time.Duration
+}
+
+type RetryPolicy struct {
+	Retries   int
+	Codes     []int
+	BaseDelay time.Duration
+	OnRetry   func(attempt int, code int, delay time.Duration)
+}
+
+type ProfileSource string
+
+const (
+	ProfileFromForwarded ProfileSource = "forwarded"
+	ProfileFromEnv       ProfileSource = "env"
+	ProfileFromRequest   ProfileSource = "request"
+	ProfileFromTarget    ProfileSource = "target"
+	ProfileFromSlice     ProfileSource = "slice"
+	ProfileFromBranch    ProfileSource = "branch"
+	ProfileFromPictlEnv  ProfileSource = "pictl-env"
+	ProfileFromNone      ProfileSource = "none"
+)
+
+type ProfileDecision struct {
+	Profile string
+	Source  ProfileSource
+}
+
+type ResolvedExtensions struct {
+	Paths    []string
+	Dirs     []string
+	Notes    []string
+	Warnings []string
+}
+
+type LaunchOptions struct {
+	Strict           bool
+	Profile          string
+	AppendProfile    string
+	DefaultProfile   string
+	ForwardedArgs    []string
+	StrictExtensions bool
+	StrictPaths      bool
+	SkipExtensions   []string
+	OnlyExtensions   []string
+	EnvFiles         []string
+	NoEnvFile        bool
+	EnvProfile       string
+	Env              []string
+	SkipRequires     bool
+	BranchProfile    bool
+	Dir              string
+	StdinFile        string
+}
+
+type SliceFile struct {
+	Name string
+	Path string
 }
 
 type SliceInfo struct {
@@ -41,6 +170,7 @@ var canonicalTargets = []Target{
 		Slice:          "meta",
 		DefaultProfile: "meta",
 		Description:    "Pi platform architecture + orchestration development",
+		Category:       "Engineering",
 		Aliases:        []string{"pi-dev", "pidev", "recon", "docs"},
 	},
 	{
@@ -48,6 +178,7 @@ var canonicalTargets = []Target{
 		Slice:          "software",
 		DefaultProfile: "execute",
 		Description:    "Daily software engineering workflow",
+		Category:       "Engineering",
 		Aliases:        []string{"software", "delivery", "dev", "eng", "work", "devflow", "ship", "release", "auto", "autopilot", "research"},
 	},
 	{
@@ -55,261 +186,1589 @@ var canonicalTargets = []Target{
 		Slice:          "sysadmin",
 		DefaultProfile: "execute",
 		Description:    "System reliability, incident forensics, and watchdog workflows",
+		Category:       "Ops",
 		Aliases:        []string{"sysadmin", "admin", "argus", "guardian"},
+		Confirm:        true,
 	},
 	{
 		Name:           "daybook",
 		Slice:          "daybook",
 		DefaultProfile: "fast",
 		Description:    "Charisma-first journaling and brainstorming workflow",
+		Category:       "Writing",
 		Aliases:        []string{"journal", "diary"},
 	},
 }
 
+var canonicalProfiles = []Profile{
+	{
+		Name:        "ultrathink",
+		Aliases:     []string{"meta", "deep", "think"},
+		Description: "Deep architecture/reflection mode",
+	},
+	{
+		Name:        "execute",
+		Aliases:     []string{"build", "dev", "workhorse"},
+		Description: "Balanced implementation mode",
+	},
+	{
+		Name:        "ship",
+		Aliases:     []string{"release", "deliver"},
+		Description: "End-to-end delivery + verification mode",
+	},
+	{
+		Name:        "fast",
+		Aliases:     []string{"quick"},
+		Description: "Quick unblock mode with minimal thinking",
+	},
+}
+
 var aliasToTarget = buildAliasMap(canonicalTargets)
 
+var aliasToProfile = buildProfileAliasMap(canonicalProfiles)
+
 func CanonicalTargets() []Target {
 	out := make([]Target, len(canonicalTargets))
 	copy(out, canonicalTargets)
 	return out
 }
 
+func GroupTargets(targets []Target, category string) []TargetGroup {
+	filter := strings.ToLower(strings.TrimSpace(category))
+
+	var groups []TargetGroup
+	index := make(map[string]int)
+	for _, target := range targets {
+		name := target.Category
+		if name == "" {
+			name = "Other"
+		}
+		if filter != "" && strings.ToLower(name) != filter {
+			continue
+		}
+
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, TargetGroup{Category: name})
+		}
+		groups[i].Targets = append(groups[i].Targets, target)
+	}
+	return groups
+}
+
+func PickerOrder(targets []Target) []Target {
+	var out []Target
+	for _, group := range GroupTargets(targets, "") {
+		out = append(out, group.Targets...)
+	}
+	return out
+}
+
+func VisibleTargets(targets []Target, showHidden bool) []Target {
+	if showHidden {
+		return targets
+	}
+	var out []Target
+	for _, target := range targets {
+		if !target.Hidden {
+			out = append(out, target)
+		}
+	}
+	return out
+}
+
+func TargetByIndex(index int, showHidden bool) (Target, error) {
+	ordered := PickerOrder(VisibleTargets(canonicalTargets, showHidden))
+	if index < 1 || index > len(ordered) {
+		return Target{}, fmt.Errorf("%w: index %d out of range (1-%d)", ErrUnknownTarget, index, len(ordered))
+	}
+	return ordered[index-1], nil
+}
+
 func ResolveTarget(name string) (Target, bool) {
+	return resolveTargetIn(canonicalTargets, aliasToTarget, name)
+}
+
+func CanonicalProfiles() []Profile {
+	out := make([]Profile, len(canonicalProfiles))
+	copy(out, canonicalProfiles)
+	return out
+}
+
+func ResolveProfile(name string) (Profile, bool) {
 	normalized := strings.ToLower(strings.TrimSpace(name))
 	if normalized == "" {
-		return Target{}, false
+		return Profile{}, false
 	}
 
-	index, ok := aliasToTarget[normalized]
+	index, ok := aliasToProfile[normalized]
 	if !ok {
-		return Target{}, false
+		return Profile{}, false
 	}
-	return canonicalTargets[index], true
+	return canonicalProfiles[index], true
+}
+
+func canonicalProfileName(name string) string {
+	if profile, ok := ResolveProfile(name); ok {
+		return profile.Name
+	}
+	return strings.ToLower(strings.TrimSpace(name))
 }
 
 func DetermineRoot(rootOverride string) (string, error) {
+	return DetermineRootTrace(rootOverride, nil)
+}
+
+func DetermineRootTrace(rootOverride string, trace func(string)) (string, error) {
+	root, _, err := cachedResolveRoot(rootOverride, trace)
+	return root, err
+}
+
+type RootSource string
+
+const (
+	RootFromFlag    RootSource = "--root"
+	RootFromEnv     RootSource = "PI_AGENT_CONFIG_ROOT"
+	RootFromWorkdir RootSource = "working directory"
+	RootFromHome    RootSource = "home candidate"
+)
+
+func ResolveRoot(rootOverride string, trace func(string)) (string, RootSource, error) {
+	if trace == nil {
+		trace = func(string) {}
+	}
+
 	if rootOverride != "" {
-		return mustBeRoot(rootOverride)
+		root, err := mustBeRoot(rootOverride)
+		if err != nil {
+			trace(fmt.Sprintf("--root %s: %v", rootOverride, err))
+			return "", "", err
+		}
+		trace("root from --root: " + root)
+		return root, RootFromFlag, nil
 	}
+	trace("--root not given")
 
 	if envRoot := strings.TrimSpace(os.Getenv("PI_AGENT_CONFIG_ROOT")); envRoot != "" {
 		root, err := mustBeRoot(envRoot)
 		if err == nil {
-			return root, nil
+			trace("root from PI_AGENT_CONFIG_ROOT: " + root)
+			return root, RootFromEnv, nil
 		}
+		trace(fmt.Sprintf("ignoring PI_AGENT_CONFIG_ROOT: %v", err))
+	} else {
+		trace("PI_AGENT_CONFIG_ROOT not set")
 	}
 
 	if cwd, err := os.Getwd(); err == nil {
-		if root, ok := findRootUp(cwd); ok {
-			return root, nil
+		if root, ok := findRootUp(cwd, trace); ok {
+			trace("root found above working directory: " + root)
+			return root, RootFromWorkdir, nil
 		}
+	} else {
+		trace(fmt.Sprintf("skipping working directory walk: %v", err))
 	}
 
-	home, _ := os.UserHomeDir()
-	candidates := []string{
-		filepath.Join(home, "Development", "pi-agent-config"),
-	}
-
-	for _, candidate := range candidates {
+	for _, candidate := range HomeRootCandidates() {
 		root, err := mustBeRoot(candidate)
 		if err == nil {
-			return root, nil
+			trace("root from home candidate: " + root)
+			return root, RootFromHome, nil
 		}
+		trace("no root at home candidate " + candidate)
+	}
+
+	return "", "", fmt.Errorf("%w; use --root or set PI_AGENT_CONFIG_ROOT", ErrRootNotFound)
+}
+
+func HomeRootCandidates() []string {
+	home, _ := os.UserHomeDir()
+
+	configHome := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME"))
+	if configHome == "" || !filepath.IsAbs(configHome) {
+		configHome = filepath.Join(home, ".config")
 	}
 
-	return "", errors.New("unable to locate pi-agent-config root; use --root or set PI_AGENT_CONFIG_ROOT")
+	return []string{
+		filepath.Join(home, "Development", "pi-agent-config"),
+		filepath.Join(configHome, "pi-agent-config"),
+		filepath.Join(home, ".pi-agent-config"),
+	}
 }
 
 func LoadSlices(root string) (map[string]SliceManifest, error) {
-	sliceDir := filepath.Join(root, "slices")
-	entries, err := os.ReadDir(sliceDir)
+	slices, err := LoadSliceManifests(root)
 	if err != nil {
-		return nil, fmt.Errorf("read slices dir: %w", err)
+		return nil, err
+	}
+	if err := expandIncludes(slices); err != nil {
+		return nil, err
+	}
+
+	return slices, nil
+}
+
+func LoadSliceManifests(root string) (map[string]SliceManifest, error) {
+	files, err := SliceFiles(root)
+	if err != nil {
+		return nil, err
 	}
 
 	slices := make(map[string]SliceManifest)
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
-			continue
+	for _, file := range files {
+		manifest, err := loadSliceManifest(file.Path)
+		if err != nil {
+			return nil, fmt.Errorf("load slice %s: %w", file.Name, err)
+		}
+		slices[file.Name] = manifest
+	}
+
+	if len(slices) == 0 {
+		return nil, fmt.Errorf("no slice manifests found in %s", SliceDir(root))
+	}
+	return slices, nil
+}
+
+func expandIncludes(slices map[string]SliceManifest) error {
+	expanded := make(map[string][]ExtensionRef)
+	requires := make(map[string][]string)
+	dirs := make(map[string][]string)
+	var expand func(name string, stack []string) ([]ExtensionRef, error)
+	expand = func(name string, stack []string) ([]ExtensionRef, error) {
+		if refs, ok := expanded[name]; ok {
+			return refs, nil
+		}
+		if err := sliceCycle("include", stack, name); err != nil {
+			return nil, err
+		}
+
+		manifest := slices[name]
+		stack = append(stack, name)
+		var refs []ExtensionRef
+		for _, include := range manifest.Include {
+			include = strings.TrimSpace(include)
+			if _, ok := slices[include]; !ok {
+				return nil, fmt.Errorf("include %q: %w", include, ErrSliceNotFound)
+			}
+			included, err := expand(include, stack)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, included...)
+			requires[name] = mergeRequires(requires[name], requires[include])
+			dirs[name] = mergeRequires(dirs[name], dirs[include])
 		}
+		refs = dedupeExtensionRefs(append(refs, manifest.Extensions...))
+		expanded[name] = refs
+		requires[name] = mergeRequires(requires[name], manifest.Requires)
+		dirs[name] = mergeRequires(dirs[name], manifest.ExtensionDirs)
+		return refs, nil
+	}
 
-		name := strings.TrimSuffix(entry.Name(), ".json")
-		manifestPath := filepath.Join(sliceDir, entry.Name())
-		manifest, err := loadSliceManifest(manifestPath)
+	for _, name := range sortedSliceNames(slices) {
+		refs, err := expand(name, nil)
 		if err != nil {
-			return nil, fmt.Errorf("load slice %s: %w", name, err)
+			return fmt.Errorf("load slice %s: %w", name, err)
+		}
+		if len(refs) == 0 && len(dirs[name]) == 0 {
+			return fmt.Errorf("load slice %s: extensions must not be empty (including included slices)", name)
 		}
+		manifest := slices[name]
+		manifest.Extensions = refs
+		manifest.ExtensionDirs = dirs[name]
+		manifest.Requires = requires[name]
 		slices[name] = manifest
 	}
+	return nil
+}
 
-	if len(slices) == 0 {
-		return nil, errors.New("no slice manifests found")
+func sliceCycle(relation string, stack []string, name string) error {
+	for i, seen := range stack {
+		if seen == name {
+			return fmt.Errorf("%s cycle: %s", relation, strings.Join(append(append([]string{}, stack[i:]...), name), " -> "))
+		}
 	}
+	return nil
+}
 
-	return slices, nil
+func dedupeExtensionRefs(refs []ExtensionRef) []ExtensionRef {
+	seen := make(map[string]bool, len(refs))
+	out := make([]ExtensionRef, 0, len(refs))
+	for _, ref := range refs {
+		key := strings.Join([]string{strings.TrimSpace(ref.Path), strings.Join(ref.OS, ","), strings.Join(ref.Arch, ",")}, "|")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, ref)
+	}
+	return out
 }
 
-func SortedSliceInfos(slices map[string]SliceManifest) []SliceInfo {
-	infos := make([]SliceInfo, 0, len(slices))
-	for name, manifest := range slices {
-		infos = append(infos, SliceInfo{Name: name, Manifest: manifest})
+const SliceDirEnv = "PICTL_SLICE_DIR"
+
+var sliceDirOverride string
+
+func UseSliceDir(dir string) {
+	sliceDirOverride = strings.TrimSpace(dir)
+}
+
+func SliceDirOverride() string {
+	dir := sliceDirOverride
+	if dir == "" {
+		dir = strings.TrimSpace(os.Getenv(SliceDirEnv))
 	}
-	sort.Slice(infos, func(i, j int) bool {
-		return infos[i].Name < infos[j].Name
-	})
-	return infos
+	if dir == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(expandHome(dir)); err == nil {
+		return abs
+	}
+	return dir
 }
 
-func BuildLaunchSpec(root string, manifest SliceManifest, strict bool, profileOverride string, forwardedArgs []string) (LaunchSpec, error) {
-	if len(manifest.Extensions) == 0 {
-		return LaunchSpec{}, errors.New("slice has no extensions configured")
+func SliceDir(root string) string {
+	if dir := SliceDirOverride(); dir != "" {
+		return dir
 	}
+	return filepath.Join(root, "slices")
+}
 
-	args := []string{"--no-extensions"}
-	if strict {
-		args = append(args, "--no-skills", "--no-prompt-templates", "--no-themes")
+func SliceFiles(root string) ([]SliceFile, error) {
+	sliceDir := SliceDir(root)
+	if info, err := os.Stat(sliceDir); os.IsNotExist(err) && SliceDirOverride() != "" {
+		return nil, fmt.Errorf("%w: slice dir %s (from --slice-dir or %s) does not exist", ErrNoSlicesDir, sliceDir, SliceDirEnv)
+	} else if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w under %s; create one or check --root", ErrNoSlicesDir, root)
+	} else if err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is not a directory", ErrNoSlicesDir, sliceDir)
+	} else if err != nil {
+		return nil, fmt.Errorf("read slices dir: %w", err)
 	}
 
-	for _, rel := range manifest.Extensions {
-		rel = strings.TrimSpace(rel)
-		if rel == "" {
-			continue
+	var files []SliceFile
+	folded := make(map[string]string)
+	err := filepath.WalkDir(sliceDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			return nil
 		}
 
-		extPath := filepath.Join(root, filepath.FromSlash(rel))
-		stat, err := os.Stat(extPath)
+		rel, err := filepath.Rel(sliceDir, path)
 		if err != nil {
-			return LaunchSpec{}, fmt.Errorf("extension path missing: %s", rel)
+			return err
 		}
-		if stat.IsDir() {
-			return LaunchSpec{}, fmt.Errorf("extension path is directory, expected file: %s", rel)
-		}
-		args = append(args, "-e", extPath)
-	}
+		name := strings.TrimSuffix(filepath.ToSlash(rel), ".json")
 
-	args = append(args, forwardedArgs...)
-	env := os.Environ()
+		key := strings.ToLower(name)
+		if existing, ok := folded[key]; ok {
+			return fmt.Errorf("slice name collision: %q and %q", existing, name)
+		}
+		folded[key] = name
 
-	profile := strings.TrimSpace(profileOverride)
-	if profile == "" {
-		profile = strings.TrimSpace(manifest.DefaultProfile)
+		files = append(files, SliceFile{Name: name, Path: path})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return files, nil
+}
 
-	if profile != "" && !HasProfileFlag(forwardedArgs) && strings.TrimSpace(os.Getenv("PI_DEFAULT_PROFILE")) == "" {
-		env = append(env, "PI_DEFAULT_PROFILE="+profile)
+func (m SliceManifest) Validate() error {
+	if len(m.Extensions) == 0 && len(m.ExtensionDirs) == 0 && len(m.Include) == 0 {
+		return errors.New("extensions must not be empty")
+	}
+	if profile := strings.TrimSpace(m.DefaultProfile); profile != "" {
+		if _, ok := ResolveProfile(profile); !ok {
+			return fmt.Errorf("unknown defaultProfile %q", profile)
+		}
+	}
+	for _, profile := range m.AllowedProfiles {
+		if _, ok := ResolveProfile(profile); !ok {
+			return fmt.Errorf("unknown profile %q in allowedProfiles", profile)
+		}
+	}
+	if !m.AllowsProfile(m.DefaultProfile) {
+		return fmt.Errorf("defaultProfile %q is not in allowedProfiles", m.DefaultProfile)
 	}
+	return nil
+}
 
-	return LaunchSpec{Args: args, Env: env}, nil
+func (m SliceManifest) IsEnabled() bool {
+	return m.Enabled == nil || *m.Enabled
 }
 
-func LaunchPi(spec LaunchSpec) error {
-	if _, err := exec.LookPath("pi"); err != nil {
-		return errors.New("pi executable not found in PATH")
+func (m SliceManifest) AllowsProfile(profile string) bool {
+	if len(m.AllowedProfiles) == 0 || strings.TrimSpace(profile) == "" {
+		return true
 	}
 
-	cmd := exec.Command("pi", spec.Args...)
-	cmd.Env = spec.Env
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-func HasProfileFlag(args []string) bool {
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-		if arg == "--profile" {
-			return true
-		}
-		if strings.HasPrefix(arg, "--profile=") {
+	want := canonicalProfileName(profile)
+	for _, allowed := range m.AllowedProfiles {
+		if canonicalProfileName(allowed) == want {
 			return true
 		}
 	}
 	return false
 }
 
-func IsTTY() bool {
-	info, err := os.Stdin.Stat()
+func ValidateSliceFile(root string, name string) (SliceFile, error) {
+	files, err := SliceFiles(root)
 	if err != nil {
-		return false
+		return SliceFile{}, err
 	}
-	return (info.Mode() & os.ModeCharDevice) != 0
+	known := make(map[string]bool, len(files))
+	var match SliceFile
+	for _, file := range files {
+		known[file.Name] = true
+		if file.Name == name {
+			match = file
+		}
+	}
+	if match.Path == "" {
+		return SliceFile{}, fmt.Errorf("%w %q", ErrSliceNotFound, name)
+	}
+
+	manifest, err := loadSliceManifest(match.Path)
+	if err != nil {
+		return match, fmt.Errorf("slice %s (%s): %w", name, match.Path, err)
+	}
+	for _, include := range manifest.Include {
+		if !known[strings.TrimSpace(include)] {
+			return match, fmt.Errorf("slice %s (%s): %w: includes unknown slice %q", name, match.Path, ErrSliceNotFound, include)
+		}
+	}
+	return match, nil
 }
 
-func mustBeRoot(candidate string) (string, error) {
-	candidate = strings.TrimSpace(candidate)
-	if candidate == "" {
-		return "", errors.New("empty path")
+func LookupSlice(slices map[string]SliceManifest, name string) (SliceManifest, error) {
+	manifest, ok := slices[name]
+	if !ok {
+		return SliceManifest{}, fmt.Errorf("%w %q", ErrSliceNotFound, name)
+	}
+	if !manifest.IsEnabled() {
+		return SliceManifest{}, fmt.Errorf("%w: %q (set \"enabled\": true in its manifest to launch it)", ErrSliceDisabled, name)
 	}
+	return manifest, nil
+}
 
-	abs, err := filepath.Abs(candidate)
-	if err != nil {
-		return "", err
+const FallbackSliceEnv = "PICTL_FALLBACK_SLICE"
+
+func LookupTargetSlice(slices map[string]SliceManifest, target Target) (SliceManifest, string, error) {
+	manifest, err := LookupSlice(slices, target.Slice)
+	if !errors.Is(err, ErrSliceNotFound) {
+		return manifest, target.Slice, err
 	}
 
-	if !hasRootMarkers(abs) {
-		return "", fmt.Errorf("not a valid pi-agent-config root: %s", abs)
+	fallback := strings.TrimSpace(target.FallbackSlice)
+	if fallback == "" {
+		fallback = strings.TrimSpace(os.Getenv(FallbackSliceEnv))
 	}
-	return abs, nil
+	if fallback == "" || fallback == target.Slice {
+		return SliceManifest{}, target.Slice, err
+	}
+	manifest, fallbackErr := LookupSlice(slices, fallback)
+	if fallbackErr != nil {
+		return SliceManifest{}, target.Slice, fmt.Errorf("%w (fallback: %v)", err, fallbackErr)
+	}
+	return manifest, fallback, nil
 }
 
-func findRootUp(start string) (string, bool) {
-	current, err := filepath.Abs(start)
-	if err != nil {
-		return "", false
+func MatchSlices(slices map[string]SliceManifest, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid slice pattern %q: %w", pattern, err)
 	}
 
-	for {
-		if hasRootMarkers(current) {
-			return current, true
-		}
-		next := filepath.Dir(current)
-		if next == current {
-			return "", false
+	var names []string
+	for _, info := range EnabledSliceInfos(SortedSliceInfos(slices)) {
+		if ok, _ := path.Match(pattern, info.Name); ok {
+			names = append(names, info.Name)
 		}
-		current = next
 	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w matching %q", ErrSliceNotFound, pattern)
+	}
+	return names, nil
 }
 
-func hasRootMarkers(dir string) bool {
-	markers := []string{
-		filepath.Join(dir, "settings.json"),
-		filepath.Join(dir, "slices"),
-		filepath.Join(dir, "extensions"),
+func SortedSliceInfos(slices map[string]SliceManifest) []SliceInfo {
+	infos := make([]SliceInfo, 0, len(slices))
+	for name, manifest := range slices {
+		infos = append(infos, SliceInfo{Name: name, Manifest: manifest})
 	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
 
-	for _, marker := range markers {
-		if _, err := os.Stat(marker); err != nil {
-			return false
+func EnabledSliceInfos(infos []SliceInfo) []SliceInfo {
+	out := make([]SliceInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.Manifest.IsEnabled() {
+			out = append(out, info)
 		}
 	}
-	return true
+	return out
 }
 
-func loadSliceManifest(path string) (SliceManifest, error) {
-	raw, err := os.ReadFile(path)
-	if err != nil {
-		return SliceManifest{}, err
+func (m SliceManifest) HasTags(tags []string, all bool) bool {
+	have := map[string]bool{}
+	for _, tag := range m.Tags {
+		have[strings.ToLower(strings.TrimSpace(tag))] = true
+	}
+	for _, tag := range tags {
+		if have[strings.ToLower(strings.TrimSpace(tag))] != all {
+			return !all
+		}
 	}
+	return all
+}
 
-	var manifest SliceManifest
-	if err := json.Unmarshal(raw, &manifest); err != nil {
-		return SliceManifest{}, err
+func FilterSliceInfosByTag(infos []SliceInfo, tags []string, all bool) []SliceInfo {
+	if len(tags) == 0 {
+		return infos
 	}
+	out := make([]SliceInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.Manifest.HasTags(tags, all) {
+			out = append(out, info)
+		}
+	}
+	return out
+}
+
+type SliceSummary struct {
+	Total      int            `json:"total"`
+	Enabled    int            `json:"enabled"`
+	Disabled   int            `json:"disabled"`
+	Extensions int            `json:"extensions"`
+	Profiles   map[string]int `json:"profiles"`
+}
+
+func SummarizeSlices(infos []SliceInfo) SliceSummary {
+	summary := SliceSummary{Total: len(infos), Profiles: make(map[string]int)}
+	extensions := make(map[string]bool)
+	for _, info := range infos {
+		if !info.Manifest.IsEnabled() {
+			summary.Disabled++
+			continue
+		}
+		summary.Enabled++
 
-	if len(manifest.Extensions) == 0 {
-		return SliceManifest{}, errors.New("extensions must not be empty")
+		profile := "none"
+		if strings.TrimSpace(info.Manifest.DefaultProfile) != "" {
+			profile = canonicalProfileName(info.Manifest.DefaultProfile)
+		}
+		summary.Profiles[profile]++
+		for _, ref := range info.Manifest.Extensions {
+			extensions[strings.TrimSpace(ref.Path)] = true
+		}
 	}
+	summary.Extensions = len(extensions)
+	return summary
+}
 
-	return manifest, nil
+func BuildLaunchSpec(root string, manifest SliceManifest, strict bool, profileOverride string, forwardedArgs []string) (LaunchSpec, error) {
+	return BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{
+		Strict:        strict,
+		Profile:       profileOverride,
+		ForwardedArgs: forwardedArgs,
+	})
 }
 
-func buildAliasMap(targets []Target) map[string]int {
+func BuildLaunchSpecWithOptions(root string, manifest SliceManifest, opts LaunchOptions) (LaunchSpec, error) {
+	if !opts.SkipRequires {
+		if err := CheckRequirements(manifest); err != nil {
+			return LaunchSpec{}, err
+		}
+	}
+	resolved, err := ResolveExtensions(root, manifest, opts)
+	if err != nil {
+		return LaunchSpec{}, err
+	}
+
+	resolved, err = FilterExtensions(resolved, opts.OnlyExtensions, opts.SkipExtensions)
+	if err != nil {
+		return LaunchSpec{}, err
+	}
+	dir, err := LaunchDir(root, opts.Dir, manifest.Cwd)
+	if err != nil {
+		return LaunchSpec{}, err
+	}
+	stdinFile, err := StdinFile(opts.StdinFile)
+	if err != nil {
+		return LaunchSpec{}, err
+	}
+
+	args := []string{"--no-extensions"}
+	if opts.Strict {
+		args = append(args, "--no-skills", "--no-prompt-templates", "--no-themes")
+	}
+	for _, extPath := range resolved.Paths {
+		args = append(args, "-e", extPath)
+	}
+	notes, warnings := resolved.Notes, resolved.Warnings
+
+	if model := strings.TrimSpace(manifest.Model); model != "" && !HasFlag(opts.ForwardedArgs, "--model") {
+		args = append(args, "--model", model)
+	}
+	args = append(args, opts.ForwardedArgs...)
+	env, err := LaunchEnv(root, opts)
+	if err != nil {
+		return LaunchSpec{}, err
+	}
+
+	decision := DecideProfile(opts.Profile, opts.DefaultProfile, manifest.DefaultProfile, opts.ForwardedArgs, env)
+	if opts.BranchProfile {
+		branchDir := "."
+		if dir != "" {
+			branchDir = dir
+		}
+		var note string
+		decision, note = DecideBranchProfile(decision, branchDir)
+		notes = append(notes, note)
+	}
+	appended := strings.TrimSpace(opts.AppendProfile) != ""
+	if appended && decision.Source == ProfileFromForwarded {
+		warnings = append(warnings, fmt.Sprintf("ignoring --append-profile %s: --profile forwarded to Pi replaces the profile", strings.TrimSpace(opts.AppendProfile)))
+		appended = false
+	}
+	if appended {
+		decision.Profile = AppendProfile(decision.Profile, opts.AppendProfile)
+	}
+	for _, part := range ProfileParts(decision.Profile) {
+		if err := CheckProfileKnown(part); err != nil {
+			return LaunchSpec{}, err
+		}
+		if !manifest.AllowsProfile(part) {
+			return LaunchSpec{}, fmt.Errorf("%w: %q (allowed: %s)", ErrProfileNotAllowed, part, strings.Join(manifest.AllowedProfiles, ", "))
+		}
+	}
+
+	switch decision.Source {
+	case ProfileFromRequest, ProfileFromTarget, ProfileFromSlice, ProfileFromBranch, ProfileFromPictlEnv:
+		env = setEnv(env, "PI_DEFAULT_PROFILE", decision.Profile)
+	default:
+		if appended {
+			env = setEnv(env, "PI_DEFAULT_PROFILE", decision.Profile)
+		}
+	}
+
+	for _, entry := range opts.Env {
+		key, value, err := ParseEnvAssignment(entry)
+		if err != nil {
+			return LaunchSpec{}, err
+		}
+		if key == "PI_DEFAULT_PROFILE" {
+			for _, part := range ProfileParts(value) {
+				if err := CheckProfileKnown(part); err != nil {
+					return LaunchSpec{}, err
+				}
+				if !manifest.AllowsProfile(part) {
+					return LaunchSpec{}, fmt.Errorf("%w: %q (allowed: %s)", ErrProfileNotAllowed, part, strings.Join(manifest.AllowedProfiles, ", "))
+				}
+			}
+		}
+		env = setEnv(env, key, value)
+	}
+
+	if dir != "" {
+		notes = append(notes, "pi runs in "+dir)
+	}
+	return LaunchSpec{Args: args, Env: env, Dir: dir, StdinFile: stdinFile, Notes: notes, Warnings: warnings}, nil
+}
+
+func StdinFile(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", nil
+	}
+	resolved, err := filepath.Abs(expandHome(path))
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", fmt.Errorf("--stdin-file: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("--stdin-file: %s is a directory", resolved)
+	}
+	return resolved, nil
+}
+
+func LaunchDir(root string, override string, sliceCwd string) (string, error) {
+	dir, source := strings.TrimSpace(override), "--cwd"
+	if dir == "" {
+		dir, source = strings.TrimSpace(sliceCwd), "slice cwd"
+	}
+	if dir == "" {
+		return "", nil
+	}
+
+	resolved := expandHome(dir)
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(root, resolved)
+	}
+	resolved = filepath.Clean(resolved)
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", fmt.Errorf("%s %q: %w", source, dir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s %q: %s is not a directory", source, dir, resolved)
+	}
+	return resolved, nil
+}
+
+func LaunchEnv(root string, opts LaunchOptions) ([]string, error) {
+	dotenv, err := dotenvEntries(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	envProfile, err := EnvProfileEntries(root, opts.EnvProfile)
+	if err != nil {
+		return nil, err
+	}
+	return FilterEnv(mergeUnder(mergeUnder(os.Environ(), envProfile), dotenv), splitEnvList(os.Getenv("PICTL_FORWARD_ENV")), splitEnvList(os.Getenv("PICTL_BLOCK_ENV"))), nil
+}
+
+const PictlProfileEnv = "PICTL_PROFILE"
+
+func DecideProfile(override string, targetDefault string, sliceDefault string, forwarded []string, env []string) ProfileDecision {
+	if value, ok := ProfileFlagValue(forwarded); ok {
+		return ProfileDecision{Profile: value, Source: ProfileFromForwarded}
+	}
+	if override = strings.TrimSpace(override); override != "" {
+		return ProfileDecision{Profile: override, Source: ProfileFromRequest}
+	}
+	if inherited, _ := lookupEnv(env, "PI_DEFAULT_PROFILE"); strings.TrimSpace(inherited) != "" {
+		return ProfileDecision{Profile: strings.TrimSpace(inherited), Source: ProfileFromEnv}
+	}
+	if targetDefault = strings.TrimSpace(targetDefault); targetDefault != "" {
+		return ProfileDecision{Profile: targetDefault, Source: ProfileFromTarget}
+	}
+	if sliceDefault = strings.TrimSpace(sliceDefault); sliceDefault != "" {
+		return ProfileDecision{Profile: sliceDefault, Source: ProfileFromSlice}
+	}
+	if fallback, _ := lookupEnv(env, PictlProfileEnv); strings.TrimSpace(fallback) != "" {
+		return ProfileDecision{Profile: strings.TrimSpace(fallback), Source: ProfileFromPictlEnv}
+	}
+	return ProfileDecision{Source: ProfileFromNone}
+}
+
+func ProfileParts(profile string) []string {
+	var parts []string
+	for _, part := range strings.Split(profile, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func AppendProfile(base string, extra string) string {
+	var names []string
+	seen := map[string]bool{}
+	for _, part := range append(ProfileParts(base), ProfileParts(extra)...) {
+		name := canonicalProfileName(part)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func FilterEnv(environ []string, allow []string, block []string) []string {
+	if len(allow) == 0 && len(block) == 0 {
+		return environ
+	}
+
+	out := make([]string, 0, len(environ))
+	for _, entry := range environ {
+		key, _, _ := strings.Cut(entry, "=")
+		if len(allow) > 0 && !matchesEnvPattern(key, allow) {
+			continue
+		}
+		if matchesEnvPattern(key, block) {
+			continue
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+func matchesEnvPattern(key string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+			continue
+		}
+		if key == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func splitEnvList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func EnvDiff(env []string, base []string) []string {
+	inherited := make(map[string]bool, len(base))
+	for _, entry := range base {
+		inherited[entry] = true
+	}
+
+	var out []string
+	for _, entry := range env {
+		if !inherited[entry] {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+func EnvRemoved(env []string, base []string) []string {
+	kept := make(map[string]bool, len(env))
+	for _, entry := range env {
+		key, _, _ := strings.Cut(entry, "=")
+		kept[key] = true
+	}
+
+	var out []string
+	seen := make(map[string]bool)
+	for _, entry := range base {
+		key, _, _ := strings.Cut(entry, "=")
+		if key == "" || kept[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, key)
+	}
+	return out
+}
+
+func setEnv(env []string, key string, value string) []string {
+	out := make([]string, 0, len(env)+1)
+	for _, entry := range env {
+		if name, _, _ := strings.Cut(entry, "="); name != key {
+			out = append(out, entry)
+		}
+	}
+	return append(out, key+"="+value)
+}
+
+func lookupEnv(env []string, key string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		if name, value, ok := strings.Cut(env[i], "="); ok && name == key {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func CheckExtensionFile(path string) string {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return "cannot stat file"
+	}
+
+	suffix := strings.ToLower(filepath.Ext(path))
+	known := false
+	for _, candidate := range extensionFileSuffixes {
+		if suffix == candidate {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Sprintf("unexpected file type %q (want %s)", suffix, strings.Join(extensionFileSuffixes, ", "))
+	}
+
+	if stat.Size() == 0 {
+		return "file is empty"
+	}
+	return ""
+}
+
+func ResolveExtensions(root string, manifest SliceManifest, opts LaunchOptions) (ResolvedExtensions, error) {
+	var resolved ResolvedExtensions
+	roots := ExtensionRoots(root)
+	seen := make(map[string]bool)
+	for _, ref := range manifest.Extensions {
+		rel := strings.TrimSpace(ref.Path)
+		if rel == "" {
+			continue
+		}
+		if !ref.Matches(runtime.GOOS, runtime.GOARCH) {
+			resolved.Notes = append(resolved.Notes, fmt.Sprintf("skipped extension %s (not for %s/%s)", rel, runtime.GOOS, runtime.GOARCH))
+			continue
+		}
+
+		extPaths, err := resolveExtension(roots, rel)
+		if err != nil {
+			return ResolvedExtensions{}, err
+		}
+		for _, extPath := range extPaths {
+			if err := resolved.add(extPath, rel, pathSegments(rel), seen, opts); err != nil {
+				return ResolvedExtensions{}, err
+			}
+		}
+	}
+
+	for _, rel := range manifest.ExtensionDirs {
+		rel = strings.TrimSpace(rel)
+		if rel == "" {
+			continue
+		}
+		dir, extPaths, err := resolveExtensionDir(roots, rel)
+		if err != nil {
+			return ResolvedExtensions{}, err
+		}
+		resolved.Dirs = append(resolved.Dirs, dir)
+		if len(extPaths) == 0 {
+			problem := fmt.Sprintf("extension dir %s has no .ts or .js files (%s)", rel, dir)
+			if opts.StrictExtensions {
+				return ResolvedExtensions{}, fmt.Errorf("%w: %s", ErrInvalidExtension, problem)
+			}
+			resolved.Warnings = append(resolved.Warnings, problem)
+			continue
+		}
+		for _, extPath := range extPaths {
+			if err := resolved.add(extPath, rel, pathSegments(rel)+1, seen, opts); err != nil {
+				return ResolvedExtensions{}, err
+			}
+		}
+	}
+
+	if len(resolved.Paths) == 0 {
+		return ResolvedExtensions{}, errors.New("slice has no extensions configured")
+	}
+	return resolved, nil
+}
+
+func (resolved *ResolvedExtensions) add(extPath string, rel string, segments int, seen map[string]bool, opts LaunchOptions) error {
+	key, err := filepath.Abs(extPath)
+	if err != nil {
+		key = extPath
+	}
+	if seen[key] {
+		resolved.Notes = append(resolved.Notes, fmt.Sprintf("dropped duplicate extension %s (from %s)", key, rel))
+		return nil
+	}
+	seen[key] = true
+
+	if mismatch := pathCaseMismatch(caseCheckReadDir, extPath, segments); mismatch != "" {
+		problem := fmt.Sprintf("path case differs from disk (%s); this breaks on case-sensitive filesystems", mismatch)
+		if opts.StrictPaths {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidExtension, rel, problem)
+		}
+		resolved.Warnings = append(resolved.Warnings, fmt.Sprintf("extension %s: %s", rel, problem))
+	}
+	if problem := CheckExtensionFile(extPath); problem != "" {
+		if opts.StrictExtensions {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidExtension, rel, problem)
+		}
+		resolved.Warnings = append(resolved.Warnings, fmt.Sprintf("extension %s: %s", rel, problem))
+	}
+	resolved.Paths = append(resolved.Paths, extPath)
+	return nil
+}
+
+var caseCheckReadDir = os.ReadDir
+
+func pathSegments(rel string) int {
+	clean := filepath.Clean(filepath.FromSlash(expandHome(rel)))
+	return len(strings.Split(strings.TrimPrefix(clean, string(filepath.Separator)), string(filepath.Separator)))
+}
+
+func pathCaseMismatch(readDir func(string) ([]fs.DirEntry, error), path string, segments int) string {
+	current := filepath.Clean(path)
+	for i := 0; i < segments; i++ {
+		dir, name := filepath.Dir(current), filepath.Base(current)
+		if dir == current || name == "." || name == ".." {
+			break
+		}
+		entries, err := readDir(dir)
+		if err != nil {
+			return ""
+		}
+		var folded string
+		exact := false
+		for _, entry := range entries {
+			if entry.Name() == name {
+				exact = true
+				break
+			}
+			if folded == "" && strings.EqualFold(entry.Name(), name) {
+				folded = entry.Name()
+			}
+		}
+		if !exact && folded != "" {
+			return fmt.Sprintf("%q is %q on disk", name, folded)
+		}
+		current = dir
+	}
+	return ""
+}
+
+func FilterExtensions(resolved ResolvedExtensions, only []string, skip []string) (ResolvedExtensions, error) {
+	if len(only) == 0 && len(skip) == 0 {
+		return resolved, nil
+	}
+
+	kept := resolved.Paths[:0:0]
+	for _, extPath := range resolved.Paths {
+		slashed := filepath.ToSlash(extPath)
+		if pattern, ok := firstSubstring(slashed, skip); ok {
+			resolved.Notes = append(resolved.Notes, fmt.Sprintf("skipped extension %s (--skip-ext %s)", extPath, pattern))
+			continue
+		}
+		if _, ok := firstSubstring(slashed, only); len(only) > 0 && !ok {
+			resolved.Notes = append(resolved.Notes, fmt.Sprintf("skipped extension %s (not matched by --only-ext)", extPath))
+			continue
+		}
+		kept = append(kept, extPath)
+	}
+	if len(kept) == 0 {
+		return ResolvedExtensions{}, fmt.Errorf("no extensions left after --only-ext/--skip-ext filters (resolved %d: %s)", len(resolved.Paths), strings.Join(resolved.Paths, ", "))
+	}
+	resolved.Paths = kept
+	return resolved, nil
+}
+
+func firstSubstring(value string, patterns []string) (string, bool) {
+	for _, pattern := range patterns {
+		if pattern != "" && strings.Contains(value, pattern) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func ExtensionRoots(root string) []string {
+	roots := []string{root}
+	seen := map[string]bool{filepath.Clean(root): true}
+	for _, entry := range filepath.SplitList(os.Getenv("PICTL_EXTENSION_PATH")) {
+		entry = expandHome(strings.TrimSpace(entry))
+		if entry == "" || seen[filepath.Clean(entry)] {
+			continue
+		}
+		seen[filepath.Clean(entry)] = true
+		roots = append(roots, entry)
+	}
+	return roots
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+func resolveExtension(roots []string, rel string) ([]string, error) {
+	expanded := expandHome(rel)
+	if filepath.IsAbs(expanded) {
+		roots = []string{""}
+	}
+
+	for _, root := range roots {
+		files, err := resolveExtensionIn(root, expanded)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rel, err)
+		}
+		if len(files) > 0 {
+			return files, nil
+		}
+	}
+
+	searched := strings.Join(roots, ", ")
+	if filepath.IsAbs(expanded) {
+		searched = expanded
+	}
+	if strings.ContainsAny(rel, "*?[") {
+		return nil, fmt.Errorf("%w: %s (glob matched no files; searched %s)", ErrExtensionMissing, rel, searched)
+	}
+	return nil, fmt.Errorf("%w: %s (searched %s)", ErrExtensionMissing, rel, searched)
+}
+
+func resolveExtensionIn(root string, rel string) ([]string, error) {
+	pattern := filepath.FromSlash(rel)
+	if root != "" {
+		pattern = filepath.Join(root, pattern)
+	}
+	if !strings.ContainsAny(rel, "*?[") {
+		found, err := checkExtensionPath(pattern)
+		if err != nil || !found {
+			return nil, err
+		}
+		return []string{pattern}, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extension glob: %w", err)
+	}
+
+	files := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if stat, err := os.Stat(match); err == nil && !stat.IsDir() {
+			files = append(files, match)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func resolveExtensionDir(roots []string, rel string) (string, []string, error) {
+	expanded := expandHome(rel)
+	if filepath.IsAbs(expanded) {
+		roots = []string{""}
+	}
+
+	for _, root := range roots {
+		dir := filepath.FromSlash(expanded)
+		if root != "" {
+			dir = filepath.Join(root, dir)
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			return "", nil, fmt.Errorf("%s: extension dir is a file, list it under extensions instead", rel)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", rel, err)
+		}
+		var files []string
+		for _, entry := range entries {
+			name := entry.Name()
+			if strings.HasSuffix(name, ".d.ts") || (filepath.Ext(name) != ".ts" && filepath.Ext(name) != ".js") {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
+				files = append(files, path)
+			}
+		}
+		sort.Strings(files)
+		return dir, files, nil
+	}
+
+	searched := strings.Join(roots, ", ")
+	if filepath.IsAbs(expanded) {
+		searched = expanded
+	}
+	return "", nil, fmt.Errorf("%w: %s (extension dir; searched %s)", ErrExtensionMissing, rel, searched)
+}
+
+func checkExtensionPath(path string) (bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false, nil
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		if info.IsDir() {
+			return false, errors.New("extension path is directory, expected file")
+		}
+		return true, nil
+	}
+
+	target, _ := os.Readlink(path)
+	resolved, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("%w: extension path is a broken symlink (-> %s)", ErrExtensionMissing, target)
+	}
+	if resolved.IsDir() {
+		return false, fmt.Errorf("extension path is a symlink to a directory (-> %s), expected file; point it at the extension's entry file", target)
+	}
+	return true, nil
+}
+
+func LaunchPi(spec LaunchSpec) error {
+	return runPi(spec, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func LaunchPiCaptured(spec LaunchSpec) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	err := runPi(spec, nil, &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+func LaunchPiContext(ctx context.Context, spec LaunchSpec) error {
+	return runPiContext(ctx, spec, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func runPi(spec LaunchSpec, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	return runPiContext(context.Background(), spec, stdin, stdout, stderr)
+}
+
+func runPiContext(ctx context.Context, spec LaunchSpec, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	if _, err := exec.LookPath("pi"); err != nil {
+		return ErrPiNotFound
+	}
+
+	if spec.StdinFile != "" {
+		file, err := os.Open(spec.StdinFile)
+		if err != nil {
+			return fmt.Errorf("--stdin-file: %w", err)
+		}
+		defer file.Close()
+		stdin = file
+	}
+
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	var startup *startupWatch
+	if spec.StartupTimeout > 0 {
+		startup = newStartupWatch()
+		stdout, stderr = startup.wrap(stdout), startup.wrap(stderr)
+	}
+
+	cmd := exec.CommandContext(runCtx, "pi", spec.Args...)
+	cmd.Env = spec.Env
+	cmd.Dir = spec.Dir
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = launchKillGrace
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if startup != nil {
+		go startup.wait(spec.StartupTimeout, stop)
+	}
+	err := cmd.Wait()
+	if startup != nil {
+		close(startup.exited)
+		if startup.timedOut.Load() {
+			return fmt.Errorf("%w: no output within the %s startup timeout", ErrLaunchTimeout, spec.StartupTimeout)
+		}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrLaunchTimeout, spec.Timeout)
+	}
+	return err
+}
+
+func LaunchPiWithRetry(spec LaunchSpec, policy RetryPolicy) error {
+	delay := policy.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := LaunchPi(spec)
+		if err == nil || attempt > policy.Retries {
+			return err
+		}
+
+		code, retryable := policy.retryable(err)
+		if !retryable {
+			return err
+		}
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, code, delay)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (p RetryPolicy) retryable(err error) (int, bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+
+	code := exitErr.ExitCode()
+	for _, candidate := range p.Codes {
+		if code == candidate {
+			return code, true
+		}
+	}
+	return code, false
+}
+
+func HasProfileFlag(args []string) bool {
+	return HasFlag(args, "--profile")
+}
+
+func HasFlag(args []string, flag string) bool {
+	_, ok := FlagValue(args, flag)
+	return ok
+}
+
+func ProfileFlagValue(args []string) (string, bool) {
+	return FlagValue(args, "--profile")
+}
+
+func FlagValue(args []string, flag string) (string, bool) {
+	value, found := "", false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == flag {
+			found = true
+			value = ""
+			if i+1 < len(args) {
+				value = strings.TrimSpace(args[i+1])
+				i++
+			}
+			continue
+		}
+		if rest, ok := strings.CutPrefix(arg, flag+"="); ok {
+			found = true
+			value = strings.TrimSpace(rest)
+		}
+	}
+	return value, found
+}
+
+func IsTTY() bool {
+	return IsTerminal(os.Stdin)
+}
+
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return (info.Mode() & os.ModeCharDevice) != 0
+}
+
+func mustBeRoot(candidate string) (string, error) {
+	candidate = strings.TrimSpace(candidate)
+	if candidate == "" {
+		return "", errors.New("empty path")
+	}
+
+	abs, err := filepath.Abs(candidate)
+	if err != nil {
+		return "", err
+	}
+
+	if !hasRootMarkers(abs) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidRoot, abs)
+	}
+	return abs, nil
+}
+
+func findRootUp(start string, trace func(string)) (string, bool) {
+	current, err := filepath.Abs(start)
+	if err != nil {
+		return "", false
+	}
+
+	subdirs := RootSubdirs()
+	for {
+		for _, sub := range subdirs {
+			candidate := filepath.Join(current, sub)
+			if hasRootMarkers(candidate) {
+				return candidate, true
+			}
+			if _, err := os.Stat(candidate); err == nil {
+				trace("no root markers in " + candidate)
+			}
+		}
+		if hasRootMarkers(current) {
+			return current, true
+		}
+		trace("no root markers in " + current)
+		next := filepath.Dir(current)
+		if next == current {
+			return "", false
+		}
+		current = next
+	}
+}
+
+func RootSubdirs() []string {
+	var out []string
+	for _, sub := range splitEnvList(os.Getenv("PICTL_ROOT_SUBDIRS")) {
+		sub = filepath.Clean(filepath.FromSlash(sub))
+		if filepath.IsAbs(sub) || sub == "." || sub == ".." || strings.HasPrefix(sub, ".."+string(filepath.Separator)) {
+			continue
+		}
+		out = append(out, sub)
+	}
+	return out
+}
+
+func hasRootMarkers(dir string) bool {
+	markers, mode := RootMarkers()
+	return HasRootMarkers(dir, markers, mode)
+}
+
+func RootMarkers() ([]string, MarkerMode) {
+	spec := strings.TrimSpace(os.Getenv("PICTL_ROOT_MARKERS"))
+	mode := MarkersAll
+	if rest, ok := strings.CutPrefix(spec, "any:"); ok {
+		spec, mode = rest, MarkersAny
+	} else if rest, ok := strings.CutPrefix(spec, "all:"); ok {
+		spec = rest
+	}
+
+	markers := splitEnvList(spec)
+	if len(markers) == 0 {
+		markers = DefaultRootMarkers
+	}
+	return markers, mode
+}
+
+func HasRootMarkers(dir string, markers []string, mode MarkerMode) bool {
+	if len(markers) == 0 {
+		return false
+	}
+	for _, marker := range markers {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(marker)))
+		if mode == MarkersAny && err == nil {
+			return true
+		}
+		if mode == MarkersAll && err != nil {
+			return false
+		}
+	}
+	return mode == MarkersAll
+}
+
+func loadSliceManifest(path string) (SliceManifest, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return SliceManifest{}, err
+	}
+	manifest, err := parseSliceManifest(raw)
+	if err != nil {
+		return SliceManifest{}, err
+	}
+	if err := manifest.Validate(); err != nil {
+		return SliceManifest{}, err
+	}
+	return manifest, nil
+}
+
+func parseSliceManifest(raw []byte) (SliceManifest, error) {
+	clean, _, err := stripJSONC(raw)
+	if err != nil {
+		return SliceManifest{}, err
+	}
+
+	var manifest SliceManifest
+	if err := json.Unmarshal(clean, &manifest); err != nil {
+		return SliceManifest{}, err
+	}
+
+	if manifest.SchemaVersion > CurrentSchemaVersion {
+		return SliceManifest{}, fmt.Errorf("unsupported schemaVersion %d (pictl supports up to %d)", manifest.SchemaVersion, CurrentSchemaVersion)
+	}
+
+	return manifest, nil
+}
+
+func buildAliasMap(targets []Target) map[string]int {
 	out := make(map[string]int)
 	for i, target := range targets {
-		out[target.Name] = i
-		for _, alias := range target.Aliases {
-			out[alias] = i
+		for _, key := range targetKeys(target) {
+			if _, taken := out[key]; !taken {
+				out[key] = i
+			}
+		}
+	}
+	return out
+}
+
+func TargetAliases(targets []Target) []TargetAlias {
+	aliases := buildAliasMap(targets)
+	out := make([]TargetAlias, 0, len(aliases))
+	for alias, index := range aliases {
+		out = append(out, TargetAlias{Alias: alias, Target: targets[index].Name})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Alias < out[j].Alias })
+	return out
+}
+
+func targetKeys(target Target) []string {
+	keys := []string{normalizeAlias(target.Name)}
+	for _, alias := range target.Aliases {
+		keys = append(keys, normalizeAlias(alias))
+	}
+	return keys
+}
+
+func normalizeAlias(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func ValidateTargets(targets []Target) error {
+	owners := make(map[string]string)
+	var problems []string
+	for _, target := range targets {
+		for _, key := range targetKeys(target) {
+			if key == "" {
+				problems = append(problems, fmt.Sprintf("target %q has an empty name or alias", target.Name))
+				continue
+			}
+			if owner, taken := owners[key]; taken && owner != target.Name {
+				problems = append(problems, fmt.Sprintf("%q is claimed by both %q and %q", key, owner, target.Name))
+				continue
+			}
+			owners[key] = target.Name
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("target alias collision: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func resolveTargetIn(targets []Target, index map[string]int, name string) (Target, bool) {
+	normalized := normalizeAlias(name)
+	if normalized == "" {
+		return Target{}, false
+	}
+
+	i, ok := index[normalized]
+	if !ok {
+		return Target{}, false
+	}
+	return targets[i], true
+}
+
+func buildProfileAliasMap(profiles []Profile) map[string]int {
+	out := make(map[string]int)
+	for i, profile := range profiles {
+		out[normalizeAlias(profile.Name)] = i
+		for _, alias := range profile.Aliases {
+			out[normalizeAlias(alias)] = i
 		}
 	}
 	return out
@@ -1,11 +1,53 @@
 package controlplane
 
 import (
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
+func TestResolveTargetNormalizesAliases(t *testing.T) {
+	targets := []Target{
+		{Name: "Build", Slice: "software", Aliases: []string{"Ship", " Delivery "}},
+		{Name: "meta", Slice: "meta"},
+	}
+	index := buildAliasMap(targets)
+
+	for _, input := range []string{"ship", " SHIP ", "Ship", "delivery", "build", " BUILD"} {
+		target, ok := resolveTargetIn(targets, index, input)
+		if !ok {
+			t.Fatalf("expected %q to resolve", input)
+		}
+		if target.Name != "Build" {
+			t.Fatalf("expected display name to keep its casing, got %q", target.Name)
+		}
+	}
+}
+
+func TestValidateTargets(t *testing.T) {
+	if err := ValidateTargets(CanonicalTargets()); err != nil {
+		t.Fatalf("expected canonical targets to be collision-free: %v", err)
+	}
+
+	colliding := []Target{
+		{Name: "build", Aliases: []string{"Ship"}},
+		{Name: "release", Aliases: []string{"ship "}},
+	}
+	err := ValidateTargets(colliding)
+	if err == nil || !strings.Contains(err.Error(), `"ship" is claimed by both "build" and "release"`) {
+		t.Fatalf("expected post-normalization collision, got %v", err)
+	}
+
+	if err := ValidateTargets([]Target{{Name: "x", Aliases: []string{" "}}}); err == nil {
+		t.Fatalf("expected empty alias to be rejected")
+	}
+}
+
 func TestResolveTargetAlias(t *testing.T) {
 	target, ok := ResolveTarget("pidev")
 	if !ok {
@@ -63,11 +105,41 @@ func TestHasProfileFlag(t *testing.T) {
 	if !HasProfileFlag([]string{"--model", "x", "--profile=ship"}) {
 		t.Fatalf("expected --profile= detection")
 	}
+	if value, ok := ProfileFlagValue([]string{"--profile", "meta", "--profile=ship"}); !ok || value != "ship" {
+		t.Fatalf("expected last --profile value, got %q", value)
+	}
 	if HasProfileFlag([]string{"--model", "x"}) {
 		t.Fatalf("did not expect profile flag")
 	}
 }
 
+func TestHasFlag(t *testing.T) {
+	cases := []struct {
+		args  []string
+		flag  string
+		want  bool
+		value string
+	}{
+		{[]string{"--model", "opus"}, "--model", true, "opus"},
+		{[]string{"--model=opus"}, "--model", true, "opus"},
+		{[]string{"--verbose", "--model"}, "--model", true, ""},
+		{[]string{"--profile", "meta"}, "--model", false, ""},
+		{[]string{"--models", "x", "--model-x=y"}, "--model", false, ""},
+		{[]string{"--thinking", "high"}, "--thinking", true, "high"},
+		{[]string{"--thinking=low", "--thinking", "high"}, "--thinking", true, "high"},
+		{nil, "--thinking", false, ""},
+		{[]string{"--profile=ship"}, "--profile", true, "ship"},
+	}
+	for _, tc := range cases {
+		if got := HasFlag(tc.args, tc.flag); got != tc.want {
+			t.Fatalf("HasFlag(%q, %s): expected %t, got %t", tc.args, tc.flag, tc.want, got)
+		}
+		if value, _ := FlagValue(tc.args, tc.flag); value != tc.value {
+			t.Fatalf("FlagValue(%q, %s): expected %q, got %q", tc.args, tc.flag, tc.value, value)
+		}
+	}
+}
+
 func TestBuildLaunchSpecSetsDefaultProfileEnv(t *testing.T) {
 	root := t.TempDir()
 	extDir := filepath.Join(root, "extensions")
@@ -81,7 +153,7 @@ func TestBuildLaunchSpecSetsDefaultProfileEnv(t *testing.T) {
 
 	manifest := SliceManifest{
 		DefaultProfile: "meta",
-		Extensions:     []string{"extensions/x.ts"},
+		Extensions:     extensionRefs("extensions/x.ts"),
 	}
 
 	prevProfile, hadProfile := os.LookupEnv("PI_DEFAULT_PROFILE")
@@ -141,7 +213,7 @@ func TestBuildLaunchSpecDoesNotSetProfileWhenForwardedHasProfile(t *testing.T) {
 
 	manifest := SliceManifest{
 		DefaultProfile: "meta",
-		Extensions:     []string{"extensions/x.ts"},
+		Extensions:     extensionRefs("extensions/x.ts"),
 	}
 
 	prevProfile, hadProfile := os.LookupEnv("PI_DEFAULT_PROFILE")
@@ -167,3 +239,1429 @@ func TestBuildLaunchSpecDoesNotSetProfileWhenForwardedHasProfile(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildLaunchSpecAppendProfile(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "extensions"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "extensions", "x.ts"), []byte("export default function () {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+	os.Unsetenv("PI_DEFAULT_PROFILE")
+	manifest := SliceManifest{DefaultProfile: "meta", Extensions: extensionRefs("extensions/x.ts")}
+
+	spec, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{AppendProfile: "quick"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, _ := lookupEnv(spec.Env, "PI_DEFAULT_PROFILE"); value != "ultrathink,fast" {
+		t.Fatalf("expected the alias-resolved default plus the appended profile, got %q", value)
+	}
+
+	spec, err = BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{AppendProfile: "deep"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, _ := lookupEnv(spec.Env, "PI_DEFAULT_PROFILE"); value != "ultrathink" {
+		t.Fatalf("expected appending an alias of the default to be a no-op, got %q", value)
+	}
+
+	manifest.AllowedProfiles = []string{"meta"}
+	if _, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{AppendProfile: "fast"}); !errors.Is(err, ErrProfileNotAllowed) {
+		t.Fatalf("expected the appended profile to be checked against allowedProfiles, got %v", err)
+	}
+}
+
+func TestBuildLaunchSpecEnvOverrides(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	unsetProfileEnv(t)
+	t.Setenv("PICTL_TEST_VAR", "shell")
+	t.Setenv("PICTL_BLOCK_ENV", "PICTL_BLOCKED")
+	manifest := SliceManifest{DefaultProfile: "meta", Extensions: extensionRefs("extensions/x.ts")}
+
+	spec, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{Env: []string{"PICTL_TEST_VAR=flag", "PICTL_BLOCKED=a=b", "PICTL_EMPTY="}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"PICTL_TEST_VAR=flag", "PICTL_BLOCKED=a=b", "PICTL_EMPTY=", "PI_DEFAULT_PROFILE=meta"} {
+		if !hasEnv(spec.Env, want) {
+			t.Fatalf("expected %s in launch env", want)
+		}
+	}
+	if countEnv(spec.Env, "PICTL_TEST_VAR") != 1 {
+		t.Fatalf("expected --env to replace the shell value, got %v", spec.Env)
+	}
+
+	spec, err = BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{Profile: "ship", Env: []string{"PI_DEFAULT_PROFILE=fast"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, _ := lookupEnv(spec.Env, "PI_DEFAULT_PROFILE"); value != "fast" || countEnv(spec.Env, "PI_DEFAULT_PROFILE") != 1 {
+		t.Fatalf("expected an explicit PI_DEFAULT_PROFILE to win, got %v", spec.Env)
+	}
+
+	for _, entry := range []string{"NOEQUALS", "=value", "1BAD=x", "BAD-KEY=x"} {
+		if _, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{Env: []string{entry}}); err == nil || !strings.Contains(err.Error(), "want KEY=VALUE") {
+			t.Fatalf("expected %q to be rejected, got %v", entry, err)
+		}
+	}
+}
+
+func TestResolveProfileAlias(t *testing.T) {
+	profile, ok := ResolveProfile("meta")
+	if !ok {
+		t.Fatalf("expected meta profile alias to resolve")
+	}
+	if profile.Name != "ultrathink" {
+		t.Fatalf("expected ultrathink profile, got %q", profile.Name)
+	}
+
+	profile, ok = ResolveProfile(" Execute ")
+	if !ok || profile.Name != "execute" {
+		t.Fatalf("expected canonical execute profile to resolve, got %q", profile.Name)
+	}
+
+	if _, ok := ResolveProfile("nope"); ok {
+		t.Fatalf("did not expect unknown profile to resolve")
+	}
+}
+
+func writeRootMarkers(t *testing.T, dir string) {
+	t.Helper()
+
+	for _, sub := range []string{"slices", "extensions"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "settings.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetermineRootHomeCandidates(t *testing.T) {
+	cases := []struct {
+		name     string
+		xdg      string
+		location func(home string, xdg string) string
+	}{
+		{"development", "", func(home, _ string) string { return filepath.Join(home, "Development", "pi-agent-config") }},
+		{"xdg config home", "xdg", func(_, xdg string) string { return filepath.Join(xdg, "pi-agent-config") }},
+		{"dot config fallback", "", func(home, _ string) string { return filepath.Join(home, ".config", "pi-agent-config") }},
+		{"dot dir", "", func(home, _ string) string { return filepath.Join(home, ".pi-agent-config") }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			home := t.TempDir()
+			xdg := ""
+			if tc.xdg != "" {
+				xdg = filepath.Join(home, tc.xdg)
+			}
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", xdg)
+			t.Setenv("PI_AGENT_CONFIG_ROOT", "")
+			t.Chdir(t.TempDir())
+
+			want := tc.location(home, xdg)
+			writeRootMarkers(t, want)
+
+			var traced []string
+			root, err := DetermineRootTrace("", func(message string) { traced = append(traced, message) })
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if root != want {
+				t.Fatalf("expected %s, got %s", want, root)
+			}
+			if len(traced) == 0 || !strings.Contains(traced[len(traced)-1], want) {
+				t.Fatalf("expected trace to mention chosen root, got %v", traced)
+			}
+		})
+	}
+}
+
+func TestDetermineRootTraceWalksUpFromWorkingDirectory(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRootMarkers(t, root)
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PI_AGENT_CONFIG_ROOT", "")
+	t.Chdir(nested)
+
+	var traced []string
+	got, err := DetermineRootTrace("", func(message string) { traced = append(traced, message) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != root {
+		t.Fatalf("expected %s, got %s", root, got)
+	}
+
+	want := []string{
+		"--root not given",
+		"PI_AGENT_CONFIG_ROOT not set",
+		"no root markers in " + nested,
+		"no root markers in " + filepath.Join(root, "a"),
+		"root found above working directory: " + root,
+	}
+	if strings.Join(traced, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected trace:\n got %q\nwant %q", traced, want)
+	}
+}
+
+func TestDetermineRootFindsNestedConfigDir(t *testing.T) {
+	mono, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(mono, "tools", "pi-agent-config")
+	writeRootMarkers(t, config)
+	cwd := filepath.Join(mono, "apps", "web")
+	if err := os.MkdirAll(cwd, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PI_AGENT_CONFIG_ROOT", "")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Chdir(cwd)
+
+	t.Setenv("PICTL_ROOT_SUBDIRS", "")
+	if _, err := DetermineRoot(""); !errors.Is(err, ErrRootNotFound) {
+		t.Fatalf("expected the default ancestor walk to miss a nested config dir, got %v", err)
+	}
+
+	t.Setenv("PICTL_ROOT_SUBDIRS", "missing, tools/pi-agent-config")
+	if got, err := DetermineRoot(""); err != nil || got != config {
+		t.Fatalf("expected nested config dir %s, got %q (%v)", config, got, err)
+	}
+
+	t.Setenv("PICTL_ROOT_MARKERS", "any: settings.json")
+	if err := os.WriteFile(filepath.Join(mono, "settings.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := DetermineRoot(""); err != nil || got != config {
+		t.Fatalf("expected nested config dir to beat a marked monorepo root, got %q (%v)", got, err)
+	}
+	t.Setenv("PICTL_ROOT_SUBDIRS", "")
+	if got, err := DetermineRoot(""); err != nil || got != mono {
+		t.Fatalf("expected the ancestor walk to stop at the monorepo root by default, got %q (%v)", got, err)
+	}
+}
+
+func TestRootSubdirs(t *testing.T) {
+	t.Setenv("PICTL_ROOT_SUBDIRS", "tools/pi, /abs, .., ../up, ., config/")
+	want := []string{filepath.Join("tools", "pi"), "config"}
+	if got := RootSubdirs(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDetermineRootIgnoresCandidatesWithoutMarkers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("PI_AGENT_CONFIG_ROOT", "")
+	t.Chdir(t.TempDir())
+
+	if err := os.MkdirAll(filepath.Join(home, ".config", "pi-agent-config", "slices"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DetermineRoot(""); !errors.Is(err, ErrRootNotFound) {
+		t.Fatalf("expected ErrRootNotFound, got %v", err)
+	}
+}
+
+func TestHasRootMarkersModes(t *testing.T) {
+	full := t.TempDir()
+	writeRootMarkers(t, full)
+	minimal := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(minimal, "slices"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if !HasRootMarkers(full, DefaultRootMarkers, MarkersAll) {
+		t.Fatalf("expected full root to satisfy all-of markers")
+	}
+	if HasRootMarkers(minimal, DefaultRootMarkers, MarkersAll) {
+		t.Fatalf("expected slices-only dir to fail all-of markers")
+	}
+	if !HasRootMarkers(minimal, DefaultRootMarkers, MarkersAny) {
+		t.Fatalf("expected slices-only dir to satisfy any-of markers")
+	}
+	if HasRootMarkers(t.TempDir(), DefaultRootMarkers, MarkersAny) {
+		t.Fatalf("expected empty dir to fail any-of markers")
+	}
+}
+
+func TestRootMarkersFromEnv(t *testing.T) {
+	t.Setenv("PICTL_ROOT_MARKERS", "")
+	if markers, mode := RootMarkers(); mode != MarkersAll || strings.Join(markers, ",") != "settings.json,slices,extensions" {
+		t.Fatalf("expected all-of default markers, got %v %v", markers, mode)
+	}
+
+	t.Setenv("PICTL_ROOT_MARKERS", "any: slices, extensions")
+	if markers, mode := RootMarkers(); mode != MarkersAny || strings.Join(markers, ",") != "slices,extensions" {
+		t.Fatalf("expected any-of slices/extensions, got %v %v", markers, mode)
+	}
+
+	minimal := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(minimal, "slices"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if root, err := DetermineRoot(minimal); err != nil || root != minimal {
+		t.Fatalf("expected slices-only root to be accepted with any-of markers, got %q %v", root, err)
+	}
+}
+
+func TestLoadSlicesMissingSlicesDir(t *testing.T) {
+	root := t.TempDir()
+	writeRootMarkers(t, root)
+	if _, err := DetermineRoot(root); err != nil {
+		t.Fatalf("expected root markers to pass: %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(root, "slices")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadSlices(root)
+	if !errors.Is(err, ErrNoSlicesDir) {
+		t.Fatalf("expected ErrNoSlicesDir, got %v", err)
+	}
+	if !strings.Contains(err.Error(), root) || !strings.Contains(err.Error(), "--root") {
+		t.Fatalf("expected guidance naming the root, got %v", err)
+	}
+}
+
+func TestBuildLaunchSpecExtensionFilters(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/web/index.ts", "extensions/guard/index.ts", "extensions/notes.ts")
+	unsetProfileEnv(t)
+	manifest := SliceManifest{Extensions: extensionRefs("extensions/web/index.ts", "extensions/guard/index.ts", "extensions/notes.ts")}
+	extArgs := func(spec LaunchSpec) []string {
+		var out []string
+		for i, arg := range spec.Args {
+			if arg == "-e" {
+				rel, _ := filepath.Rel(root, spec.Args[i+1])
+				out = append(out, filepath.ToSlash(rel))
+			}
+		}
+		return out
+	}
+
+	spec, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{SkipExtensions: []string{"guard/", "notes"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := extArgs(spec); !reflect.DeepEqual(got, []string{"extensions/web/index.ts"}) {
+		t.Fatalf("expected skip to leave web only, got %v", got)
+	}
+	if len(spec.Notes) != 2 || !strings.Contains(spec.Notes[0], "--skip-ext guard/") {
+		t.Fatalf("expected a note per skipped extension, got %v", spec.Notes)
+	}
+
+	spec, err = BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{OnlyExtensions: []string{"web", "notes"}, SkipExtensions: []string{"notes"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := extArgs(spec); !reflect.DeepEqual(got, []string{"extensions/web/index.ts"}) {
+		t.Fatalf("expected only web (notes skipped), got %v", got)
+	}
+
+	_, err = BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{OnlyExtensions: []string{"nope"}})
+	if err == nil || !strings.Contains(err.Error(), "no extensions left") {
+		t.Fatalf("expected an empty filter result to fail, got %v", err)
+	}
+}
+
+func TestBuildLaunchSpecSliceModel(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	unsetProfileEnv(t)
+	extPath := filepath.Join(root, "extensions", "x.ts")
+
+	cases := []struct {
+		name      string
+		model     string
+		forwarded []string
+		want      []string
+	}{
+		{"injected", "sonnet", []string{"--verbose"}, []string{"--no-extensions", "-e", extPath, "--model", "sonnet", "--verbose"}},
+		{"forwarded flag wins", "sonnet", []string{"--model", "opus"}, []string{"--no-extensions", "-e", extPath, "--model", "opus"}},
+		{"forwarded equals form wins", "sonnet", []string{"--model=opus"}, []string{"--no-extensions", "-e", extPath, "--model=opus"}},
+		{"no model", "", nil, []string{"--no-extensions", "-e", extPath}},
+	}
+	for _, tc := range cases {
+		manifest := SliceManifest{Model: tc.model, Extensions: extensionRefs("extensions/x.ts")}
+		spec, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{ForwardedArgs: tc.forwarded})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(spec.Args, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, spec.Args)
+		}
+	}
+
+	manifest, err := parseSliceManifest([]byte(`{"model": "sonnet", "extensions": ["extensions/x.ts"]}`))
+	if err != nil || manifest.Model != "sonnet" {
+		t.Fatalf("expected model to parse, got %+v (%v)", manifest, err)
+	}
+}
+
+func writeExtensionFiles(t *testing.T, root string, rels ...string) {
+	t.Helper()
+
+	for _, rel := range rels {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("export default function () {}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func countArg(args []string, want string) int {
+	count := 0
+	for _, arg := range args {
+		if arg == want {
+			count++
+		}
+	}
+	return count
+}
+
+func TestBuildLaunchSpecDeduplicatesExtensions(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts", "extensions/y.ts")
+
+	manifest := SliceManifest{
+		Extensions: extensionRefs("extensions/x.ts", "extensions/./x.ts", "extensions/*.ts"),
+	}
+
+	spec, err := BuildLaunchSpec(root, manifest, false, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	xPath := filepath.Join(root, "extensions", "x.ts")
+	yPath := filepath.Join(root, "extensions", "y.ts")
+	if countArg(spec.Args, xPath) != 1 {
+		t.Fatalf("expected a single -e entry for x.ts, got %v", spec.Args)
+	}
+	if countArg(spec.Args, "-e") != 2 {
+		t.Fatalf("expected two -e entries, got %v", spec.Args)
+	}
+	if spec.Args[2] != xPath || spec.Args[4] != yPath {
+		t.Fatalf("expected first-occurrence order x.ts, y.ts; got %v", spec.Args)
+	}
+	if len(spec.Notes) != 2 {
+		t.Fatalf("expected two duplicate notes, got %v", spec.Notes)
+	}
+}
+
+func TestBuildLaunchSpecGlobWithoutMatchesFails(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+
+	manifest := SliceManifest{Extensions: extensionRefs("extensions/*.js")}
+	_, err := BuildLaunchSpec(root, manifest, false, "", nil)
+	if !errors.Is(err, ErrExtensionMissing) {
+		t.Fatalf("expected ErrExtensionMissing, got %v", err)
+	}
+}
+
+func TestBuildLaunchSpecResolvesFromSecondaryExtensionRoot(t *testing.T) {
+	root := t.TempDir()
+	shared := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/local.ts", "extensions/both.ts")
+	writeExtensionFiles(t, shared, "extensions/shared.ts", "extensions/both.ts")
+	unsetProfileEnv(t)
+	t.Setenv("PICTL_EXTENSION_PATH", shared)
+
+	manifest := SliceManifest{Extensions: extensionRefs("extensions/local.ts", "extensions/shared.ts", "extensions/both.ts")}
+	spec, err := BuildLaunchSpec(root, manifest, false, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"--no-extensions",
+		"-e", filepath.Join(root, "extensions", "local.ts"),
+		"-e", filepath.Join(shared, "extensions", "shared.ts"),
+		"-e", filepath.Join(root, "extensions", "both.ts"),
+	}
+	if strings.Join(spec.Args, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected repo root to win, then shared root; got %v", spec.Args)
+	}
+}
+
+func TestBuildLaunchSpecMissingExtensionListsSearchedRoots(t *testing.T) {
+	root := t.TempDir()
+	shared := t.TempDir()
+	t.Setenv("PICTL_EXTENSION_PATH", shared)
+
+	manifest := SliceManifest{Extensions: extensionRefs("extensions/nowhere.ts")}
+	_, err := BuildLaunchSpec(root, manifest, false, "", nil)
+	if !errors.Is(err, ErrExtensionMissing) {
+		t.Fatalf("expected ErrExtensionMissing, got %v", err)
+	}
+	if !strings.Contains(err.Error(), root) || !strings.Contains(err.Error(), shared) {
+		t.Fatalf("expected error to list both roots, got %v", err)
+	}
+}
+
+func TestBuildLaunchSpecExtensionDirs(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/core.ts", "plugins/b.ts", "plugins/a.js", "plugins/types.d.ts", "plugins/notes.md", "plugins/nested/c.ts")
+	manifest := SliceManifest{Extensions: extensionRefs("extensions/core.ts", "plugins/b.ts"), ExtensionDirs: []string{"plugins"}}
+
+	spec, err := BuildLaunchSpec(root, manifest, false, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"--no-extensions",
+		"-e", filepath.Join(root, "extensions", "core.ts"),
+		"-e", filepath.Join(root, "plugins", "b.ts"),
+		"-e", filepath.Join(root, "plugins", "a.js"),
+	}
+	if !reflect.DeepEqual(spec.Args, want) {
+		t.Fatalf("expected explicit extensions, then the directory in name order without duplicates\n got %v\nwant %v", spec.Args, want)
+	}
+
+	if err := os.Mkdir(filepath.Join(root, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	manifest.ExtensionDirs = []string{"empty"}
+	spec, err = BuildLaunchSpec(root, manifest, false, "", nil)
+	if err != nil || len(spec.Warnings) != 1 || !strings.Contains(spec.Warnings[0], "extension dir empty has no .ts or .js files") {
+		t.Fatalf("expected an empty dir to warn, got %v (%v)", spec.Warnings, err)
+	}
+	if _, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{StrictExtensions: true}); !errors.Is(err, ErrInvalidExtension) {
+		t.Fatalf("expected an empty dir to fail under --strict-extensions, got %v", err)
+	}
+
+	manifest.ExtensionDirs = []string{"missing"}
+	if _, err := BuildLaunchSpec(root, manifest, false, "", nil); !errors.Is(err, ErrExtensionMissing) {
+		t.Fatalf("expected a missing dir to be reported as missing, got %v", err)
+	}
+}
+
+func TestLoadSlicesExtensionDirsOnly(t *testing.T) {
+	root := t.TempDir()
+	writeRootMarkers(t, root)
+	writeSliceFiles(t, root, map[string]string{
+		"plugins.json": `{"extensionDirs": ["plugins"]}`,
+		"child.json":   `{"include": ["plugins"], "extensionDirs": ["local"]}`,
+	})
+	slices, err := LoadSlices(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := slices["child"].ExtensionDirs; !reflect.DeepEqual(got, []string{"plugins", "local"}) {
+		t.Fatalf("expected included dirs first, got %v", got)
+	}
+}
+
+func TestBuildLaunchSpecExtensionSymlinks(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "vendor/tool/index.ts")
+	unsetProfileEnv(t)
+	links := map[string]string{
+		"extensions/file.ts":   filepath.Join(root, "vendor", "tool", "index.ts"),
+		"extensions/dir.ts":    filepath.Join(root, "vendor", "tool"),
+		"extensions/broken.ts": filepath.Join(root, "vendor", "gone.ts"),
+	}
+	if err := os.MkdirAll(filepath.Join(root, "extensions", "plain"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(link))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spec, err := BuildLaunchSpec(root, SliceManifest{Extensions: extensionRefs("extensions/file.ts")}, false, "", nil)
+	if err != nil {
+		t.Fatalf("expected a symlink to a file to load, got %v", err)
+	}
+	if !reflect.DeepEqual(spec.Args, []string{"--no-extensions", "-e", filepath.Join(root, "extensions", "file.ts")}) {
+		t.Fatalf("unexpected args %v", spec.Args)
+	}
+
+	cases := []struct {
+		path    string
+		want    string
+		missing bool
+	}{
+		{"extensions/dir.ts", "symlink to a directory (-> " + links["extensions/dir.ts"] + ")", false},
+		{"extensions/broken.ts", "broken symlink (-> " + links["extensions/broken.ts"] + ")", true},
+		{"extensions/plain", "extension path is directory, expected file", false},
+		{"extensions/absent.ts", "(searched " + root + ")", true},
+	}
+	for _, tc := range cases {
+		_, err := BuildLaunchSpec(root, SliceManifest{Extensions: extensionRefs(tc.path)}, false, "", nil)
+		if err == nil || !strings.Contains(err.Error(), tc.want) || errors.Is(err, ErrExtensionMissing) != tc.missing {
+			t.Fatalf("%s: expected an error containing %q (missing=%t), got %v", tc.path, tc.want, tc.missing, err)
+		}
+	}
+}
+
+func TestBuildLaunchSpecAbsoluteExtensionBypassesRoots(t *testing.T) {
+	root := t.TempDir()
+	elsewhere := t.TempDir()
+	writeExtensionFiles(t, elsewhere, "abs.ts")
+	unsetProfileEnv(t)
+
+	absPath := filepath.Join(elsewhere, "abs.ts")
+	spec, err := BuildLaunchSpec(root, SliceManifest{Extensions: extensionRefs(absPath)}, false, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spec.Args[2] != absPath {
+		t.Fatalf("expected absolute path to be used as-is, got %v", spec.Args)
+	}
+}
+
+func TestBuildLaunchSpecChecksExtensionFiles(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/valid.ts", "extensions/README.md")
+	if err := os.WriteFile(filepath.Join(root, "extensions", "empty.ts"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		rel     string
+		problem bool
+	}{
+		{name: "valid ts", rel: "extensions/valid.ts", problem: false},
+		{name: "empty file", rel: "extensions/empty.ts", problem: true},
+		{name: "markdown", rel: "extensions/README.md", problem: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			manifest := SliceManifest{Extensions: extensionRefs(tc.rel)}
+
+			spec, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error in warn mode: %v", err)
+			}
+			if got := len(spec.Warnings) > 0; got != tc.problem {
+				t.Fatalf("expected warning=%v, got warnings %v", tc.problem, spec.Warnings)
+			}
+
+			_, err = BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{StrictExtensions: true})
+			if got := errors.Is(err, ErrInvalidExtension); got != tc.problem {
+				t.Fatalf("expected strict error=%v, got %v", tc.problem, err)
+			}
+		})
+	}
+}
+
+func hasEnv(env []string, entry string) bool {
+	for _, candidate := range env {
+		if candidate == entry {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBuildLaunchSpecForwardEnvFiltering(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	manifest := SliceManifest{DefaultProfile: "meta", Extensions: extensionRefs("extensions/x.ts")}
+
+	t.Setenv("PICTL_TEST_KEEP", "1")
+	t.Setenv("PICTL_TEST_DROP", "1")
+	t.Setenv("PI_DEFAULT_PROFILE", "ship")
+
+	cases := []struct {
+		name    string
+		allow   string
+		block   string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "allowlist only",
+			allow:   "PICTL_TEST_K*",
+			want:    []string{"PICTL_TEST_KEEP=1", "PI_DEFAULT_PROFILE=meta"},
+			notWant: []string{"PICTL_TEST_DROP=1", "PI_DEFAULT_PROFILE=ship"},
+		},
+		{
+			name:    "denylist only",
+			block:   "PICTL_TEST_DROP,PI_DEFAULT_PROFILE",
+			want:    []string{"PICTL_TEST_KEEP=1", "PI_DEFAULT_PROFILE=meta"},
+			notWant: []string{"PICTL_TEST_DROP=1", "PI_DEFAULT_PROFILE=ship"},
+		},
+		{
+			name:    "allowlist keeps inherited profile",
+			allow:   "PICTL_TEST_KEEP,PI_DEFAULT_PROFILE",
+			want:    []string{"PICTL_TEST_KEEP=1", "PI_DEFAULT_PROFILE=ship"},
+			notWant: []string{"PICTL_TEST_DROP=1", "PI_DEFAULT_PROFILE=meta"},
+		},
+		{
+			name:    "no filters",
+			want:    []string{"PICTL_TEST_KEEP=1", "PICTL_TEST_DROP=1", "PI_DEFAULT_PROFILE=ship"},
+			notWant: []string{"PI_DEFAULT_PROFILE=meta"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("PICTL_FORWARD_ENV", tc.allow)
+			t.Setenv("PICTL_BLOCK_ENV", tc.block)
+
+			spec, err := BuildLaunchSpec(root, manifest, false, "", nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, entry := range tc.want {
+				if !hasEnv(spec.Env, entry) {
+					t.Fatalf("expected %s in env", entry)
+				}
+			}
+			for _, entry := range tc.notWant {
+				if hasEnv(spec.Env, entry) {
+					t.Fatalf("did not expect %s in env", entry)
+				}
+			}
+		})
+	}
+}
+
+func writeSliceFiles(t *testing.T, root string, slices map[string]string) {
+	t.Helper()
+
+	for rel, body := range slices {
+		path := filepath.Join(root, "slices", filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadSlicesNestedDirectories(t *testing.T) {
+	root := t.TempDir()
+	writeSliceFiles(t, root, map[string]string{
+		"top.json":          `{"extensions": ["extensions/x.ts"]}`,
+		"team-a/foo.json":   `{"extensions": ["extensions/x.ts"]}`,
+		"team-b/x/bar.json": `{"extensions": ["extensions/x.ts"]}`,
+		"team-b/notes.md":   "ignored",
+	})
+
+	slices, err := LoadSlices(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"top", "team-a/foo", "team-b/x/bar"} {
+		if _, ok := slices[name]; !ok {
+			t.Fatalf("expected slice %q, got %v", name, SortedSliceInfos(slices))
+		}
+	}
+	if len(slices) != 3 {
+		t.Fatalf("expected 3 slices, got %d", len(slices))
+	}
+}
+
+func TestLoadSlicesValidatesWithSliceName(t *testing.T) {
+	root := t.TempDir()
+	writeSliceFiles(t, root, map[string]string{
+		"team-a/foo.json": `{"defaultProfile": "turbo", "extensions": ["extensions/x.ts"]}`,
+	})
+
+	_, err := LoadSlices(root)
+	if err == nil || !strings.Contains(err.Error(), "team-a/foo") || !strings.Contains(err.Error(), `unknown defaultProfile "turbo"`) {
+		t.Fatalf("expected validation error naming the slice, got %v", err)
+	}
+}
+
+func TestLoadSlicesExpandsIncludes(t *testing.T) {
+	root := t.TempDir()
+	writeSliceFiles(t, root, map[string]string{
+		"base.json":  `{"description": "base", "defaultProfile": "fast", "extensions": ["extensions/a.ts", "extensions/b.ts"]}`,
+		"lint.json":  `{"include": ["base"], "extensions": ["extensions/lint.ts"]}`,
+		"combo.json": `{"description": "combo", "include": ["base", "lint"], "extensions": ["extensions/b.ts", "extensions/own.ts"]}`,
+	})
+
+	slices, err := LoadSlices(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	combo := slices["combo"]
+	want := extensionRefs("extensions/a.ts", "extensions/b.ts", "extensions/lint.ts", "extensions/own.ts")
+	if !reflect.DeepEqual(combo.Extensions, want) {
+		t.Fatalf("expected %v, got %v", want, combo.Extensions)
+	}
+	if combo.DefaultProfile != "" || combo.Description != "combo" {
+		t.Fatalf("expected include not to inherit profile or description, got %+v", combo)
+	}
+}
+
+func TestLoadSlicesRejectsIncludeCycles(t *testing.T) {
+	root := t.TempDir()
+	writeSliceFiles(t, root, map[string]string{
+		"a.json": `{"include": ["b"], "extensions": ["extensions/a.ts"]}`,
+		"b.json": `{"include": ["c"]}`,
+		"c.json": `{"include": ["a"]}`,
+	})
+
+	_, err := LoadSlices(root)
+	if err == nil || !strings.Contains(err.Error(), "include cycle: a -> b -> c -> a") {
+		t.Fatalf("expected include cycle error, got %v", err)
+	}
+
+	writeSliceFiles(t, root, map[string]string{"c.json": `{"include": ["nope"]}`})
+	if _, err := LoadSlices(root); !errors.Is(err, ErrSliceNotFound) {
+		t.Fatalf("expected unknown include to be ErrSliceNotFound, got %v", err)
+	}
+}
+
+func TestLoadSlicesNameCollision(t *testing.T) {
+	root := t.TempDir()
+	writeSliceFiles(t, root, map[string]string{
+		"team/foo.json": `{"extensions": ["extensions/x.ts"]}`,
+		"Team/Foo.json": `{"extensions": ["extensions/x.ts"]}`,
+	})
+
+	entries, err := os.ReadDir(filepath.Join(root, "slices"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 2 {
+		t.Skip("filesystem is case-insensitive")
+	}
+
+	if _, err := LoadSlices(root); err == nil || !strings.Contains(err.Error(), "collision") {
+		t.Fatalf("expected collision error, got %v", err)
+	}
+}
+
+func TestDisabledSlicesAreLoadedButFiltered(t *testing.T) {
+	root := t.TempDir()
+	writeSliceFiles(t, root, map[string]string{
+		"live.json":    `{"extensions": ["extensions/x.ts"]}`,
+		"on.json":      `{"enabled": true, "extensions": ["extensions/x.ts"]}`,
+		"retired.json": `{"enabled": false, "extensions": ["extensions/x.ts"]}`,
+	})
+
+	slices, err := LoadSlices(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(slices) != 3 {
+		t.Fatalf("expected disabled slice to still be parsed, got %d slices", len(slices))
+	}
+
+	enabled := EnabledSliceInfos(SortedSliceInfos(slices))
+	if len(enabled) != 2 || enabled[0].Name != "live" || enabled[1].Name != "on" {
+		t.Fatalf("unexpected enabled slices: %+v", enabled)
+	}
+
+	if _, err := LookupSlice(slices, "retired"); !errors.Is(err, ErrSliceDisabled) {
+		t.Fatalf("expected ErrSliceDisabled, got %v", err)
+	}
+	if _, err := LookupSlice(slices, "on"); err != nil {
+		t.Fatalf("unexpected error for enabled slice: %v", err)
+	}
+}
+
+func TestSliceDirOverride(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	writeSliceFiles(t, root, map[string]string{"local.json": `{"extensions": ["extensions/x.ts"]}`})
+	shared := t.TempDir()
+	writeSliceFiles(t, shared, map[string]string{"team.json": `{"extensions": ["extensions/x.ts"]}`})
+	t.Setenv(SliceDirEnv, "")
+	t.Cleanup(func() { UseSliceDir("") })
+
+	UseSliceDir(filepath.Join(shared, "slices"))
+	slices, err := LoadSlices(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := slices["team"]; !ok || len(slices) != 1 {
+		t.Fatalf("expected only the override dir's slices, got %v", slices)
+	}
+	spec, err := BuildLaunchSpec(root, slices["team"], false, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(strings.Join(spec.Args, " "), filepath.Join(root, "extensions", "x.ts")) {
+		t.Fatalf("expected extensions to resolve under root, got %v", spec.Args)
+	}
+
+	UseSliceDir("")
+	t.Setenv(SliceDirEnv, filepath.Join(shared, "slices"))
+	if slices, err := LoadSlices(root); err != nil || len(slices) != 1 || slices["team"].Extensions == nil {
+		t.Fatalf("expected %s to select the shared dir, got %v, %v", SliceDirEnv, slices, err)
+	}
+
+	t.Setenv(SliceDirEnv, filepath.Join(shared, "missing"))
+	if _, err := LoadSlices(root); !errors.Is(err, ErrNoSlicesDir) || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("expected ErrNoSlicesDir naming the override, got %v", err)
+	}
+	empty := t.TempDir()
+	t.Setenv(SliceDirEnv, empty)
+	if _, err := LoadSlices(root); err == nil || !strings.Contains(err.Error(), "no slice manifests found in "+empty) {
+		t.Fatalf("expected an empty override dir to fail, got %v", err)
+	}
+}
+
+func TestLookupTargetSliceFallback(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	t.Setenv(FallbackSliceEnv, "")
+	slices := map[string]SliceManifest{
+		"spare":   {Extensions: extensionRefs("extensions/x.ts")},
+		"retired": {Enabled: new(bool), Extensions: extensionRefs("extensions/x.ts")},
+	}
+
+	target := Target{Name: "build", Slice: "software", FallbackSlice: "spare"}
+	manifest, used, err := LookupTargetSlice(slices, target)
+	if err != nil || used != "spare" {
+		t.Fatalf("expected the fallback slice, got %q, %v", used, err)
+	}
+	if _, err := BuildLaunchSpec(root, manifest, false, "", nil); err != nil {
+		t.Fatalf("expected the fallback to build a launch spec, got %v", err)
+	}
+
+	t.Setenv(FallbackSliceEnv, "spare")
+	if _, used, err := LookupTargetSlice(slices, Target{Name: "build", Slice: "software"}); err != nil || used != "spare" {
+		t.Fatalf("expected %s to supply the fallback, got %q, %v", FallbackSliceEnv, used, err)
+	}
+
+	_, used, err = LookupTargetSlice(slices, Target{Name: "build", Slice: "software", FallbackSlice: "gone"})
+	if !errors.Is(err, ErrSliceNotFound) || used != "software" || !strings.Contains(err.Error(), `"gone"`) {
+		t.Fatalf("expected a missing fallback to report both slices, got %q, %v", used, err)
+	}
+
+	if _, _, err := LookupTargetSlice(slices, Target{Name: "old", Slice: "retired", FallbackSlice: "spare"}); !errors.Is(err, ErrSliceDisabled) {
+		t.Fatalf("expected a disabled slice not to fall back, got %v", err)
+	}
+}
+
+func TestDecideProfile(t *testing.T) {
+	cases := []struct {
+		name      string
+		override  string
+		target    string
+		slice     string
+		forwarded []string
+		env       []string
+		want      ProfileDecision
+	}{
+		{"forwarded wins", "execute", "", "fast", []string{"--profile=ship"}, []string{"PI_DEFAULT_PROFILE=meta"}, ProfileDecision{"ship", ProfileFromForwarded}},
+		{"override beats env", "execute", "", "fast", nil, []string{"PI_DEFAULT_PROFILE=meta"}, ProfileDecision{"execute", ProfileFromRequest}},
+		{"env beats target default", "", "execute", "fast", nil, []string{"PI_DEFAULT_PROFILE=meta"}, ProfileDecision{"meta", ProfileFromEnv}},
+		{"env beats slice default", "", "", "fast", nil, []string{"PI_DEFAULT_PROFILE=meta"}, ProfileDecision{"meta", ProfileFromEnv}},
+		{"blank env ignored", "", "execute", "fast", nil, []string{"PI_DEFAULT_PROFILE= "}, ProfileDecision{"execute", ProfileFromTarget}},
+		{"slice default", "", "", "fast", nil, nil, ProfileDecision{"fast", ProfileFromSlice}},
+		{"none", "", "", "", nil, nil, ProfileDecision{"", ProfileFromNone}},
+		{"pictl env as last resort", "", "", "", nil, []string{"PICTL_PROFILE=ship"}, ProfileDecision{"ship", ProfileFromPictlEnv}},
+		{"slice default beats pictl env", "", "", "fast", nil, []string{"PICTL_PROFILE=ship"}, ProfileDecision{"fast", ProfileFromSlice}},
+		{"target default beats pictl env", "", "execute", "", nil, []string{"PICTL_PROFILE=ship"}, ProfileDecision{"execute", ProfileFromTarget}},
+		{"override beats pictl env", "execute", "", "", nil, []string{"PICTL_PROFILE=ship"}, ProfileDecision{"execute", ProfileFromRequest}},
+		{"forwarded beats pictl env", "", "", "", []string{"--profile", "meta"}, []string{"PICTL_PROFILE=ship"}, ProfileDecision{"meta", ProfileFromForwarded}},
+		{"inherited env beats pictl env", "", "", "", nil, []string{"PICTL_PROFILE=ship", "PI_DEFAULT_PROFILE=meta"}, ProfileDecision{"meta", ProfileFromEnv}},
+		{"blank pictl env ignored", "", "", "", nil, []string{"PICTL_PROFILE= "}, ProfileDecision{"", ProfileFromNone}},
+	}
+	for _, tc := range cases {
+		if got := DecideProfile(tc.override, tc.target, tc.slice, tc.forwarded, tc.env); got != tc.want {
+			t.Fatalf("%s: expected %+v, got %+v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestBuildLaunchSpecPictlProfileFallback(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	t.Setenv("PI_DEFAULT_PROFILE", "")
+	t.Setenv("PICTL_PROFILE", "ship")
+
+	spec, err := BuildLaunchSpec(root, SliceManifest{Extensions: extensionRefs("extensions/x.ts")}, false, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := lookupEnv(spec.Env, "PI_DEFAULT_PROFILE"); got != "ship" {
+		t.Fatalf("expected PICTL_PROFILE to supply the profile, got %q", got)
+	}
+
+	spec, err = BuildLaunchSpec(root, SliceManifest{DefaultProfile: "fast", Extensions: extensionRefs("extensions/x.ts")}, false, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := lookupEnv(spec.Env, "PI_DEFAULT_PROFILE"); got != "fast" {
+		t.Fatalf("expected the manifest default to beat PICTL_PROFILE, got %q", got)
+	}
+}
+
+func TestBuildLaunchSpecExplicitProfileBeatsEnv(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	t.Setenv("PI_DEFAULT_PROFILE", "ship")
+	manifest := SliceManifest{DefaultProfile: "fast", Extensions: extensionRefs("extensions/x.ts")}
+
+	spec, err := BuildLaunchSpec(root, manifest, false, "execute", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := lookupEnv(spec.Env, "PI_DEFAULT_PROFILE"); got != "execute" {
+		t.Fatalf("expected explicit override to win over env, got %q", got)
+	}
+	if countEnv(spec.Env, "PI_DEFAULT_PROFILE") != 1 {
+		t.Fatalf("expected a single PI_DEFAULT_PROFILE entry, got %v", spec.Env)
+	}
+
+	spec, err = BuildLaunchSpec(root, manifest, false, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := lookupEnv(spec.Env, "PI_DEFAULT_PROFILE"); got != "ship" {
+		t.Fatalf("expected env to win over the manifest default, got %q", got)
+	}
+}
+
+func countEnv(env []string, key string) int {
+	count := 0
+	for _, entry := range env {
+		if name, _, _ := strings.Cut(entry, "="); name == key {
+			count++
+		}
+	}
+	return count
+}
+
+func TestBuildLaunchSpecAllowedProfiles(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	unsetProfileEnv(t)
+
+	constrained := SliceManifest{
+		DefaultProfile:  "fast",
+		AllowedProfiles: []string{"fast", "meta"},
+		Extensions:      extensionRefs("extensions/x.ts"),
+	}
+
+	if _, err := BuildLaunchSpec(root, constrained, false, "", nil); err != nil {
+		t.Fatalf("expected manifest default to be allowed: %v", err)
+	}
+	if _, err := BuildLaunchSpec(root, constrained, false, "ultrathink", nil); err != nil {
+		t.Fatalf("expected canonical name of allowed alias to be accepted: %v", err)
+	}
+
+	_, err := BuildLaunchSpec(root, constrained, false, "execute", nil)
+	if !errors.Is(err, ErrProfileNotAllowed) {
+		t.Fatalf("expected ErrProfileNotAllowed, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "fast, meta") {
+		t.Fatalf("expected error to name the allowed set, got %v", err)
+	}
+
+	unconstrained := SliceManifest{Extensions: extensionRefs("extensions/x.ts")}
+	if _, err := BuildLaunchSpec(root, unconstrained, false, "execute", nil); err != nil {
+		t.Fatalf("expected unconstrained slice to accept any profile: %v", err)
+	}
+}
+
+func TestBuildLaunchSpecAllowedProfilesChecksEffectiveProfile(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	unsetProfileEnv(t)
+
+	constrained := SliceManifest{
+		DefaultProfile:  "fast",
+		AllowedProfiles: []string{"fast"},
+		Extensions:      extensionRefs("extensions/x.ts"),
+	}
+
+	for _, forwarded := range [][]string{{"--profile", "execute"}, {"--profile=execute"}} {
+		if _, err := BuildLaunchSpec(root, constrained, false, "", forwarded); !errors.Is(err, ErrProfileNotAllowed) {
+			t.Fatalf("expected forwarded %v to be rejected, got %v", forwarded, err)
+		}
+	}
+	if _, err := BuildLaunchSpec(root, constrained, false, "execute", []string{"--profile", "quick"}); err != nil {
+		t.Fatalf("expected forwarded allowed profile to win over override: %v", err)
+	}
+
+	t.Setenv("PI_DEFAULT_PROFILE", "execute")
+	if _, err := BuildLaunchSpec(root, constrained, false, "", nil); !errors.Is(err, ErrProfileNotAllowed) {
+		t.Fatalf("expected inherited PI_DEFAULT_PROFILE to be rejected, got %v", err)
+	}
+	if _, err := BuildLaunchSpec(root, constrained, false, "", []string{"--profile", "fast"}); err != nil {
+		t.Fatalf("expected forwarded profile to take precedence over env: %v", err)
+	}
+}
+
+func unsetProfileEnv(t *testing.T) {
+	t.Helper()
+
+	prevProfile, hadProfile := os.LookupEnv("PI_DEFAULT_PROFILE")
+	if err := os.Unsetenv("PI_DEFAULT_PROFILE"); err != nil {
+		t.Fatalf("unset PI_DEFAULT_PROFILE: %v", err)
+	}
+	t.Cleanup(func() {
+		if hadProfile {
+			_ = os.Setenv("PI_DEFAULT_PROFILE", prevProfile)
+			return
+		}
+		_ = os.Unsetenv("PI_DEFAULT_PROFILE")
+	})
+}
+
+func writeFakePi(t *testing.T, script string) {
+	t.Helper()
+
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "pi"), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestLaunchPiTimeout(t *testing.T) {
+	writeFakePi(t, "exec sleep 5")
+
+	start := time.Now()
+	err := LaunchPi(LaunchSpec{Env: os.Environ(), Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, ErrLaunchTimeout) {
+		t.Fatalf("expected ErrLaunchTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("expected SIGTERM to stop pi promptly, took %s", elapsed)
+	}
+}
+
+func TestLaunchPiStartupTimeout(t *testing.T) {
+	writeFakePi(t, "exec sleep 5")
+
+	start := time.Now()
+	_, _, err := LaunchPiCaptured(LaunchSpec{Env: os.Environ(), StartupTimeout: 100 * time.Millisecond})
+	if !errors.Is(err, ErrLaunchTimeout) || !strings.Contains(err.Error(), "no output within the 100ms startup timeout") {
+		t.Fatalf("expected a startup timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("expected pi to be stopped promptly, took %s", elapsed)
+	}
+}
+
+func TestLaunchPiStartupTimeoutOnlyBoundsFirstOutput(t *testing.T) {
+	writeFakePi(t, "echo starting >&2; sleep 0.3; echo done")
+
+	stdout, stderr, err := LaunchPiCaptured(LaunchSpec{Env: os.Environ(), StartupTimeout: 100 * time.Millisecond})
+	if err != nil || stdout != "done\n" || stderr != "starting\n" {
+		t.Fatalf("expected early output to satisfy the startup timeout, got %q %q (%v)", stdout, stderr, err)
+	}
+}
+
+func TestLaunchPiWithinTimeout(t *testing.T) {
+	writeFakePi(t, "exit 0")
+
+	if err := LaunchPi(LaunchSpec{Env: os.Environ(), Timeout: 5 * time.Second}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLaunchPiCaptured(t *testing.T) {
+	writeFakePi(t, `echo "out $1"; echo "err $PICTL_TEST_CAPTURE" >&2; exit 3`)
+
+	env := append(os.Environ(), "PICTL_TEST_CAPTURE=value")
+	stdout, stderr, err := LaunchPiCaptured(LaunchSpec{Args: []string{"--no-extensions"}, Env: env})
+	if stdout != "out --no-extensions\n" || stderr != "err value\n" {
+		t.Fatalf("unexpected captured output: stdout=%q stderr=%q", stdout, stderr)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+
+	writeFakePi(t, "cat; echo done")
+	stdout, _, err = LaunchPiCaptured(LaunchSpec{Env: os.Environ()})
+	if err != nil || stdout != "done\n" {
+		t.Fatalf("expected captured launch to get no stdin, got %q (%v)", stdout, err)
+	}
+}
+
+func TestBuildLaunchSpecDir(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/a.ts", "work/notes.md")
+	unsetProfileEnv(t)
+	elsewhere := t.TempDir()
+
+	cases := []struct {
+		name     string
+		cwd      string
+		override string
+		want     string
+	}{
+		{"default inherits", "", "", ""},
+		{"slice cwd is root-relative", "work", "", filepath.Join(root, "work")},
+		{"flag overrides slice", "work", elsewhere, elsewhere},
+		{"relative flag is root-relative", "", "./work/", filepath.Join(root, "work")},
+	}
+	for _, tc := range cases {
+		manifest := SliceManifest{Extensions: extensionRefs("extensions/a.ts"), Cwd: tc.cwd}
+		spec, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{Dir: tc.override})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if spec.Dir != tc.want {
+			t.Fatalf("%s: expected Dir %q, got %q", tc.name, tc.want, spec.Dir)
+		}
+	}
+
+	for _, cwd := range []string{"missing", "work/notes.md"} {
+		manifest := SliceManifest{Extensions: extensionRefs("extensions/a.ts"), Cwd: cwd}
+		if _, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{}); err == nil || !strings.Contains(err.Error(), `slice cwd "`+cwd+`"`) {
+			t.Fatalf("expected an error for cwd %q, got %v", cwd, err)
+		}
+	}
+}
+
+func TestLaunchPiRunsInDir(t *testing.T) {
+	writeFakePi(t, "pwd")
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err := LaunchPiCaptured(LaunchSpec{Env: os.Environ(), Dir: dir})
+	if err != nil || stdout != dir+"\n" {
+		t.Fatalf("expected pi to run in %s, got %q (%v)", dir, stdout, err)
+	}
+}
+
+func TestLaunchPiStdinFile(t *testing.T) {
+	writeFakePi(t, `while read -r line; do echo "got $line"; done`)
+	input := filepath.Join(t.TempDir(), "prompt.txt")
+	if err := os.WriteFile(input, []byte("first\nsecond\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, _, err := LaunchPiCaptured(LaunchSpec{Env: os.Environ(), StdinFile: input})
+	if err != nil || stdout != "got first\ngot second\n" {
+		t.Fatalf("expected pi to read the file on stdin, got %q (%v)", stdout, err)
+	}
+
+	if _, err := StdinFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil || !strings.Contains(err.Error(), "--stdin-file") {
+		t.Fatalf("expected a missing file to fail, got %v", err)
+	}
+	if _, err := StdinFile(t.TempDir()); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Fatalf("expected a directory to fail, got %v", err)
+	}
+}
+
+func TestValidateSliceFileIgnoresRestOfCatalog(t *testing.T) {
+	root := t.TempDir()
+	writeRootMarkers(t, root)
+	writeSliceFiles(t, root, map[string]string{
+		"good.json":       `{"extensions": ["extensions/a.ts"], "include": ["team/base"]}`,
+		"team/base.json":  `{"extensions": [`,
+		"broken.json":     `{"extensions": []}`,
+		"dangling.json":   `{"include": ["nowhere"]}`,
+		"badprofile.json": `{"extensions": ["extensions/a.ts"], "defaultProfile": "nope"}`,
+	})
+	if _, err := LoadSlices(root); err == nil {
+		t.Fatal("expected the full catalog to fail to load")
+	}
+
+	file, err := ValidateSliceFile(root, "good")
+	if err != nil || file.Path != filepath.Join(root, "slices", "good.json") {
+		t.Fatalf("expected good to validate on its own, got %+v (%v)", file, err)
+	}
+
+	cases := map[string]string{
+		"team/base":  "slice team/base (",
+		"broken":     "extensions must not be empty",
+		"dangling":   `includes unknown slice "nowhere"`,
+		"badprofile": `unknown defaultProfile "nope"`,
+	}
+	for name, want := range cases {
+		if _, err := ValidateSliceFile(root, name); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: expected an error containing %q, got %v", name, want, err)
+		}
+	}
+	if _, err := ValidateSliceFile(root, "missing"); !errors.Is(err, ErrSliceNotFound) {
+		t.Fatalf("expected a missing slice to be not found, got %v", err)
+	}
+}
+
+func TestLaunchPiWithRetryRecoversFromRetryableExit(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "attempts")
+	writeFakePi(t, `n=$(cat "`+counter+`" 2>/dev/null || echo 0)
+n=$((n+1))
+echo "$n" > "`+counter+`"
+[ "$n" -ge 3 ] || exit 75
+exit 0`)
+
+	var retries []int
+	err := LaunchPiWithRetry(LaunchSpec{Env: os.Environ()}, RetryPolicy{
+		Retries:   3,
+		Codes:     []int{75},
+		BaseDelay: time.Millisecond,
+		OnRetry: func(attempt int, code int, delay time.Duration) {
+			retries = append(retries, attempt)
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if len(retries) != 2 {
+		t.Fatalf("expected 2 retries, got %v", retries)
+	}
+}
+
+func TestLaunchPiWithRetryPassesThroughNonRetryableExit(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "attempts")
+	writeFakePi(t, `echo x >> "`+counter+`"; exit 3`)
+
+	err := LaunchPiWithRetry(LaunchSpec{Env: os.Environ()}, RetryPolicy{Retries: 3, Codes: []int{75}, BaseDelay: time.Millisecond})
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	raw, _ := os.ReadFile(counter)
+	if got := strings.Count(string(raw), "x"); got != 1 {
+		t.Fatalf("expected a single attempt, got %d", got)
+	}
+}
+
+func TestLaunchPiWithRetryNeverRetriesTimeout(t *testing.T) {
+	writeFakePi(t, "exec sleep 5")
+
+	retried := false
+	err := LaunchPiWithRetry(LaunchSpec{Env: os.Environ(), Timeout: 100 * time.Millisecond}, RetryPolicy{
+		Retries:   2,
+		Codes:     []int{-1, 75, 143},
+		BaseDelay: time.Millisecond,
+		OnRetry: func(attempt int, code int, delay time.Duration) {
+			retried = true
+		},
+	})
+	if !errors.Is(err, ErrLaunchTimeout) {
+		t.Fatalf("expected ErrLaunchTimeout, got %v", err)
+	}
+	if retried {
+		t.Fatalf("expected timeouts not to be retried")
+	}
+}
+
+func TestLaunchPiWithRetryReturnsFinalExit(t *testing.T) {
+	writeFakePi(t, "exit 75")
+
+	err := LaunchPiWithRetry(LaunchSpec{Env: os.Environ()}, RetryPolicy{Retries: 2, Codes: []int{75}, BaseDelay: time.Millisecond})
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 75 {
+		t.Fatalf("expected final exit code 75, got %v", err)
+	}
+}
+
+func TestGroupTargets(t *testing.T) {
+	targets := []Target{
+		{Name: "a", Category: "Engineering"},
+		{Name: "b", Category: "Ops"},
+		{Name: "c", Category: "Engineering"},
+		{Name: "d"},
+	}
+
+	groups := GroupTargets(targets, "")
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %+v", groups)
+	}
+	if groups[0].Category != "Engineering" || len(groups[0].Targets) != 2 || groups[0].Targets[1].Name != "c" {
+		t.Fatalf("unexpected first group: %+v", groups[0])
+	}
+	if groups[1].Category != "Ops" || groups[2].Category != "Other" {
+		t.Fatalf("expected first-appearance category order with Other fallback, got %+v", groups)
+	}
+
+	filtered := GroupTargets(targets, " ops ")
+	if len(filtered) != 1 || filtered[0].Targets[0].Name != "b" {
+		t.Fatalf("unexpected filtered groups: %+v", filtered)
+	}
+
+	if groups := GroupTargets(targets, "writing"); len(groups) != 0 {
+		t.Fatalf("expected no groups for unknown category, got %+v", groups)
+	}
+}
+
+func TestCategoriesDoNotAffectResolution(t *testing.T) {
+	for _, target := range CanonicalTargets() {
+		if target.Category == "" {
+			t.Fatalf("expected canonical target %q to have a category", target.Name)
+		}
+		resolved, ok := ResolveTarget(target.Name)
+		if !ok || resolved.Name != target.Name {
+			t.Fatalf("expected %q to resolve to itself", target.Name)
+		}
+	}
+}
+
+func extensionRefs(paths ...string) []ExtensionRef {
+	refs := make([]ExtensionRef, 0, len(paths))
+	for _, path := range paths {
+		refs = append(refs, ExtensionRef{Path: path})
+	}
+	return refs
+}
+
+func TestMatchSlices(t *testing.T) {
+	disabled := false
+	slices := map[string]SliceManifest{
+		"team/a":   {},
+		"team/b":   {},
+		"team/off": {Enabled: &disabled},
+		"meta":     {},
+	}
+
+	got, err := MatchSlices(slices, "team/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(got, ",") != "team/a,team/b" {
+		t.Fatalf("expected sorted enabled matches, got %v", got)
+	}
+
+	if _, err := MatchSlices(slices, "nope*"); !errors.Is(err, ErrSliceNotFound) {
+		t.Fatalf("expected ErrSliceNotFound, got %v", err)
+	}
+	if _, err := MatchSlices(slices, "[bad"); err == nil {
+		t.Fatalf("expected invalid pattern error")
+	}
+}
+
+func TestTargetByIndexMatchesPickerOrder(t *testing.T) {
+	ordered := PickerOrder(CanonicalTargets())
+	for i, want := range ordered {
+		got, err := TargetByIndex(i+1, false)
+		if err != nil {
+			t.Fatalf("unexpected error for index %d: %v", i+1, err)
+		}
+		if got.Name != want.Name {
+			t.Fatalf("index %d: expected %s, got %s", i+1, want.Name, got.Name)
+		}
+	}
+
+	for _, index := range []int{0, -1, len(ordered) + 1} {
+		if _, err := TargetByIndex(index, false); !errors.Is(err, ErrUnknownTarget) || !strings.Contains(err.Error(), "out of range") {
+			t.Fatalf("expected out-of-range error for %d, got %v", index, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package controlplane
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func UnusedSlices(slices map[string]SliceManifest, targets []Target) []string {
+	used := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		used[target.Slice] = true
+	}
+
+	var unused []string
+	for name := range slices {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+func SliceReferences(name string, slices map[string]SliceManifest, targets []Target) []string {
+	var refs []string
+	for _, target := range targets {
+		if target.Slice == name {
+			refs = append(refs, "target "+target.Name)
+		} else if strings.TrimSpace(target.FallbackSlice) == name {
+			refs = append(refs, "target "+target.Name+" (fallback)")
+		}
+	}
+	for _, other := range sortedSliceNames(slices) {
+		for _, include := range slices[other].Include {
+			if strings.TrimSpace(include) == name && other != name {
+				refs = append(refs, "slice "+other)
+			}
+		}
+	}
+	return refs
+}
+
+func ExtensionEntryPoints(root string) ([]string, error) {
+	dir := filepath.Join(root, "extensions")
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			if hasExtensionSuffix(entry.Name()) {
+				out = append(out, "extensions/"+entry.Name())
+			}
+			continue
+		}
+		for _, suffix := range extensionFileSuffixes {
+			rel := "extensions/" + entry.Name() + "/index" + suffix
+			if stat, err := os.Stat(filepath.Join(root, filepath.FromSlash(rel))); err == nil && !stat.IsDir() {
+				out = append(out, rel)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func OrphanExtensions(root string, slices map[string]SliceManifest) ([]string, error) {
+	candidates, err := ExtensionEntryPoints(root)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := ExtensionRoots(root)
+	referenced := make(map[string]bool)
+	for _, manifest := range slices {
+		for _, ref := range manifest.Extensions {
+			paths, err := resolveExtension(roots, strings.TrimSpace(ref.Path))
+			if err != nil {
+				continue
+			}
+			for _, path := range paths {
+				referenced[absPath(path)] = true
+			}
+		}
+	}
+
+	var orphans []string
+	for _, rel := range candidates {
+		if !referenced[absPath(filepath.Join(root, filepath.FromSlash(rel)))] {
+			orphans = append(orphans, rel)
+		}
+	}
+	return orphans, nil
+}
+
+type ExtensionUsage struct {
+	Path   string   `json:"path"`
+	Slices []string `json:"slices"`
+}
+
+func ExtensionInventory(root string, slices map[string]SliceManifest) ([]ExtensionUsage, error) {
+	users := make(map[string][]string)
+	for _, name := range sortedSliceNames(slices) {
+		manifest := slices[name]
+		if !manifest.IsEnabled() {
+			continue
+		}
+		resolved, err := ResolveExtensions(root, manifest, LaunchOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("slice %s: %w", name, err)
+		}
+		for _, path := range resolved.Paths {
+			display := displayPath(root, path)
+			users[display] = append(users[display], name)
+		}
+	}
+
+	out := make([]ExtensionUsage, 0, len(users))
+	for path, names := range users {
+		out = append(out, ExtensionUsage{Path: path, Slices: names})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, nil
+}
+
+func displayPath(root string, path string) string {
+	rel, err := filepath.Rel(absPath(root), absPath(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return absPath(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func hasExtensionSuffix(name string) bool {
+	for _, suffix := range extensionFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+type AliasRepair struct {
+	Alias       string   `json:"alias"`
+	Winner      string   `json:"winner"`
+	Claimants   []string `json:"claimants"`
+	Suggestions []string `json:"suggestions"`
+}
+
+func AliasRepairs(targets []Target) []AliasRepair {
+	taken := make(map[string]bool)
+	owners := make(map[string][]string)
+	var order []string
+	for _, target := range targets {
+		for _, key := range targetKeys(target) {
+			taken[key] = true
+			claimants := owners[key]
+			if len(claimants) > 0 && claimants[len(claimants)-1] == target.Name {
+				continue
+			}
+			if len(claimants) == 0 {
+				order = append(order, key)
+			}
+			owners[key] = append(claimants, target.Name)
+		}
+	}
+
+	var repairs []AliasRepair
+	for _, key := range order {
+		claimants := owners[key]
+		if key == "" {
+			repairs = append(repairs, AliasRepair{Claimants: claimants, Suggestions: []string{fmt.Sprintf("remove the empty name or alias from %s", strings.Join(claimants, ", "))}})
+			continue
+		}
+		if len(claimants) < 2 {
+			continue
+		}
+
+		repair := AliasRepair{Alias: key, Winner: claimants[0], Claimants: claimants}
+		named := ""
+		for _, target := range targets {
+			if normalizeAlias(target.Name) == key {
+				named = target.Name
+				break
+			}
+		}
+		for _, claimant := range claimants {
+			switch {
+			case named != "" && claimant == named:
+				continue
+			case named != "":
+				repair.Suggestions = append(repair.Suggestions, fmt.Sprintf("drop alias %q from %s; it shadows target %s's name", key, claimant, named))
+			case claimant == repair.Winner:
+				continue
+			default:
+				rename := uniqueAlias(claimant+"-"+key, taken)
+				repair.Suggestions = append(repair.Suggestions, fmt.Sprintf("rename %s's alias %q to %q (%s keeps %q)", claimant, key, rename, repair.Winner, key))
+			}
+		}
+		repairs = append(repairs, repair)
+	}
+	return repairs
+}
+
+func uniqueAlias(base string, taken map[string]bool) string {
+	candidate := normalizeAlias(base)
+	for n := 2; taken[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", normalizeAlias(base), n)
+	}
+	taken[candidate] = true
+	return candidate
+}
@@ -0,0 +1,116 @@
+package controlplane
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnusedSlices(t *testing.T) {
+	slices := map[string]SliceManifest{"meta": {}, "software": {}, "scratch": {}}
+	targets := []Target{{Name: "meta", Slice: "meta"}, {Name: "build", Slice: "software"}}
+
+	if got := UnusedSlices(slices, targets); !reflect.DeepEqual(got, []string{"scratch"}) {
+		t.Fatalf("expected [scratch], got %v", got)
+	}
+}
+
+func TestOrphanExtensions(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root,
+		"extensions/used/index.ts",
+		"extensions/used/helper.ts",
+		"extensions/lonely/index.ts",
+		"extensions/glob-a/index.js",
+		"extensions/top.mjs",
+	)
+	t.Setenv("PICTL_EXTENSION_PATH", "")
+
+	slices := map[string]SliceManifest{
+		"meta":  {Extensions: extensionRefs("extensions/used/index.ts")},
+		"other": {Extensions: []ExtensionRef{{Path: "extensions/glob-*/index.js", OS: []string{"plan9"}}, {Path: "extensions/missing.ts"}}},
+	}
+
+	got, err := OrphanExtensions(root, slices)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"extensions/lonely/index.ts", "extensions/top.mjs"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestExtensionInventory(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root,
+		"extensions/shared/index.ts",
+		"extensions/glob-a/index.ts",
+		"extensions/glob-b/index.ts",
+		"extensions/off.ts",
+	)
+	t.Setenv("PICTL_EXTENSION_PATH", "")
+
+	disabled := false
+	slices := map[string]SliceManifest{
+		"meta":     {Extensions: extensionRefs("extensions/shared/index.ts", "extensions/glob-*/index.ts")},
+		"software": {Extensions: extensionRefs("extensions/shared/index.ts")},
+		"parked":   {Enabled: &disabled, Extensions: extensionRefs("extensions/off.ts")},
+	}
+
+	got, err := ExtensionInventory(root, slices)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ExtensionUsage{
+		{Path: "extensions/glob-a/index.ts", Slices: []string{"meta"}},
+		{Path: "extensions/glob-b/index.ts", Slices: []string{"meta"}},
+		{Path: "extensions/shared/index.ts", Slices: []string{"meta", "software"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	slices["broken"] = SliceManifest{Extensions: extensionRefs("extensions/missing.ts")}
+	if _, err := ExtensionInventory(root, slices); err == nil {
+		t.Fatalf("expected unresolvable slice to fail the inventory")
+	}
+}
+
+func TestSliceReferences(t *testing.T) {
+	slices := map[string]SliceManifest{
+		"base":   {Extensions: extensionRefs("extensions/x.ts")},
+		"review": {Include: []string{"base"}},
+		"other":  {Extensions: extensionRefs("extensions/y.ts")},
+	}
+	targets := []Target{{Name: "build", Slice: "base"}, {Name: "research", Slice: "other", FallbackSlice: "base"}}
+
+	want := []string{"target build", "target research (fallback)", "slice review"}
+	if got := SliceReferences("base", slices, targets); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := SliceReferences("review", slices, targets); len(got) != 0 {
+		t.Fatalf("expected no references, got %v", got)
+	}
+}
+
+func TestAliasRepairs(t *testing.T) {
+	if repairs := AliasRepairs(CanonicalTargets()); len(repairs) != 0 {
+		t.Fatalf("expected no repairs for the built-in catalog, got %+v", repairs)
+	}
+
+	targets := []Target{
+		{Name: "build", Aliases: []string{"dev", "ops"}},
+		{Name: "ops", Aliases: []string{"admin", "dev"}},
+		{Name: "daybook", Aliases: []string{"admin", "ops-dev", " "}},
+	}
+	repairs := AliasRepairs(targets)
+	want := []AliasRepair{
+		{Alias: "dev", Winner: "build", Claimants: []string{"build", "ops"}, Suggestions: []string{`rename ops's alias "dev" to "ops-dev-2" (build keeps "dev")`}},
+		{Alias: "ops", Winner: "build", Claimants: []string{"build", "ops"}, Suggestions: []string{`drop alias "ops" from build; it shadows target ops's name`}},
+		{Alias: "admin", Winner: "ops", Claimants: []string{"ops", "daybook"}, Suggestions: []string{`rename daybook's alias "admin" to "daybook-admin" (ops keeps "admin")`}},
+		{Claimants: []string{"daybook"}, Suggestions: []string{"remove the empty name or alias from daybook"}},
+	}
+	if !reflect.DeepEqual(repairs, want) {
+		t.Fatalf("unexpected repairs:\n got %+v\nwant %+v", repairs, want)
+	}
+}
@@ -0,0 +1,123 @@
+package controlplane
+
+import (
+	"sort"
+	"strings"
+)
+
+type SliceEdge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Missing bool   `json:"missing,omitempty"`
+	Cycle   bool   `json:"cycle,omitempty"`
+}
+
+type SliceGraph struct {
+	Slices []string    `json:"slices"`
+	Edges  []SliceEdge `json:"edges"`
+	Cycles [][]string  `json:"cycles,omitempty"`
+}
+
+func DependencyGraph(slices map[string]SliceManifest) SliceGraph {
+	graph := SliceGraph{Slices: sortedSliceNames(slices), Edges: []SliceEdge{}}
+	for _, name := range graph.Slices {
+		for _, include := range slices[name].Include {
+			include = strings.TrimSpace(include)
+			_, ok := slices[include]
+			graph.Edges = append(graph.Edges, SliceEdge{From: name, To: include, Missing: !ok})
+		}
+	}
+
+	graph.Cycles = includeCycles(graph.Slices, graph.Edges)
+	inCycle := map[[2]string]bool{}
+	for _, cycle := range graph.Cycles {
+		for i := 0; i+1 < len(cycle); i++ {
+			inCycle[[2]string{cycle[i], cycle[i+1]}] = true
+		}
+	}
+	for i, edge := range graph.Edges {
+		graph.Edges[i].Cycle = inCycle[[2]string{edge.From, edge.To}]
+	}
+	return graph
+}
+
+func (g SliceGraph) Includes(name string) []SliceEdge {
+	var out []SliceEdge
+	for _, edge := range g.Edges {
+		if edge.From == name {
+			out = append(out, edge)
+		}
+	}
+	return out
+}
+
+func (g SliceGraph) Roots() []string {
+	included := map[string]bool{}
+	for _, edge := range g.Edges {
+		if edge.From != edge.To {
+			included[edge.To] = true
+		}
+	}
+	var roots []string
+	for _, name := range g.Slices {
+		if !included[name] {
+			roots = append(roots, name)
+		}
+	}
+	return roots
+}
+
+func includeCycles(names []string, edges []SliceEdge) [][]string {
+	next := map[string][]string{}
+	for _, edge := range edges {
+		if !edge.Missing {
+			next[edge.From] = append(next[edge.From], edge.To)
+		}
+	}
+
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := map[string]int{}
+	seen := map[string]bool{}
+	var cycles [][]string
+	var stack []string
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = active
+		stack = append(stack, name)
+		for _, to := range next[name] {
+			switch state[to] {
+			case active:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == to {
+						cycle := append(append([]string{}, stack[i:]...), to)
+						if key := cycleKey(cycle); !seen[key] {
+							seen[key] = true
+							cycles = append(cycles, cycle)
+						}
+						break
+					}
+				}
+			case unvisited:
+				visit(to)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+	for _, name := range names {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+	return cycles
+}
+
+func cycleKey(cycle []string) string {
+	members := append([]string{}, cycle[:len(cycle)-1]...)
+	sort.Strings(members)
+	return strings.Join(members, "\x00")
+}
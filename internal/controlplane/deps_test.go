@@ -0,0 +1,40 @@
+package controlplane
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependencyGraphEdgesAndCycles(t *testing.T) {
+	root := t.TempDir()
+	writeSliceFiles(t, root, map[string]string{
+		"base.json":  `{"extensions": ["extensions/base.ts"]}`,
+		"combo.json": `{"include": ["base", "lint"]}`,
+		"lint.json":  `{"include": ["base", "nope"]}`,
+		"a.json":     `{"include": ["b"]}`,
+		"b.json":     `{"include": ["a"]}`,
+	})
+	slices, err := LoadSliceManifests(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph := DependencyGraph(slices)
+	want := []SliceEdge{
+		{From: "a", To: "b", Cycle: true},
+		{From: "b", To: "a", Cycle: true},
+		{From: "combo", To: "base"},
+		{From: "combo", To: "lint"},
+		{From: "lint", To: "base"},
+		{From: "lint", To: "nope", Missing: true},
+	}
+	if !reflect.DeepEqual(graph.Edges, want) {
+		t.Fatalf("unexpected edges:\n got %+v\nwant %+v", graph.Edges, want)
+	}
+	if !reflect.DeepEqual(graph.Cycles, [][]string{{"a", "b", "a"}}) {
+		t.Fatalf("expected one a <-> b cycle, got %v", graph.Cycles)
+	}
+	if roots := graph.Roots(); !reflect.DeepEqual(roots, []string{"combo"}) {
+		t.Fatalf("expected combo to be the only root, got %v", roots)
+	}
+}
@@ -0,0 +1,54 @@
+package controlplane
+
+import (
+	"strings"
+	"text/template"
+)
+
+type DescriptionContext struct {
+	Target   string
+	Slice    string
+	Profile  string
+	Category string
+	Targets  []string
+}
+
+func RenderDescription(text string, ctx DescriptionContext) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	tmpl, err := template.New("description").Option("missingkey=error").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(text)
+	if err != nil {
+		return text
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, ctx); err != nil {
+		return text
+	}
+	return out.String()
+}
+
+func TargetDescription(target Target) string {
+	return RenderDescription(target.Description, DescriptionContext{
+		Target:   target.Name,
+		Slice:    target.Slice,
+		Profile:  target.DefaultProfile,
+		Category: target.Category,
+		Targets:  []string{target.Name},
+	})
+}
+
+func SliceDescription(name string, manifest SliceManifest, targets []Target) string {
+	ctx := DescriptionContext{Slice: name, Profile: manifest.DefaultProfile}
+	for _, target := range targets {
+		if target.Slice == name {
+			ctx.Targets = append(ctx.Targets, target.Name)
+		}
+	}
+	if len(ctx.Targets) > 0 {
+		ctx.Target = ctx.Targets[0]
+	}
+	return RenderDescription(manifest.Description, ctx)
+}
@@ -0,0 +1,41 @@
+package controlplane
+
+import "testing"
+
+func TestRenderDescription(t *testing.T) {
+	ctx := DescriptionContext{Target: "build", Slice: "software", Profile: "execute", Category: "Engineering", Targets: []string{"build", "review"}}
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain text passes through", "Implementation and delivery", "Implementation and delivery"},
+		{"placeholders", "{{.Slice}} tools for {{.Target}} ({{.Profile}})", "software tools for build (execute)"},
+		{"join", "used by {{join .Targets \", \"}}", "used by build, review"},
+		{"parse error keeps raw text", "broken {{.Slice", "broken {{.Slice"},
+		{"unknown field keeps raw text", "{{.Nope}} here", "{{.Nope}} here"},
+	}
+	for _, tc := range cases {
+		if got := RenderDescription(tc.text, ctx); got != tc.want {
+			t.Fatalf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestSliceAndTargetDescriptions(t *testing.T) {
+	targets := []Target{
+		{Name: "build", Slice: "software", DefaultProfile: "execute", Description: "{{.Slice}} via {{.Profile}}"},
+		{Name: "review", Slice: "software"},
+	}
+	if got := TargetDescription(targets[0]); got != "software via execute" {
+		t.Fatalf("unexpected target description %q", got)
+	}
+
+	manifest := SliceManifest{DefaultProfile: "fast", Description: "{{.Slice}} ({{.Profile}}) backs {{join .Targets \"+\"}}"}
+	if got := SliceDescription("software", manifest, targets); got != "software (fast) backs build+review" {
+		t.Fatalf("unexpected slice description %q", got)
+	}
+	if got := SliceDescription("lonely", SliceManifest{Description: "first target: {{.Target}}."}, targets); got != "first target: ." {
+		t.Fatalf("expected an unused slice to render an empty target, got %q", got)
+	}
+}
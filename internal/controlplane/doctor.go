@@ -0,0 +1,264 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type CheckStatus string
+
+const (
+	CheckOK   CheckStatus = "ok"
+	CheckWarn CheckStatus = "warn"
+	CheckFail CheckStatus = "fail"
+)
+
+type CheckResult struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message"`
+}
+
+func Diagnose(root string, slices map[string]SliceManifest) []CheckResult {
+	results := []CheckResult{CheckTargetAliases(CanonicalTargets())}
+	results = append(results, CheckTargetCatalog(CanonicalTargets())...)
+	results = append(results, CheckSliceDescriptions(slices))
+	results = append(results, CheckSliceExtensions(root, slices)...)
+	results = append(results, CheckSliceRequirements(slices)...)
+	results = append(results, CheckUnusedSlices(slices, CanonicalTargets()))
+	results = append(results, CheckOrphanExtensions(root, slices))
+	return results
+}
+
+func PromoteWarnings(results []CheckResult) []CheckResult {
+	out := make([]CheckResult, len(results))
+	for i, result := range results {
+		if result.Status == CheckWarn {
+			result.Status = CheckFail
+		}
+		out[i] = result
+	}
+	return out
+}
+
+func CheckSliceExtensions(root string, slices map[string]SliceManifest) []CheckResult {
+	var results []CheckResult
+	for _, name := range sortedSliceNames(slices) {
+		resolved, err := ResolveExtensions(root, slices[name], LaunchOptions{})
+		if err != nil {
+			results = append(results, CheckResult{Name: "extensions", Status: CheckFail, Message: fmt.Sprintf("slice %s: %v", name, err)})
+			continue
+		}
+		for _, warning := range resolved.Warnings {
+			results = append(results, CheckResult{Name: "extensions", Status: CheckWarn, Message: fmt.Sprintf("slice %s: %s", name, warning)})
+		}
+	}
+	if len(results) == 0 {
+		results = append(results, CheckResult{Name: "extensions", Status: CheckOK, Message: fmt.Sprintf("all %d slices resolve", len(slices))})
+	}
+	return results
+}
+
+func CheckUnusedSlices(slices map[string]SliceManifest, targets []Target) CheckResult {
+	unused := UnusedSlices(slices, targets)
+	if len(unused) == 0 {
+		return CheckResult{Name: "unused-slices", Status: CheckOK, Message: "every slice is used by a target"}
+	}
+	return CheckResult{Name: "unused-slices", Status: CheckWarn, Message: strings.Join(unused, ", ")}
+}
+
+func CheckOrphanExtensions(root string, slices map[string]SliceManifest) CheckResult {
+	orphans, err := OrphanExtensions(root, slices)
+	if err != nil {
+		return CheckResult{Name: "orphaned-extensions", Status: CheckFail, Message: err.Error()}
+	}
+	if len(orphans) == 0 {
+		return CheckResult{Name: "orphaned-extensions", Status: CheckOK, Message: "every extension is loaded by a slice"}
+	}
+	return CheckResult{Name: "orphaned-extensions", Status: CheckWarn, Message: strings.Join(orphans, ", ")}
+}
+
+func CheckTargetAliases(targets []Target) CheckResult {
+	if err := ValidateTargets(targets); err != nil {
+		return CheckResult{Name: "target-aliases", Status: CheckFail, Message: err.Error()}
+	}
+	return CheckResult{Name: "target-aliases", Status: CheckOK, Message: fmt.Sprintf("%d targets, no alias collisions", len(targets))}
+}
+
+func CheckTargetCatalog(targets []Target) []CheckResult {
+	var results []CheckResult
+	byDescription := make(map[string][]string)
+	var descriptions []string
+	for _, target := range targets {
+		if len(target.Aliases) == 0 {
+			results = append(results, CheckResult{Name: "target-catalog", Status: CheckWarn, Message: fmt.Sprintf("target %s has no aliases", target.Name)})
+		}
+		description := strings.TrimSpace(target.Description)
+		if description == "" {
+			continue
+		}
+		if _, seen := byDescription[description]; !seen {
+			descriptions = append(descriptions, description)
+		}
+		byDescription[description] = append(byDescription[description], target.Name)
+	}
+	for _, description := range descriptions {
+		if names := byDescription[description]; len(names) > 1 {
+			results = append(results, CheckResult{Name: "target-catalog", Status: CheckWarn, Message: fmt.Sprintf("targets %s share the description %q", strings.Join(names, ", "), description)})
+		}
+	}
+	if len(results) == 0 {
+		results = append(results, CheckResult{Name: "target-catalog", Status: CheckOK, Message: "every target has aliases and a distinct description"})
+	}
+	return results
+}
+
+func CheckSliceDescriptions(slices map[string]SliceManifest) CheckResult {
+	var missing []string
+	for name, manifest := range slices {
+		if strings.TrimSpace(manifest.Description) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return CheckResult{Name: "slice-descriptions", Status: CheckOK, Message: "every slice has a description"}
+	}
+	sort.Strings(missing)
+	return CheckResult{Name: "slice-descriptions", Status: CheckWarn, Message: "missing description: " + strings.Join(missing, ", ")}
+}
+
+func CheckExtensionFiles(root string, slices map[string]SliceManifest) []CheckResult {
+	roots := ExtensionRoots(root)
+	var results []CheckResult
+	for _, name := range sortedSliceNames(slices) {
+		var problems []string
+		files := 0
+		for _, ref := range slices[name].Extensions {
+			paths, err := resolveExtension(roots, strings.TrimSpace(ref.Path))
+			if err != nil {
+				problems = append(problems, err.Error())
+				continue
+			}
+			for _, path := range paths {
+				files++
+				if problem := CheckExtensionFile(path); problem != "" {
+					problems = append(problems, fmt.Sprintf("%s: %s", path, problem))
+				}
+			}
+		}
+		for _, rel := range slices[name].ExtensionDirs {
+			_, paths, err := resolveExtensionDir(roots, strings.TrimSpace(rel))
+			if err != nil {
+				problems = append(problems, err.Error())
+				continue
+			}
+			files += len(paths)
+		}
+
+		if len(problems) > 0 {
+			results = append(results, CheckResult{Name: "extension-files", Status: CheckFail, Message: fmt.Sprintf("slice %s: %s", name, strings.Join(problems, "; "))})
+			continue
+		}
+		results = append(results, CheckResult{Name: "extension-files", Status: CheckOK, Message: fmt.Sprintf("slice %s: %d file(s) ok", name, files)})
+	}
+	return results
+}
+
+func TypecheckExtensions(root string, slices map[string]SliceManifest, timeout time.Duration) []CheckResult {
+	checker, args := findTypechecker()
+	if checker == "" {
+		return []CheckResult{{Name: "typecheck", Status: CheckWarn, Message: "no deno or tsc in PATH; checked file existence only"}}
+	}
+
+	var results []CheckResult
+	for _, name := range sortedSliceNames(slices) {
+		resolved, err := ResolveExtensions(root, slices[name], LaunchOptions{})
+		if err != nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		cmd := exec.CommandContext(ctx, checker, append(append([]string{}, args...), resolved.Paths...)...)
+		cmd.Dir = root
+		output, err := cmd.CombinedOutput()
+		timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+		cancel()
+
+		switch {
+		case timedOut:
+			results = append(results, CheckResult{Name: "typecheck", Status: CheckWarn, Message: fmt.Sprintf("slice %s: %s timed out after %s", name, checker, timeout)})
+		case err != nil:
+			results = append(results, CheckResult{Name: "typecheck", Status: CheckFail, Message: fmt.Sprintf("slice %s: %s", name, firstLine(string(output), err))})
+		default:
+			results = append(results, CheckResult{Name: "typecheck", Status: CheckOK, Message: fmt.Sprintf("slice %s: %s passed", name, checker)})
+		}
+	}
+	return results
+}
+
+var PiFlags = []string{"--no-extensions", "--no-skills", "--no-prompt-templates", "--no-themes", "-e"}
+
+func CheckPiFlags(timeout time.Duration) CheckResult {
+	if _, err := exec.LookPath("pi"); err != nil {
+		return CheckResult{Name: "pi-flags", Status: CheckFail, Message: ErrPiNotFound.Error()}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "pi", "--help").CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return CheckResult{Name: "pi-flags", Status: CheckWarn, Message: fmt.Sprintf("pi --help timed out after %s", timeout)}
+	}
+	if err != nil && len(output) == 0 {
+		return CheckResult{Name: "pi-flags", Status: CheckFail, Message: fmt.Sprintf("pi --help: %v", err)}
+	}
+
+	var missing []string
+	for _, flag := range PiFlags {
+		if !advertisesFlag(string(output), flag) {
+			missing = append(missing, flag)
+		}
+	}
+	if len(missing) > 0 {
+		return CheckResult{Name: "pi-flags", Status: CheckFail, Message: "pi --help does not list " + strings.Join(missing, ", ")}
+	}
+	return CheckResult{Name: "pi-flags", Status: CheckOK, Message: fmt.Sprintf("pi accepts all %d flags pictl passes", len(PiFlags))}
+}
+
+func advertisesFlag(help string, flag string) bool {
+	return regexp.MustCompile(`(^|[\s,\[(])` + regexp.QuoteMeta(flag) + `($|[\s,=<\[\])])`).MatchString(help)
+}
+
+func findTypechecker() (string, []string) {
+	if _, err := exec.LookPath("deno"); err == nil {
+		return "deno", []string{"check"}
+	}
+	if _, err := exec.LookPath("tsc"); err == nil {
+		return "tsc", []string{"--noEmit", "--allowJs", "--skipLibCheck"}
+	}
+	return "", nil
+}
+
+func firstLine(output string, err error) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return err.Error()
+}
+
+func sortedSliceNames(slices map[string]SliceManifest) []string {
+	names := make([]string, 0, len(slices))
+	for name := range slices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,156 @@
+package controlplane
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckSliceExtensionsStatuses(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts", "extensions/notes.txt")
+	t.Setenv("PICTL_EXTENSION_PATH", "")
+
+	slices := map[string]SliceManifest{
+		"good":   {Extensions: extensionRefs("extensions/x.ts")},
+		"broken": {Extensions: extensionRefs("extensions/missing.ts")},
+		"odd":    {Extensions: extensionRefs("extensions/notes.txt")},
+	}
+
+	statuses := map[CheckStatus]int{}
+	for _, result := range CheckSliceExtensions(root, slices) {
+		statuses[result.Status]++
+	}
+	if statuses[CheckFail] != 1 || statuses[CheckWarn] != 1 || statuses[CheckOK] != 0 {
+		t.Fatalf("expected one fail and one warn, got %v", statuses)
+	}
+
+	results := CheckSliceExtensions(root, map[string]SliceManifest{"good": slices["good"]})
+	if len(results) != 1 || results[0].Status != CheckOK {
+		t.Fatalf("expected a single ok result, got %+v", results)
+	}
+}
+
+func TestCheckTargetCatalog(t *testing.T) {
+	results := CheckTargetCatalog([]Target{
+		{Name: "a", Description: "same", Aliases: []string{"x"}},
+		{Name: "b", Description: "same"},
+		{Name: "c", Description: "other", Aliases: []string{"y"}},
+	})
+	if len(results) != 2 {
+		t.Fatalf("expected two warnings, got %+v", results)
+	}
+	if results[0].Status != CheckWarn || !strings.Contains(results[0].Message, "target b has no aliases") {
+		t.Fatalf("expected missing-alias warning, got %+v", results[0])
+	}
+	if results[1].Status != CheckWarn || !strings.Contains(results[1].Message, "targets a, b share") {
+		t.Fatalf("expected shared-description warning, got %+v", results[1])
+	}
+
+	results = CheckTargetCatalog(CanonicalTargets())
+	if len(results) != 1 || results[0].Status != CheckOK {
+		t.Fatalf("expected canonical targets to pass, got %+v", results)
+	}
+}
+
+func TestCheckSliceDescriptions(t *testing.T) {
+	result := CheckSliceDescriptions(map[string]SliceManifest{"b": {}, "a": {Description: " "}, "c": {Description: "ok"}})
+	if result.Status != CheckWarn || result.Message != "missing description: a, b" {
+		t.Fatalf("expected warning listing a, b; got %+v", result)
+	}
+	if result := CheckSliceDescriptions(map[string]SliceManifest{"c": {Description: "ok"}}); result.Status != CheckOK {
+		t.Fatalf("expected ok, got %+v", result)
+	}
+}
+
+func TestCheckExtensionFilesAggregatesPerSlice(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts", "extensions/dir/nested.ts")
+	if err := os.WriteFile(filepath.Join(root, "extensions", "empty.ts"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PICTL_EXTENSION_PATH", "")
+
+	slices := map[string]SliceManifest{
+		"clean": {Extensions: []ExtensionRef{{Path: "extensions/x.ts"}, {Path: "extensions/x.ts", OS: []string{"plan9"}}}},
+		"messy": {Extensions: extensionRefs("extensions/missing.ts", "extensions/dir", "extensions/empty.ts", "extensions/x.ts")},
+	}
+
+	results := CheckExtensionFiles(root, slices)
+	if len(results) != 2 {
+		t.Fatalf("expected one result per slice, got %+v", results)
+	}
+	if results[0].Status != CheckOK || !strings.Contains(results[0].Message, "slice clean: 2 file(s) ok") {
+		t.Fatalf("expected clean slice to pass, got %+v", results[0])
+	}
+	messy := results[1]
+	if messy.Status != CheckFail {
+		t.Fatalf("expected messy slice to fail, got %+v", messy)
+	}
+	for _, want := range []string{"extensions/missing.ts", "directory", "empty"} {
+		if !strings.Contains(messy.Message, want) {
+			t.Fatalf("expected %q in %q", want, messy.Message)
+		}
+	}
+}
+
+func TestTypecheckExtensionsDegradesWithoutChecker(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	results := TypecheckExtensions(t.TempDir(), map[string]SliceManifest{}, time.Second)
+	if len(results) != 1 || results[0].Status != CheckWarn {
+		t.Fatalf("expected a single existence-only warning, got %+v", results)
+	}
+}
+
+func TestTypecheckExtensionsReportsCheckerFailure(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/good.ts", "extensions/bad.ts")
+	binDir := t.TempDir()
+	script := "#!/bin/sh\ncase \"$*\" in *bad.ts*) echo 'bad.ts(1,1): error TS1005'; exit 2;; esac\n"
+	if err := os.WriteFile(filepath.Join(binDir, "tsc"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+
+	results := TypecheckExtensions(root, map[string]SliceManifest{
+		"bad":  {Extensions: extensionRefs("extensions/bad.ts")},
+		"good": {Extensions: extensionRefs("extensions/good.ts")},
+	}, 5*time.Second)
+	if len(results) != 2 || results[0].Status != CheckFail || results[1].Status != CheckOK {
+		t.Fatalf("expected bad to fail and good to pass, got %+v", results)
+	}
+	if !strings.Contains(results[0].Message, "TS1005") {
+		t.Fatalf("expected checker output in message, got %q", results[0].Message)
+	}
+}
+
+func TestCheckPiFlags(t *testing.T) {
+	writeFakePi(t, `cat <<'HELP'
+Usage: pi [options]
+  -e, --extension <path>  Load an extension
+  --no-extensions         Disable discovered extensions
+  --no-skills             Disable skills
+  --no-themes-extra       Not the flag we want
+HELP`)
+
+	result := CheckPiFlags(5 * time.Second)
+	if result.Status != CheckFail {
+		t.Fatalf("expected missing flags to fail, got %+v", result)
+	}
+	if result.Message != "pi --help does not list --no-prompt-templates, --no-themes" {
+		t.Fatalf("unexpected message: %q", result.Message)
+	}
+
+	writeFakePi(t, "echo '-e <path> --no-extensions --no-skills --no-prompt-templates --no-themes'; exit 1")
+	if result := CheckPiFlags(5 * time.Second); result.Status != CheckOK {
+		t.Fatalf("expected every flag to be found even with a nonzero exit, got %+v", result)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if result := CheckPiFlags(time.Second); result.Status != CheckFail || !strings.Contains(result.Message, "not found") {
+		t.Fatalf("expected missing pi to fail, got %+v", result)
+	}
+}
@@ -0,0 +1,110 @@
+package controlplane
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const DotenvFile = ".env"
+
+func LoadDotenv(path string) ([]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDotenv(raw)
+}
+
+func ParseDotenv(raw []byte) ([]string, error) {
+	var out []string
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validEnvKey(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=value", line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		out = append(out, key+"="+value)
+	}
+	return out, scanner.Err()
+}
+
+func ParseEnvAssignment(entry string) (string, string, error) {
+	key, value, ok := strings.Cut(entry, "=")
+	if !ok || !validEnvKey(key) {
+		return "", "", fmt.Errorf("invalid --env %q (want KEY=VALUE with a name of letters, digits, and _)", entry)
+	}
+	return key, value, nil
+}
+
+func validEnvKey(key string) bool {
+	if key == "" || (key[0] >= '0' && key[0] <= '9') {
+		return false
+	}
+	for _, r := range key {
+		if !(r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func dotenvEntries(root string, opts LaunchOptions) ([]string, error) {
+	if opts.NoEnvFile {
+		return nil, nil
+	}
+
+	var paths []string
+	for _, path := range opts.EnvFiles {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		path := filepath.Join(root, DotenvFile)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+		paths = []string{path}
+	}
+
+	var merged []string
+	for _, path := range paths {
+		entries, err := LoadDotenv(path)
+		if err != nil {
+			return nil, fmt.Errorf("env file %s: %w", path, err)
+		}
+		merged = mergeUnder(entries, merged)
+	}
+	return merged, nil
+}
+
+func mergeUnder(environ []string, defaults []string) []string {
+	present := make(map[string]bool, len(environ))
+	for _, entry := range environ {
+		key, _, _ := strings.Cut(entry, "=")
+		present[key] = true
+	}
+
+	out := make([]string, 0, len(defaults)+len(environ))
+	for _, entry := range defaults {
+		key, _, _ := strings.Cut(entry, "=")
+		if !present[key] {
+			out = append(out, entry)
+		}
+	}
+	return append(out, environ...)
+}
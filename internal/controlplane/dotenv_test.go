@@ -0,0 +1,119 @@
+package controlplane
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	raw := []byte("# defaults\n\nPI_A=one\n  PI_B = \"two words\"  \nPI_C='x=y'\nPI_D=\n")
+
+	got, err := ParseDotenv(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "PI_A=one|PI_B=two words|PI_C=x=y|PI_D="
+	if strings.Join(got, "|") != want {
+		t.Fatalf("expected %q, got %q", want, strings.Join(got, "|"))
+	}
+
+	for _, bad := range []string{"NOEQUALS\n", "1BAD=x\n", "export PI_A=x\n"} {
+		if _, err := ParseDotenv([]byte(bad)); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Fatalf("expected line error for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestBuildLaunchSpecDotenvPrecedence(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	unsetProfileEnv(t)
+	if err := os.WriteFile(filepath.Join(root, ".env"), []byte("PICTL_TEST_DOTENV=from-file\nPICTL_TEST_SHADOWED=from-file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PICTL_TEST_SHADOWED", "from-process")
+
+	manifest := SliceManifest{DefaultProfile: "execute", Extensions: extensionRefs("extensions/x.ts")}
+	spec, err := BuildLaunchSpec(root, manifest, false, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasEnv(spec.Env, "PICTL_TEST_DOTENV=from-file") {
+		t.Fatalf("expected .env value to be added")
+	}
+	if !hasEnv(spec.Env, "PICTL_TEST_SHADOWED=from-process") || hasEnv(spec.Env, "PICTL_TEST_SHADOWED=from-file") {
+		t.Fatalf("expected process env to win over .env")
+	}
+
+	spec, err = BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{NoEnvFile: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hasEnv(spec.Env, "PICTL_TEST_DOTENV=from-file") {
+		t.Fatalf("expected NoEnvFile to skip .env")
+	}
+}
+
+func TestBuildLaunchSpecDotenvProfile(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	unsetProfileEnv(t)
+	envFile := filepath.Join(t.TempDir(), "alt.env")
+	if err := os.WriteFile(envFile, []byte("PI_DEFAULT_PROFILE=fast\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := SliceManifest{DefaultProfile: "execute", Extensions: extensionRefs("extensions/x.ts")}
+	spec, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{EnvFiles: []string{envFile}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasEnv(spec.Env, "PI_DEFAULT_PROFILE=fast") || hasEnv(spec.Env, "PI_DEFAULT_PROFILE=execute") {
+		t.Fatalf("expected env file profile to act like an inherited PI_DEFAULT_PROFILE, got %v", spec.Env)
+	}
+
+	t.Setenv("PI_DEFAULT_PROFILE", "ship")
+	spec, err = BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{EnvFiles: []string{envFile}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasEnv(spec.Env, "PI_DEFAULT_PROFILE=ship") || hasEnv(spec.Env, "PI_DEFAULT_PROFILE=fast") {
+		t.Fatalf("expected process PI_DEFAULT_PROFILE to beat the env file")
+	}
+
+	if _, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{EnvFiles: []string{filepath.Join(root, "missing.env")}}); err == nil {
+		t.Fatalf("expected an explicit missing env file to fail")
+	}
+}
+
+func TestBuildLaunchSpecLayersEnvFiles(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	dir := t.TempDir()
+	base, override := filepath.Join(dir, "base.env"), filepath.Join(dir, "staging.env")
+	if err := os.WriteFile(base, []byte("PICTL_TEST_URL=http://base\nPICTL_TEST_TOKEN=base-token\nPICTL_TEST_LEVEL=info\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(override, []byte("PICTL_TEST_URL=http://staging\nPICTL_TEST_LEVEL=debug\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PICTL_TEST_LEVEL", "warn")
+
+	manifest := SliceManifest{Extensions: extensionRefs("extensions/x.ts")}
+	spec, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{EnvFiles: []string{base, override}, Env: []string{"PICTL_TEST_TOKEN=flag-token"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"PICTL_TEST_URL=http://staging", "PICTL_TEST_LEVEL=warn", "PICTL_TEST_TOKEN=flag-token"} {
+		if !hasEnv(spec.Env, want) {
+			t.Fatalf("expected %s (later file over earlier, shell over files, --env over all), got %v", want, spec.Env)
+		}
+	}
+	for _, unwanted := range []string{"PICTL_TEST_URL=http://base", "PICTL_TEST_LEVEL=debug", "PICTL_TEST_TOKEN=base-token"} {
+		if hasEnv(spec.Env, unwanted) {
+			t.Fatalf("expected %s to be overridden, got %v", unwanted, spec.Env)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const EnvProfilesFile = "env-profiles.json"
+
+var ErrUnknownEnvProfile = errors.New("unknown env profile")
+
+func LoadEnvProfiles(root string) (map[string]map[string]string, error) {
+	path := filepath.Join(root, EnvProfilesFile)
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := map[string]map[string]string{}
+	if err := json.Unmarshal(raw, &profiles); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, vars := range profiles {
+		for key := range vars {
+			if !validEnvKey(key) {
+				return nil, fmt.Errorf("%s: env profile %q: invalid variable name %q", path, name, key)
+			}
+		}
+	}
+	return profiles, nil
+}
+
+func EnvProfileEntries(root string, name string) ([]string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+
+	profiles, err := LoadEnvProfiles(root)
+	if err != nil {
+		return nil, err
+	}
+	vars, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q (defined in %s: %s)", ErrUnknownEnvProfile, name, EnvProfilesFile, strings.Join(EnvProfileNames(profiles), ", "))
+	}
+
+	out := make([]string, 0, len(vars))
+	for key, value := range vars {
+		out = append(out, key+"="+value)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func EnvProfileNames(profiles map[string]map[string]string) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,104 @@
+package controlplane
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeEnvProfiles(t *testing.T, root string, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(root, EnvProfilesFile), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildLaunchSpecEnvProfile(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	unsetProfileEnv(t)
+	writeEnvProfiles(t, root, `{
+  "dev": {"PICTL_TEST_API": "http://localhost:8080", "PICTL_TEST_SHADOWED": "from-profile", "PICTL_TEST_LAYER": "from-profile"},
+  "prod": {"PICTL_TEST_API": "https://api.example.com"}
+}`)
+	if err := os.WriteFile(filepath.Join(root, ".env"), []byte("PICTL_TEST_LAYER=from-dotenv\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PICTL_TEST_SHADOWED", "from-process")
+
+	manifest := SliceManifest{Extensions: extensionRefs("extensions/x.ts")}
+	spec, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{EnvProfile: "dev"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasEnv(spec.Env, "PICTL_TEST_API=http://localhost:8080") {
+		t.Fatalf("expected env profile value to be added")
+	}
+	if !hasEnv(spec.Env, "PICTL_TEST_SHADOWED=from-process") || hasEnv(spec.Env, "PICTL_TEST_SHADOWED=from-profile") {
+		t.Fatalf("expected process env to win over the env profile")
+	}
+	if !hasEnv(spec.Env, "PICTL_TEST_LAYER=from-profile") || hasEnv(spec.Env, "PICTL_TEST_LAYER=from-dotenv") {
+		t.Fatalf("expected the selected env profile to win over .env")
+	}
+
+	spec, err = BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hasEnv(spec.Env, "PICTL_TEST_API=http://localhost:8080") {
+		t.Fatalf("expected no env profile values without a selection")
+	}
+
+	_, err = BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{EnvProfile: "staging"})
+	if !errors.Is(err, ErrUnknownEnvProfile) || !strings.Contains(err.Error(), "dev, prod") {
+		t.Fatalf("expected unknown env profile error listing defined names, got %v", err)
+	}
+}
+
+func TestBuildLaunchSpecEnvProfilePiDefaultProfile(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	unsetProfileEnv(t)
+	writeEnvProfiles(t, root, `{"quick": {"PI_DEFAULT_PROFILE": "fast"}}`)
+
+	manifest := SliceManifest{DefaultProfile: "execute", Extensions: extensionRefs("extensions/x.ts")}
+	cases := []struct {
+		name string
+		opts LaunchOptions
+		env  string
+		want string
+	}{
+		{"env profile beats slice default", LaunchOptions{EnvProfile: "quick"}, "", "fast"},
+		{"explicit profile beats env profile", LaunchOptions{EnvProfile: "quick", Profile: "ship"}, "", "ship"},
+		{"process env beats env profile", LaunchOptions{EnvProfile: "quick"}, "meta", "meta"},
+	}
+	for _, tc := range cases {
+		if tc.env != "" {
+			t.Setenv("PI_DEFAULT_PROFILE", tc.env)
+		}
+		spec, err := BuildLaunchSpecWithOptions(root, manifest, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if got, _ := lookupEnv(spec.Env, "PI_DEFAULT_PROFILE"); got != tc.want {
+			t.Fatalf("%s: expected PI_DEFAULT_PROFILE=%s, got %q", tc.name, tc.want, got)
+		}
+		if n := countEnv(spec.Env, "PI_DEFAULT_PROFILE"); n != 1 {
+			t.Fatalf("%s: expected one PI_DEFAULT_PROFILE entry, got %d", tc.name, n)
+		}
+	}
+}
+
+func TestLoadEnvProfilesValidatesKeys(t *testing.T) {
+	root := t.TempDir()
+	if profiles, err := LoadEnvProfiles(root); err != nil || len(profiles) != 0 {
+		t.Fatalf("expected missing file to mean no env profiles, got %v (%v)", profiles, err)
+	}
+
+	writeEnvProfiles(t, root, `{"dev": {"1BAD": "x"}}`)
+	if _, err := LoadEnvProfiles(root); err == nil || !strings.Contains(err.Error(), "1BAD") {
+		t.Fatalf("expected invalid variable name error, got %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	LockfileName    = "pictl.lock.json"
+	lockfileVersion = 1
+)
+
+type Lockfile struct {
+	Version  int                      `json:"version"`
+	Targets  []Target                 `json:"targets"`
+	Slices   map[string]SliceManifest `json:"slices"`
+	Profiles []Profile                `json:"profiles"`
+}
+
+func BuildLockfile(root string, slices map[string]SliceManifest) (Lockfile, error) {
+	lock := Lockfile{
+		Version:  lockfileVersion,
+		Targets:  CanonicalTargets(),
+		Slices:   make(map[string]SliceManifest, len(slices)),
+		Profiles: CanonicalProfiles(),
+	}
+	roots := ExtensionRoots(root)
+	for _, name := range sortedSliceNames(slices) {
+		manifest := slices[name]
+		refs, err := lockExtensions(roots, manifest)
+		if err != nil {
+			return Lockfile{}, fmt.Errorf("slice %s: %w", name, err)
+		}
+		manifest.Extensions = refs
+		manifest.ExtensionDirs = nil
+		lock.Slices[name] = manifest
+	}
+	return lock, nil
+}
+
+func lockExtensions(roots []string, manifest SliceManifest) ([]ExtensionRef, error) {
+	var refs []ExtensionRef
+	for _, ref := range manifest.Extensions {
+		rel := strings.TrimSpace(ref.Path)
+		if rel == "" {
+			continue
+		}
+		paths, err := resolveExtension(roots, rel)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			refs = append(refs, ExtensionRef{Path: lockPath(roots, path), OS: ref.OS, Arch: ref.Arch})
+		}
+	}
+	for _, rel := range manifest.ExtensionDirs {
+		if rel = strings.TrimSpace(rel); rel == "" {
+			continue
+		}
+		_, paths, err := resolveExtensionDir(roots, rel)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			refs = append(refs, ExtensionRef{Path: lockPath(roots, path)})
+		}
+	}
+	return dedupeExtensionRefs(refs), nil
+}
+
+const lockExtensionPathPrefix = "$PICTL_EXTENSION_PATH/"
+
+func lockPath(roots []string, path string) string {
+	for i, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if i > 0 {
+			return lockExtensionPathPrefix + filepath.ToSlash(rel)
+		}
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+func MarshalLockfile(lock Lockfile) ([]byte, error) {
+	raw, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(raw, '\n'), nil
+}
+
+func LoadLockfile(path string) (Lockfile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Lockfile{}, err
+	}
+	var lock Lockfile
+	if err := json.Unmarshal(raw, &lock); err != nil {
+		return Lockfile{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if lock.Version > lockfileVersion {
+		return Lockfile{}, fmt.Errorf("%s: unsupported lockfile version %d (pictl supports up to %d)", path, lock.Version, lockfileVersion)
+	}
+	return lock, nil
+}
+
+func LockfileDrift(locked Lockfile, current Lockfile) []string {
+	var drift []string
+
+	slices := DiffSlices(locked.Slices, current.Slices)
+	for _, name := range slices.Added {
+		drift = append(drift, fmt.Sprintf("slice %s is not in the lockfile", name))
+	}
+	for _, name := range slices.Removed {
+		drift = append(drift, fmt.Sprintf("slice %s is locked but gone", name))
+	}
+	for _, change := range slices.Changed {
+		var parts []string
+		for _, path := range change.AddedExtensions {
+			parts = append(parts, "+"+path)
+		}
+		for _, path := range change.RemovedExtensions {
+			parts = append(parts, "-"+path)
+		}
+		if change.ExtensionsReordered {
+			parts = append(parts, "extensions reordered")
+		}
+		for _, field := range change.Fields {
+			parts = append(parts, fmt.Sprintf("%s %q -> %q", field.Field, field.From, field.To))
+		}
+		drift = append(drift, fmt.Sprintf("slice %s changed: %s", change.Name, strings.Join(parts, ", ")))
+	}
+
+	lockedTargets, currentTargets := map[string]any{}, map[string]any{}
+	for _, target := range locked.Targets {
+		lockedTargets[target.Name] = target
+	}
+	for _, target := range current.Targets {
+		currentTargets[target.Name] = target
+	}
+	drift = append(drift, namedDrift("target", lockedTargets, currentTargets)...)
+
+	lockedProfiles, currentProfiles := map[string]any{}, map[string]any{}
+	for _, profile := range locked.Profiles {
+		lockedProfiles[profile.Name] = profile
+	}
+	for _, profile := range current.Profiles {
+		currentProfiles[profile.Name] = profile
+	}
+	return append(drift, namedDrift("profile", lockedProfiles, currentProfiles)...)
+}
+
+func namedDrift(kind string, locked map[string]any, current map[string]any) []string {
+	names := map[string]bool{}
+	for name := range locked {
+		names[name] = true
+	}
+	for name := range current {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var drift []string
+	for _, name := range sorted {
+		before, wasLocked := locked[name]
+		after, exists := current[name]
+		switch {
+		case !wasLocked:
+			drift = append(drift, fmt.Sprintf("%s %s is not in the lockfile", kind, name))
+		case !exists:
+			drift = append(drift, fmt.Sprintf("%s %s is locked but gone", kind, name))
+		default:
+			if fields := fieldDrift(before, after); len(fields) > 0 {
+				drift = append(drift, fmt.Sprintf("%s %s changed: %s", kind, name, strings.Join(fields, ", ")))
+			}
+		}
+	}
+	return drift
+}
+
+func fieldDrift(before any, after any) []string {
+	from, to := jsonFields(before), jsonFields(after)
+
+	keys := map[string]bool{}
+	for key := range from {
+		keys[key] = true
+	}
+	for key := range to {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var fields []string
+	for _, key := range sorted {
+		if string(from[key]) != string(to[key]) {
+			fields = append(fields, fmt.Sprintf("%s %s -> %s", key, lockValue(from[key]), lockValue(to[key])))
+		}
+	}
+	return fields
+}
+
+func jsonFields(value any) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	if raw, err := json.Marshal(value); err == nil {
+		_ = json.Unmarshal(raw, &fields)
+	}
+	return fields
+}
+
+func lockValue(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return "(none)"
+	}
+	return string(raw)
+}
+
+func CheckLockfile(path string, root string, slices map[string]SliceManifest) []CheckResult {
+	locked, err := LoadLockfile(path)
+	if err != nil {
+		return []CheckResult{{Name: "lockfile", Status: CheckFail, Message: err.Error()}}
+	}
+	current, err := BuildLockfile(root, slices)
+	if err != nil {
+		return []CheckResult{{Name: "lockfile", Status: CheckFail, Message: fmt.Sprintf("cannot resolve the live catalog: %v", err)}}
+	}
+
+	drift := LockfileDrift(locked, current)
+	if len(drift) == 0 {
+		return []CheckResult{{Name: "lockfile", Status: CheckOK, Message: fmt.Sprintf("matches %s (%d targets, %d slices, %d profiles)", path, len(current.Targets), len(current.Slices), len(current.Profiles))}}
+	}
+	results := make([]CheckResult, 0, len(drift))
+	for _, line := range drift {
+		results = append(results, CheckResult{Name: "lockfile", Status: CheckFail, Message: fmt.Sprintf("%s (review, then pictl lock --write)", line)})
+	}
+	return results
+}
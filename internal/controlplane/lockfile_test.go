@@ -0,0 +1,109 @@
+package controlplane
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildLockfileResolvesExtensions(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/a.ts", "extensions/b.ts", "plugins/c.ts")
+	slices := map[string]SliceManifest{
+		"meta": {DefaultProfile: "fast", Extensions: extensionRefs("extensions/*.ts"), ExtensionDirs: []string{"plugins"}},
+	}
+
+	lock, err := BuildLockfile(root, slices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := extensionPaths(lock.Slices["meta"].Extensions)
+	if strings.Join(got, ",") != "extensions/a.ts,extensions/b.ts,plugins/c.ts" || lock.Slices["meta"].ExtensionDirs != nil {
+		t.Fatalf("expected root-relative resolved extensions, got %v", lock.Slices["meta"])
+	}
+	if len(lock.Targets) == 0 || len(lock.Profiles) == 0 {
+		t.Fatalf("expected the target and profile catalogs, got %+v", lock)
+	}
+
+	slices["meta"] = SliceManifest{Extensions: extensionRefs("extensions/missing.ts")}
+	if _, err := BuildLockfile(root, slices); err == nil || !strings.Contains(err.Error(), "slice meta") {
+		t.Fatalf("expected an unresolvable extension to fail, got %v", err)
+	}
+}
+
+func TestBuildLockfileExtensionPathIsPortable(t *testing.T) {
+	root, extra := t.TempDir(), t.TempDir()
+	writeExtensionFiles(t, root, "extensions/a.ts")
+	writeExtensionFiles(t, extra, "shared/b.ts")
+	t.Setenv("PICTL_EXTENSION_PATH", extra)
+	slices := map[string]SliceManifest{
+		"meta": {DefaultProfile: "fast", Extensions: extensionRefs("extensions/a.ts", "shared/b.ts")},
+	}
+
+	lock, err := BuildLockfile(root, slices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := extensionPaths(lock.Slices["meta"].Extensions)
+	if strings.Join(got, ",") != "extensions/a.ts,$PICTL_EXTENSION_PATH/shared/b.ts" {
+		t.Fatalf("expected PICTL_EXTENSION_PATH extensions relative to their search root, got %v", got)
+	}
+}
+
+func TestLockfileDrift(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/a.ts", "extensions/b.ts")
+	slices := map[string]SliceManifest{"meta": {Extensions: extensionRefs("extensions/a.ts")}}
+	locked, err := BuildLockfile(root, slices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, LockfileName)
+	raw, err := MarshalLockfile(locked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if results := CheckLockfile(path, root, slices); len(results) != 1 || results[0].Status != CheckOK {
+		t.Fatalf("expected a fresh lockfile to match, got %+v", results)
+	}
+
+	slices["meta"] = SliceManifest{Extensions: extensionRefs("extensions/a.ts", "extensions/b.ts")}
+	slices["extra"] = SliceManifest{Extensions: extensionRefs("extensions/a.ts")}
+	results := CheckLockfile(path, root, slices)
+	var messages []string
+	for _, result := range results {
+		if result.Status != CheckFail {
+			t.Fatalf("expected drift to fail, got %+v", result)
+		}
+		messages = append(messages, result.Message)
+	}
+	joined := strings.Join(messages, "\n")
+	for _, want := range []string{"slice extra is not in the lockfile", "slice meta changed: +extensions/b.ts"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected %q in drift:\n%s", want, joined)
+		}
+	}
+
+	locked.Targets[0].DefaultProfile = "fast"
+	locked.Profiles = locked.Profiles[1:]
+	drift := strings.Join(LockfileDrift(locked, mustLockfile(t, root, slices)), "\n")
+	for _, want := range []string{"target " + locked.Targets[0].Name + " changed: defaultProfile \"fast\" -> ", "profile " + CanonicalProfiles()[0].Name + " is not in the lockfile"} {
+		if !strings.Contains(drift, want) {
+			t.Fatalf("expected %q in drift:\n%s", want, drift)
+		}
+	}
+}
+
+func mustLockfile(t *testing.T, root string, slices map[string]SliceManifest) Lockfile {
+	t.Helper()
+	lock, err := BuildLockfile(root, slices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lock
+}
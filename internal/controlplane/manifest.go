@@ -0,0 +1,418 @@
+package controlplane
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const CurrentSchemaVersion = 1
+
+var ErrManifestHasComments = errors.New("manifest contains comments; a canonical rewrite would drop them")
+
+var ErrPruneWouldEmpty = errors.New("every extension is missing; pruning would leave the slice empty")
+
+func NormalizeManifest(raw []byte) ([]byte, error) {
+	clean, hadComments, err := stripJSONC(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(clean))
+	decoder.UseNumber()
+
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	if entries, ok := fields["extensions"].([]any); ok {
+		kept := make([]any, 0, len(entries))
+		for _, entry := range entries {
+			switch value := entry.(type) {
+			case string:
+				value = strings.TrimSpace(value)
+				if value == "" {
+					continue
+				}
+				entry = value
+			case map[string]any:
+				if path, ok := value["path"].(string); ok {
+					path = strings.TrimSpace(path)
+					if path == "" {
+						continue
+					}
+					value["path"] = path
+				}
+			}
+			kept = append(kept, entry)
+		}
+		fields["extensions"] = kept
+	}
+
+	if _, ok := fields["schemaVersion"]; !ok {
+		fields["schemaVersion"] = CurrentSchemaVersion
+	}
+
+	fixed, err := marshalCanonical(fields)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := parseSliceManifest(fixed)
+	if err != nil {
+		return nil, err
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	if hadComments {
+		return nil, ErrManifestHasComments
+	}
+	return fixed, nil
+}
+
+func CheckManifestFormatting(root string, slices map[string]SliceManifest) []CheckResult {
+	files, err := SliceFiles(root)
+	if err != nil {
+		return []CheckResult{{Name: "formatting", Status: CheckFail, Message: err.Error()}}
+	}
+
+	var results []CheckResult
+	checked := 0
+	for _, file := range files {
+		if _, ok := slices[file.Name]; !ok {
+			continue
+		}
+		raw, err := os.ReadFile(file.Path)
+		if err != nil {
+			results = append(results, CheckResult{Name: "formatting", Status: CheckFail, Message: fmt.Sprintf("slice %s: %v", file.Name, err)})
+			continue
+		}
+		checked++
+		canonical, err := NormalizeManifest(raw)
+		switch {
+		case errors.Is(err, ErrManifestHasComments):
+			results = append(results, CheckResult{Name: "formatting", Status: CheckWarn, Message: fmt.Sprintf("%s contains comments, so it cannot be in canonical form", file.Path)})
+		case err != nil:
+			results = append(results, CheckResult{Name: "formatting", Status: CheckFail, Message: fmt.Sprintf("slice %s: %v", file.Name, err)})
+		case !bytes.Equal(raw, canonical):
+			results = append(results, CheckResult{Name: "formatting", Status: CheckWarn, Message: fmt.Sprintf("%s is not in canonical form (pictl doctor --fix --write rewrites it)", file.Path)})
+		}
+	}
+
+	if len(results) == 0 {
+		return []CheckResult{{Name: "formatting", Status: CheckOK, Message: fmt.Sprintf("%d manifest(s) in canonical form", checked)}}
+	}
+	return results
+}
+
+func MissingExtensionRefs(root string, raw []byte) ([]string, error) {
+	manifest, err := parseSliceManifest(raw)
+	if err != nil {
+		return nil, err
+	}
+	roots := ExtensionRoots(root)
+	var missing []string
+	for _, ref := range manifest.Extensions {
+		path := strings.TrimSpace(ref.Path)
+		if path == "" {
+			continue
+		}
+		if _, err := resolveExtension(roots, path); errors.Is(err, ErrExtensionMissing) {
+			missing = append(missing, path)
+		}
+	}
+	return missing, nil
+}
+
+func PruneManifestExtensions(raw []byte, missing []string) ([]byte, error) {
+	clean, hadComments, err := stripJSONC(raw)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(clean))
+	decoder.UseNumber()
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	drop := make(map[string]bool, len(missing))
+	for _, path := range missing {
+		drop[path] = true
+	}
+	entries, _ := fields["extensions"].([]any)
+	kept := make([]any, 0, len(entries))
+	for _, entry := range entries {
+		path, _ := entry.(string)
+		if object, ok := entry.(map[string]any); ok {
+			path, _ = object["path"].(string)
+		}
+		if !drop[strings.TrimSpace(path)] {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) == len(entries) {
+		return raw, nil
+	}
+	fields["extensions"] = kept
+
+	fixed, err := marshalCanonical(fields)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := parseSliceManifest(fixed)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifest.Extensions) == 0 && len(manifest.ExtensionDirs) == 0 && len(manifest.Include) == 0 {
+		return nil, ErrPruneWouldEmpty
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	if hadComments {
+		return nil, ErrManifestHasComments
+	}
+	return fixed, nil
+}
+
+func StampSchemaVersion(raw []byte) ([]byte, error) {
+	clean, _, err := stripJSONC(raw)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(clean, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["schemaVersion"]; ok {
+		return raw, nil
+	}
+	return NormalizeManifest(raw)
+}
+
+func ScaffoldManifest(manifest SliceManifest) ([]byte, error) {
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	manifest.SchemaVersion = CurrentSchemaVersion
+
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		if value == "" || value == nil {
+			delete(fields, key)
+		}
+	}
+	return marshalCanonical(fields)
+}
+
+var (
+	enabledLinePattern   = regexp.MustCompile(`(?m)^[ \t]*"enabled"[ \t]*:[ \t]*(true|false)[ \t]*,[ \t]*\r?\n`)
+	enabledInlinePattern = regexp.MustCompile(`"enabled"[ \t]*:[ \t]*false[ \t]*,[ \t]*`)
+	enabledValuePattern  = regexp.MustCompile(`("enabled"\s*:\s*)(true|false)`)
+)
+
+func SetManifestEnabled(raw []byte, enabled bool) ([]byte, error) {
+	var out []byte
+	switch {
+	case enabled && enabledLinePattern.Match(raw):
+		out = enabledLinePattern.ReplaceAll(raw, nil)
+	case enabled && enabledInlinePattern.Match(raw):
+		out = enabledInlinePattern.ReplaceAll(raw, nil)
+	case enabledValuePattern.Match(raw):
+		out = enabledValuePattern.ReplaceAll(raw, []byte("${1}"+strconv.FormatBool(enabled)))
+	case enabled:
+		out = raw
+	default:
+		out = insertEnabledFalse(raw)
+	}
+
+	manifest, err := parseSliceManifest(out)
+	if err != nil {
+		return nil, fmt.Errorf("rewrite enabled flag: %w", err)
+	}
+	if manifest.IsEnabled() != enabled {
+		return nil, fmt.Errorf("rewrite enabled flag: manifest still has enabled=%t", manifest.IsEnabled())
+	}
+	return out, nil
+}
+
+func insertEnabledFalse(raw []byte) []byte {
+	open := bytes.IndexByte(raw, '{')
+	if open < 0 {
+		return raw
+	}
+	rest := raw[open+1:]
+	member := `"enabled": false, `
+	if newline := bytes.IndexByte(rest, '\n'); newline >= 0 && len(bytes.TrimSpace(rest[:newline])) == 0 {
+		next := rest[newline+1:]
+		indent := next[:len(next)-len(bytes.TrimLeft(next, " \t"))]
+		member = "\n" + string(indent) + `"enabled": false,`
+	}
+
+	out := make([]byte, 0, len(raw)+len(member))
+	out = append(out, raw[:open+1]...)
+	out = append(out, member...)
+	return append(out, rest...)
+}
+
+func marshalCanonical(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("encode manifest: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func stripJSONC(raw []byte) ([]byte, bool, error) {
+	out := make([]byte, 0, len(raw))
+	hadComments := false
+	inString := false
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(raw) {
+				i++
+				out = append(out, raw[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(raw) && raw[i+1] == '/':
+			hadComments = true
+			for i < len(raw) && raw[i] != '\n' {
+				i++
+			}
+			if i < len(raw) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(raw) && raw[i+1] == '*':
+			hadComments = true
+			start := i
+			i += 2
+			for i < len(raw) && !(raw[i] == '*' && i+1 < len(raw) && raw[i+1] == '/') {
+				if raw[i] == '\n' {
+					out = append(out, '\n')
+				}
+				i++
+			}
+			if i >= len(raw) {
+				return nil, hadComments, fmt.Errorf("unterminated block comment at byte %d", start)
+			}
+			i++
+			out = append(out, ' ')
+		default:
+			out = append(out, c)
+		}
+	}
+
+	return dropTrailingCommas(out), hadComments, nil
+}
+
+func dropTrailingCommas(raw []byte) []byte {
+	out := make([]byte, 0, len(raw))
+	inString := false
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(raw) {
+				i++
+				out = append(out, raw[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		if c == '"' {
+			inString = true
+		}
+		if c == ',' {
+			next := i + 1
+			for next < len(raw) && strings.ContainsRune(" \t\r\n", rune(raw[next])) {
+				next++
+			}
+			if next < len(raw) && (raw[next] == '}' || raw[next] == ']') {
+				continue
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+type ExtensionRef struct {
+	Path string   `json:"path"`
+	OS   []string `json:"os,omitempty"`
+	Arch []string `json:"arch,omitempty"`
+}
+
+func (r *ExtensionRef) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		*r = ExtensionRef{Path: path}
+		return nil
+	}
+
+	type plain ExtensionRef
+	var ref plain
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ref); err != nil {
+		return fmt.Errorf(`extension entry must be a path string or an object like {"path": "...", "os": ["darwin"]}: %w`, err)
+	}
+	if strings.TrimSpace(ref.Path) == "" {
+		return fmt.Errorf("extension entry %s has an empty path", data)
+	}
+	*r = ExtensionRef(ref)
+	return nil
+}
+
+func (r ExtensionRef) MarshalJSON() ([]byte, error) {
+	if len(r.OS) == 0 && len(r.Arch) == 0 {
+		return json.Marshal(r.Path)
+	}
+
+	type plain ExtensionRef
+	return json.Marshal(plain(r))
+}
+
+func (r ExtensionRef) Matches(goos string, goarch string) bool {
+	return matchesConstraint(r.OS, goos) && matchesConstraint(r.Arch, goarch)
+}
+
+func matchesConstraint(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, candidate := range allowed {
+		if strings.EqualFold(strings.TrimSpace(candidate), value) {
+			return true
+		}
+	}
+	return false
+}
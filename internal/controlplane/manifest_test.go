@@ -0,0 +1,368 @@
+package controlplane
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeManifest(t *testing.T) {
+	raw := []byte(`{"extensions": [" extensions/x.ts ", "", "extensions/y.ts"], "description": "demo", "defaultProfile": "fast"}`)
+
+	fixed, err := NormalizeManifest(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{
+  "defaultProfile": "fast",
+  "description": "demo",
+  "extensions": [
+    "extensions/x.ts",
+    "extensions/y.ts"
+  ],
+  "schemaVersion": 1
+}
+`
+	if string(fixed) != want {
+		t.Fatalf("unexpected canonical form:\n%s", fixed)
+	}
+
+	again, err := NormalizeManifest(fixed)
+	if err != nil {
+		t.Fatalf("unexpected error on second pass: %v", err)
+	}
+	if string(again) != string(fixed) {
+		t.Fatalf("expected normalization to be idempotent:\n%s", again)
+	}
+}
+
+func TestNormalizeManifestRefusesBrokenInput(t *testing.T) {
+	cases := map[string]string{
+		"invalid json":     `{"extensions": [`,
+		"empty extensions": `{"extensions": ["  ", ""]}`,
+	}
+
+	for name, raw := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NormalizeManifest([]byte(raw)); err == nil {
+				t.Fatalf("expected error for %s", name)
+			}
+		})
+	}
+}
+
+func TestNormalizeManifestKeepsUnknownFields(t *testing.T) {
+	fixed, err := NormalizeManifest([]byte(`{"extensions": ["extensions/x.ts"], "owner": "team-a", "schemaVersion": 1}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(fixed), `"owner": "team-a"`) {
+		t.Fatalf("expected unknown field to survive normalization:\n%s", fixed)
+	}
+}
+
+func TestCheckManifestFormatting(t *testing.T) {
+	root := t.TempDir()
+	writeRootMarkers(t, root)
+	canonical, err := NormalizeManifest([]byte(`{"extensions": ["extensions/a.ts"], "description": "tidy"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeSliceFiles(t, root, map[string]string{
+		"tidy.json":  string(canonical),
+		"messy.json": `{"schemaVersion": 1, "extensions": ["extensions/a.ts"], "description": "messy"}`,
+	})
+	slices, err := LoadSlices(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := CheckManifestFormatting(root, slices)
+	if len(results) != 1 || results[0].Status != CheckWarn || !strings.Contains(results[0].Message, "messy.json is not in canonical form") {
+		t.Fatalf("expected only messy.json to be flagged, got %+v", results)
+	}
+
+	delete(slices, "messy")
+	results = CheckManifestFormatting(root, slices)
+	if len(results) != 1 || results[0].Status != CheckOK || results[0].Message != "1 manifest(s) in canonical form" {
+		t.Fatalf("expected the canonical manifest to pass, got %+v", results)
+	}
+}
+
+func TestParseSliceManifestJSONC(t *testing.T) {
+	commented := []byte(`{
+  // Why this slice exists.
+  "description": "docs live at https://example.com/a//b", /* inline */
+  "defaultProfile": "fast",
+  /* multi-line
+     rationale */
+  "extensions": [
+    "extensions/x.ts", // trailing comment
+    "extensions/y.ts",
+  ],
+}
+`)
+	clean := []byte(`{"description": "docs live at https://example.com/a//b", "defaultProfile": "fast", "extensions": ["extensions/x.ts", "extensions/y.ts"]}`)
+
+	got, err := parseSliceManifest(commented)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := parseSliceManifest(clean)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Description != want.Description || got.DefaultProfile != want.DefaultProfile || !reflect.DeepEqual(got.Extensions, want.Extensions) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestStripJSONCPreservesStrings(t *testing.T) {
+	raw := []byte(`{"a": "x // y /* z */ \"q,]\"", "b": [1,],}`)
+
+	clean, hadComments, err := stripJSONC(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hadComments {
+		t.Fatalf("did not expect comments to be detected inside strings")
+	}
+	if string(clean) != `{"a": "x // y /* z */ \"q,]\"", "b": [1]}` {
+		t.Fatalf("unexpected output: %s", clean)
+	}
+}
+
+func TestNormalizeManifestRefusesCommentedManifest(t *testing.T) {
+	raw := []byte("{\n  // keep me\n  \"extensions\": [\"extensions/x.ts\"]\n}\n")
+	if _, err := NormalizeManifest(raw); !errors.Is(err, ErrManifestHasComments) {
+		t.Fatalf("expected comment refusal, got %v", err)
+	}
+}
+
+func TestParseSliceManifestRejectsUnterminatedBlockComment(t *testing.T) {
+	raw := []byte(`{"extensions": ["extensions/x.ts"]} /* unterminated`)
+	if _, err := parseSliceManifest(raw); err == nil || !strings.Contains(err.Error(), "unterminated block comment") {
+		t.Fatalf("expected unterminated comment error, got %v", err)
+	}
+}
+
+func TestExtensionRefUnmarshalMixedEntries(t *testing.T) {
+	manifest, err := parseSliceManifest([]byte(`{"extensions": ["extensions/x.ts", {"path": "extensions/mac.ts", "os": ["darwin"], "arch": ["arm64"]}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []ExtensionRef{
+		{Path: "extensions/x.ts"},
+		{Path: "extensions/mac.ts", OS: []string{"darwin"}, Arch: []string{"arm64"}},
+	}
+	if !reflect.DeepEqual(manifest.Extensions, want) {
+		t.Fatalf("expected %+v, got %+v", want, manifest.Extensions)
+	}
+
+	if _, err := parseSliceManifest([]byte(`{"extensions": [42]}`)); err == nil {
+		t.Fatalf("expected error for non-string, non-object entry")
+	}
+}
+
+func TestBuildLaunchSpecPlatformConstraints(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts", "extensions/here.ts", "extensions/elsewhere.ts")
+
+	other := "plan9"
+	if runtime.GOOS == other {
+		other = "linux"
+	}
+
+	manifest := SliceManifest{Extensions: []ExtensionRef{
+		{Path: "extensions/x.ts"},
+		{Path: "extensions/here.ts", OS: []string{runtime.GOOS}, Arch: []string{runtime.GOARCH}},
+		{Path: "extensions/elsewhere.ts", OS: []string{other}},
+	}}
+
+	spec, err := BuildLaunchSpec(root, manifest, false, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if countArg(spec.Args, "-e") != 2 {
+		t.Fatalf("expected matching constraint to load and non-matching to skip, got %v", spec.Args)
+	}
+	for _, arg := range spec.Args {
+		if strings.HasSuffix(arg, "elsewhere.ts") {
+			t.Fatalf("did not expect non-matching extension in args: %v", spec.Args)
+		}
+	}
+
+	onlyOther := SliceManifest{Extensions: []ExtensionRef{{Path: "extensions/elsewhere.ts", OS: []string{other}}}}
+	if _, err := BuildLaunchSpec(root, onlyOther, false, "", nil); err == nil || !strings.Contains(err.Error(), "no extensions configured") {
+		t.Fatalf("expected no-extensions error when every entry is filtered, got %v", err)
+	}
+}
+
+func TestExtensionRefRejectsUnknownKeysAndEmptyPath(t *testing.T) {
+	for _, raw := range []string{
+		`{"extensions": [{"paht": "extensions/x.ts", "oss": ["darwin"]}]}`,
+		`{"extensions": [{"path": "extensions/x.ts", "oss": ["darwin"]}]}`,
+		`{"extensions": [{"path": "  ", "os": ["darwin"]}]}`,
+	} {
+		if _, err := parseSliceManifest([]byte(raw)); err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestNormalizeManifestTrimsObjectEntries(t *testing.T) {
+	raw := []byte(`{"extensions": [{"path": " extensions/mac.ts ", "os": ["darwin"]}, {"path": " ", "os": ["linux"]}, "extensions/x.ts"]}`)
+
+	got, err := NormalizeManifest(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manifest, err := parseSliceManifest(got)
+	if err != nil {
+		t.Fatalf("normalized manifest does not parse: %v", err)
+	}
+	want := []ExtensionRef{{Path: "extensions/mac.ts", OS: []string{"darwin"}}, {Path: "extensions/x.ts"}}
+	if !reflect.DeepEqual(manifest.Extensions, want) {
+		t.Fatalf("expected %+v, got %+v", want, manifest.Extensions)
+	}
+}
+
+func TestSliceManifestValidate(t *testing.T) {
+	ext := extensionRefs("extensions/x.ts")
+	cases := []struct {
+		name     string
+		manifest SliceManifest
+		wantErr  string
+	}{
+		{"valid", SliceManifest{DefaultProfile: "meta", Extensions: ext}, ""},
+		{"no profile", SliceManifest{Extensions: ext}, ""},
+		{"empty extensions", SliceManifest{DefaultProfile: "fast"}, "extensions must not be empty"},
+		{"unknown default profile", SliceManifest{DefaultProfile: "turbo", Extensions: ext}, `unknown defaultProfile "turbo"`},
+		{"unknown allowed profile", SliceManifest{AllowedProfiles: []string{"fast", "turbo"}, Extensions: ext}, `unknown profile "turbo"`},
+		{"default outside allowed", SliceManifest{DefaultProfile: "execute", AllowedProfiles: []string{"fast"}, Extensions: ext}, "not in allowedProfiles"},
+		{"alias inside allowed", SliceManifest{DefaultProfile: "quick", AllowedProfiles: []string{"fast"}, Extensions: ext}, ""},
+	}
+	for _, tc := range cases {
+		err := tc.manifest.Validate()
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+func TestSetManifestEnabledPreservesFormatting(t *testing.T) {
+	cases := map[string]struct {
+		raw      string
+		disabled string
+	}{
+		"indented": {
+			raw:      "{\n    \"description\": \"x\",\n    \"extensions\": [\"extensions/x.ts\"]\n}\n",
+			disabled: "{\n    \"enabled\": false,\n    \"description\": \"x\",\n    \"extensions\": [\"extensions/x.ts\"]\n}\n",
+		},
+		"one line": {
+			raw:      `{"extensions": ["extensions/x.ts"]}`,
+			disabled: `{"enabled": false, "extensions": ["extensions/x.ts"]}`,
+		},
+	}
+	for name, tc := range cases {
+		disabled, err := SetManifestEnabled([]byte(tc.raw), false)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if string(disabled) != tc.disabled {
+			t.Fatalf("%s: expected %q, got %q", name, tc.disabled, disabled)
+		}
+		enabled, err := SetManifestEnabled(disabled, true)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if string(enabled) != tc.raw {
+			t.Fatalf("%s: expected round trip to %q, got %q", name, tc.raw, enabled)
+		}
+	}
+
+	explicit := "{\n  \"enabled\": true,\n  \"extensions\": [\"extensions/x.ts\"]\n}\n"
+	got, err := SetManifestEnabled([]byte(explicit), false)
+	if err != nil || string(got) != strings.Replace(explicit, "true", "false", 1) {
+		t.Fatalf("expected in-place toggle, got %q (%v)", got, err)
+	}
+}
+
+func TestPruneManifestExtensions(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	raw := []byte(`{"description": "d", "extensions": ["extensions/x.ts", "extensions/gone.ts", {"path": "extensions/old.ts", "os": ["linux"]}]}`)
+
+	missing, err := MissingExtensionRefs(root, raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(missing, ",") != "extensions/gone.ts,extensions/old.ts" {
+		t.Fatalf("unexpected missing entries %v", missing)
+	}
+
+	pruned, err := PruneManifestExtensions(raw, missing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manifest, err := parseSliceManifest(pruned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Extensions) != 1 || manifest.Extensions[0].Path != "extensions/x.ts" || manifest.Description != "d" {
+		t.Fatalf("expected only the existing extension to remain, got %+v", manifest)
+	}
+
+	if same, err := PruneManifestExtensions(raw, nil); err != nil || string(same) != string(raw) {
+		t.Fatalf("expected nothing to prune to return the input, got %q, %v", same, err)
+	}
+}
+
+func TestPruneManifestExtensionsRefusesToEmpty(t *testing.T) {
+	raw := []byte(`{"extensions": ["extensions/gone.ts"]}`)
+	if _, err := PruneManifestExtensions(raw, []string{"extensions/gone.ts"}); !errors.Is(err, ErrPruneWouldEmpty) {
+		t.Fatalf("expected ErrPruneWouldEmpty, got %v", err)
+	}
+
+	withInclude := []byte(`{"include": ["base"], "extensions": ["extensions/gone.ts"]}`)
+	if _, err := PruneManifestExtensions(withInclude, []string{"extensions/gone.ts"}); err != nil {
+		t.Fatalf("expected a slice with includes to keep loading, got %v", err)
+	}
+
+	commented := []byte("{\n  // note\n  \"extensions\": [\"extensions/x.ts\", \"extensions/gone.ts\"]\n}\n")
+	if _, err := PruneManifestExtensions(commented, []string{"extensions/gone.ts"}); !errors.Is(err, ErrManifestHasComments) {
+		t.Fatalf("expected ErrManifestHasComments, got %v", err)
+	}
+}
+
+func TestStampSchemaVersion(t *testing.T) {
+	raw := []byte(`{"extensions": ["extensions/x.ts"], "description": "d"}`)
+	stamped, err := StampSchemaVersion(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"description\": \"d\",\n  \"extensions\": [\n    \"extensions/x.ts\"\n  ],\n  \"schemaVersion\": 1\n}\n"
+	if string(stamped) != want {
+		t.Fatalf("expected the canonical manifest with schemaVersion, got:\n%s", stamped)
+	}
+
+	already := []byte(`{"schemaVersion": 1,   "extensions": ["extensions/x.ts"]}`)
+	if same, err := StampSchemaVersion(already); err != nil || string(same) != string(already) {
+		t.Fatalf("expected a stamped manifest to be returned untouched, got %q, %v", same, err)
+	}
+
+	if _, err := StampSchemaVersion([]byte("{\n  // notes\n  \"extensions\": [\"extensions/x.ts\"]\n}")); !errors.Is(err, ErrManifestHasComments) {
+		t.Fatalf("expected a commented manifest to be refused, got %v", err)
+	}
+}
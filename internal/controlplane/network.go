@@ -0,0 +1,70 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const ProviderURLEnv = "PICTL_PROVIDER_URL"
+
+var providerURLEnvs = []string{ProviderURLEnv, "ANTHROPIC_BASE_URL", "OPENAI_BASE_URL"}
+
+func ProviderURL(env []string) (string, string) {
+	for _, key := range providerURLEnvs {
+		if value, _ := lookupEnv(env, key); strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value), key
+		}
+	}
+	return "", ""
+}
+
+func CheckNetwork(env []string, timeout time.Duration) CheckResult {
+	endpoint, source := ProviderURL(env)
+	if endpoint == "" {
+		return CheckResult{Name: "network", Status: CheckWarn, Message: fmt.Sprintf("no provider URL to check; set %s (or %s)", ProviderURLEnv, strings.Join(providerURLEnvs[1:], "/"))}
+	}
+	return CheckProviderReachable(endpoint, source, timeout)
+}
+
+func CheckProviderReachable(endpoint string, source string, timeout time.Duration) CheckResult {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return CheckResult{Name: "network", Status: CheckFail, Message: fmt.Sprintf("%s=%s is not an http(s) URL", source, endpoint)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+	if err != nil {
+		return CheckResult{Name: "network", Status: CheckFail, Message: fmt.Sprintf("%s: %v", endpoint, err)}
+	}
+
+	start := time.Now()
+	response, err := http.DefaultClient.Do(request)
+	latency := time.Since(start).Round(time.Millisecond)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return CheckResult{Name: "network", Status: CheckFail, Message: fmt.Sprintf("%s unreachable: no response within %s (from %s)", endpoint, timeout, source)}
+	}
+	if err != nil {
+		return CheckResult{Name: "network", Status: CheckFail, Message: fmt.Sprintf("%s unreachable: %v (from %s)", endpoint, unwrapURLError(err), source)}
+	}
+	response.Body.Close()
+
+	if response.StatusCode >= 500 {
+		return CheckResult{Name: "network", Status: CheckWarn, Message: fmt.Sprintf("%s reachable in %s but answered %s (from %s)", endpoint, latency, response.Status, source)}
+	}
+	return CheckResult{Name: "network", Status: CheckOK, Message: fmt.Sprintf("%s reachable in %s (HTTP %d, from %s)", endpoint, latency, response.StatusCode, source)}
+}
+
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package controlplane
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckNetworkReachable(t *testing.T) {
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	result := CheckNetwork([]string{"ANTHROPIC_BASE_URL=" + server.URL}, time.Second)
+	if result.Status != CheckOK || !strings.Contains(result.Message, server.URL+" reachable in ") || !strings.Contains(result.Message, "HTTP 404, from ANTHROPIC_BASE_URL") {
+		t.Fatalf("expected the server to be reachable, got %+v", result)
+	}
+	if method != http.MethodHead {
+		t.Fatalf("expected a HEAD request, got %s", method)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	env := []string{"ANTHROPIC_BASE_URL=" + server.URL, ProviderURLEnv + "=" + failing.URL}
+	if result := CheckNetwork(env, time.Second); result.Status != CheckWarn || !strings.Contains(result.Message, "503 Service Unavailable (from "+ProviderURLEnv+")") {
+		t.Fatalf("expected %s to win and a 503 to warn, got %+v", ProviderURLEnv, result)
+	}
+}
+
+func TestCheckNetworkUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	closed := server.URL
+	server.Close()
+
+	if result := CheckNetwork([]string{ProviderURLEnv + "=" + closed}, time.Second); result.Status != CheckFail || !strings.Contains(result.Message, closed+" unreachable") {
+		t.Fatalf("expected a closed server to be unreachable, got %+v", result)
+	}
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-release }))
+	defer slow.Close()
+	defer close(release)
+	if result := CheckNetwork([]string{ProviderURLEnv + "=" + slow.URL}, 50*time.Millisecond); result.Status != CheckFail || !strings.Contains(result.Message, "no response within 50ms") {
+		t.Fatalf("expected a timeout, got %+v", result)
+	}
+
+	if result := CheckNetwork([]string{ProviderURLEnv + "=api.example.com"}, time.Second); result.Status != CheckFail || !strings.Contains(result.Message, "not an http(s) URL") {
+		t.Fatalf("expected a bad URL to fail, got %+v", result)
+	}
+	if result := CheckNetwork(nil, time.Second); result.Status != CheckWarn || !strings.Contains(result.Message, "no provider URL") {
+		t.Fatalf("expected a warning without a provider URL, got %+v", result)
+	}
+}
@@ -0,0 +1,71 @@
+package controlplane
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func caseInsensitiveReadDir(files fstest.MapFS) func(string) ([]fs.DirEntry, error) {
+	return func(dir string) ([]fs.DirEntry, error) {
+		return fs.ReadDir(files, strings.ToLower(dir))
+	}
+}
+
+func TestPathCaseMismatch(t *testing.T) {
+	readDir := caseInsensitiveReadDir(fstest.MapFS{"extensions/foo/index.ts": {Data: []byte("x")}})
+
+	cases := map[string]string{
+		"extensions/foo/index.ts": "",
+		"Extensions/foo/index.ts": `"Extensions" is "extensions" on disk`,
+		"extensions/Foo/index.ts": `"Foo" is "foo" on disk`,
+		"extensions/foo/INDEX.ts": `"INDEX.ts" is "index.ts" on disk`,
+	}
+	for path, want := range cases {
+		if got := pathCaseMismatch(readDir, path, pathSegments(path)); got != want {
+			t.Fatalf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+
+	if got := pathCaseMismatch(readDir, "Extensions/foo/index.ts", 2); got != "" {
+		t.Fatalf("expected segments outside the reference to be ignored, got %q", got)
+	}
+}
+
+func TestResolveExtensionsCaseMismatch(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/foo.ts")
+	manifest := SliceManifest{Extensions: extensionRefs("extensions/foo.ts")}
+
+	resolved, err := ResolveExtensions(root, manifest, LaunchOptions{StrictPaths: true})
+	if err != nil || len(resolved.Warnings) != 0 {
+		t.Fatalf("expected matching case to pass, got %v (%v)", resolved.Warnings, err)
+	}
+
+	prev := caseCheckReadDir
+	t.Cleanup(func() { caseCheckReadDir = prev })
+	caseCheckReadDir = func(dir string) ([]fs.DirEntry, error) {
+		entries, err := os.ReadDir(dir)
+		if filepath.Base(dir) != "extensions" {
+			return entries, err
+		}
+		return fs.ReadDir(fstest.MapFS{"Foo.ts": {Data: []byte("x")}}, ".")
+	}
+
+	resolved, err = ResolveExtensions(root, manifest, LaunchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resolved.Warnings) != 1 || !strings.Contains(resolved.Warnings[0], `"foo.ts" is "Foo.ts" on disk`) {
+		t.Fatalf("expected case mismatch warning, got %v", resolved.Warnings)
+	}
+
+	_, err = ResolveExtensions(root, manifest, LaunchOptions{StrictPaths: true})
+	if !errors.Is(err, ErrInvalidExtension) || !strings.Contains(err.Error(), "case-sensitive") {
+		t.Fatalf("expected strict paths to fail, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package controlplane
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func CheckPermissions(root string, slices map[string]SliceManifest, configFiles []string) []CheckResult {
+	seen := map[string]bool{}
+	var results []CheckResult
+	checked := 0
+	check := func(path string, config bool) {
+		if path == "" || seen[path] {
+			return
+		}
+		seen[path] = true
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) && config {
+			return
+		}
+		checked++
+		if problem := permissionProblem(path, info, err, config); problem != "" {
+			results = append(results, CheckResult{Name: "permissions", Status: CheckWarn, Message: path + ": " + problem})
+		}
+	}
+
+	if files, err := SliceFiles(root); err == nil {
+		for _, file := range files {
+			check(file.Path, false)
+		}
+	}
+	roots := ExtensionRoots(root)
+	for _, name := range sortedSliceNames(slices) {
+		for _, ref := range slices[name].Extensions {
+			paths, err := resolveExtension(roots, strings.TrimSpace(ref.Path))
+			if err != nil {
+				continue
+			}
+			for _, path := range paths {
+				check(path, false)
+			}
+		}
+	}
+	for _, path := range append([]string{
+		filepath.Join(root, DotenvFile),
+		filepath.Join(root, EnvProfilesFile),
+		DefaultProfileCatalogPath(root),
+	}, configFiles...) {
+		check(path, true)
+	}
+
+	if len(results) == 0 {
+		return []CheckResult{{Name: "permissions", Status: CheckOK, Message: fmt.Sprintf("%d file(s) readable and not world-writable", checked)}}
+	}
+	return results
+}
+
+func permissionProblem(path string, info os.FileInfo, statErr error, config bool) string {
+	if statErr != nil {
+		return statErr.Error()
+	}
+	mode := info.Mode().Perm()
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Sprintf("mode %04o is not readable by you (%v)", mode, err)
+	}
+	file.Close()
+
+	switch {
+	case mode&0o002 != 0:
+		return fmt.Sprintf("mode %04o is world-writable", mode)
+	case config && mode&0o020 != 0:
+		return fmt.Sprintf("mode %04o is group-writable; pictl state and config should be 0644 or tighter", mode)
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package controlplane
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func chmod(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckPermissionsFlagsWritableFiles(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts", "extensions/open.ts")
+	writeSliceFiles(t, root, map[string]string{"meta.json": `{"extensions": ["extensions/x.ts", "extensions/open.ts"]}`})
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(statePath, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	slices, err := LoadSlices(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := CheckPermissions(root, slices, []string{statePath, filepath.Join(root, "missing.json")})
+	if len(results) != 1 || results[0].Status != CheckOK || !strings.Contains(results[0].Message, "4 file(s)") {
+		t.Fatalf("expected a single ok result over 4 files, got %+v", results)
+	}
+
+	chmod(t, filepath.Join(root, "extensions", "open.ts"), 0o666)
+	chmod(t, filepath.Join(root, "slices", "meta.json"), 0o646)
+	chmod(t, statePath, 0o664)
+	results = CheckPermissions(root, slices, []string{statePath})
+	if len(results) != 3 {
+		t.Fatalf("expected three warnings, got %+v", results)
+	}
+	for _, want := range []string{"meta.json: mode 0646 is world-writable", "open.ts: mode 0666 is world-writable", "state.json: mode 0664 is group-writable"} {
+		found := false
+		for _, result := range results {
+			if result.Status == CheckWarn && strings.Contains(result.Message, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("expected a warning containing %q, got %+v", want, results)
+		}
+	}
+}
+
+func TestCheckPermissionsFlagsUnreadableFiles(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any file")
+	}
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	writeSliceFiles(t, root, map[string]string{"meta.json": `{"extensions": ["extensions/x.ts"]}`})
+	slices, err := LoadSlices(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, "extensions", "x.ts")
+	chmod(t, path, 0o200)
+	t.Cleanup(func() { os.Chmod(path, 0o644) })
+
+	results := CheckPermissions(root, slices, nil)
+	if len(results) != 1 || results[0].Status != CheckWarn || !strings.Contains(results[0].Message, "mode 0200 is not readable by you") {
+		t.Fatalf("expected an unreadable warning, got %+v", results)
+	}
+}
@@ -0,0 +1,95 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const ProfileCatalogFile = "profiles.json"
+
+var ErrUnknownProfile = errors.New("unknown profile")
+
+var builtinProfiles = CanonicalProfiles()
+
+var profileCatalogPath string
+
+func DefaultProfileCatalogPath(root string) string {
+	return filepath.Join(root, ProfileCatalogFile)
+}
+
+func LoadProfileCatalog(path string) ([]Profile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []Profile
+	if err := json.Unmarshal(raw, &profiles); err != nil {
+		return nil, fmt.Errorf("profile catalog %s: %w", path, err)
+	}
+	if err := ValidateProfiles(profiles); err != nil {
+		return nil, fmt.Errorf("profile catalog %s: %w", path, err)
+	}
+	return profiles, nil
+}
+
+func ValidateProfiles(profiles []Profile) error {
+	if len(profiles) == 0 {
+		return errors.New("no profiles defined")
+	}
+
+	owners := make(map[string]string)
+	var problems []string
+	for _, profile := range profiles {
+		for _, key := range append([]string{profile.Name}, profile.Aliases...) {
+			key = normalizeAlias(key)
+			if key == "" {
+				problems = append(problems, fmt.Sprintf("profile %q has an empty name or alias", profile.Name))
+				continue
+			}
+			if owner, taken := owners[key]; taken && owner != profile.Name {
+				problems = append(problems, fmt.Sprintf("%q is claimed by both %q and %q", key, owner, profile.Name))
+				continue
+			}
+			owners[key] = profile.Name
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("profile alias collision: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func UseProfileCatalog(path string, profiles []Profile) {
+	if len(profiles) == 0 {
+		path, profiles = "", builtinProfiles
+	}
+	profileCatalogPath = path
+	canonicalProfiles = append([]Profile(nil), profiles...)
+	aliasToProfile = buildProfileAliasMap(canonicalProfiles)
+}
+
+func ProfileCatalogPath() string {
+	return profileCatalogPath
+}
+
+func CheckProfileKnown(profile string) error {
+	profile = strings.TrimSpace(profile)
+	if profileCatalogPath == "" || profile == "" {
+		return nil
+	}
+	if _, ok := ResolveProfile(profile); ok {
+		return nil
+	}
+	names := make([]string, 0, len(canonicalProfiles))
+	for _, known := range canonicalProfiles {
+		names = append(names, known.Name)
+	}
+	return fmt.Errorf("%w %q (known in %s: %s)", ErrUnknownProfile, profile, profileCatalogPath, strings.Join(names, ", "))
+}
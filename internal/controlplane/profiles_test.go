@@ -0,0 +1,77 @@
+package controlplane
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func useTestProfileCatalog(t *testing.T, profiles []Profile) {
+	t.Helper()
+	UseProfileCatalog("profiles.json", profiles)
+	t.Cleanup(func() { UseProfileCatalog("", nil) })
+}
+
+func TestLoadProfileCatalog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	write := func(body string) {
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`[{"name": "research", "aliases": ["dig"], "description": "Read-heavy"}, {"name": "fast", "aliases": ["quick"]}]`)
+	profiles, err := LoadProfileCatalog(path)
+	if err != nil || len(profiles) != 2 || profiles[0].Name != "research" {
+		t.Fatalf("unexpected catalog %+v (%v)", profiles, err)
+	}
+
+	cases := map[string]string{
+		"collision": `[{"name": "a", "aliases": ["x"]}, {"name": "b", "aliases": ["X"]}]`,
+		"empty":     `[]`,
+		"no name":   `[{"aliases": ["x"]}]`,
+		"not json":  `{`,
+	}
+	for name, body := range cases {
+		write(body)
+		if _, err := LoadProfileCatalog(path); err == nil || !strings.Contains(err.Error(), path) {
+			t.Fatalf("%s: expected catalog error naming the file, got %v", name, err)
+		}
+	}
+}
+
+func TestBuildLaunchSpecProfileCatalog(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts")
+	unsetProfileEnv(t)
+	manifest := SliceManifest{Extensions: extensionRefs("extensions/x.ts")}
+
+	if _, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{Profile: "turbo"}); err != nil {
+		t.Fatalf("expected pass-through without a catalog, got %v", err)
+	}
+
+	useTestProfileCatalog(t, []Profile{{Name: "research", Aliases: []string{"dig"}}})
+	if _, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{Profile: "dig"}); err != nil {
+		t.Fatalf("expected catalog alias to be accepted, got %v", err)
+	}
+	for _, opts := range []LaunchOptions{{Profile: "turbo"}, {ForwardedArgs: []string{"--profile", "turbo"}}} {
+		_, err := BuildLaunchSpecWithOptions(root, manifest, opts)
+		if !errors.Is(err, ErrUnknownProfile) || !strings.Contains(err.Error(), "research") {
+			t.Fatalf("expected unknown profile error listing the catalog, got %v", err)
+		}
+	}
+	t.Setenv("PI_DEFAULT_PROFILE", "turbo")
+	if _, err := BuildLaunchSpecWithOptions(root, manifest, LaunchOptions{}); !errors.Is(err, ErrUnknownProfile) {
+		t.Fatalf("expected inherited unknown profile to be rejected, got %v", err)
+	}
+
+	if _, ok := ResolveProfile("meta"); ok {
+		t.Fatalf("expected the catalog to replace the built-in profiles")
+	}
+	UseProfileCatalog("", nil)
+	if _, ok := ResolveProfile("meta"); !ok {
+		t.Fatalf("expected built-in profiles to be restored")
+	}
+}
@@ -0,0 +1,62 @@
+package controlplane
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var ErrMissingRequirements = errors.New("missing required tools")
+
+func MissingRequirements(manifest SliceManifest) []string {
+	var missing []string
+	for _, tool := range manifest.Requires {
+		if tool = strings.TrimSpace(tool); tool == "" {
+			continue
+		}
+		if _, err := exec.LookPath(tool); err != nil {
+			missing = append(missing, tool)
+		}
+	}
+	return missing
+}
+
+func CheckRequirements(manifest SliceManifest) error {
+	if missing := MissingRequirements(manifest); len(missing) > 0 {
+		return fmt.Errorf("%w: %s not found on PATH (install them or pass --skip-requires)", ErrMissingRequirements, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func CheckSliceRequirements(slices map[string]SliceManifest) []CheckResult {
+	var results []CheckResult
+	checked := 0
+	for _, name := range sortedSliceNames(slices) {
+		if len(slices[name].Requires) == 0 {
+			continue
+		}
+		checked++
+		if missing := MissingRequirements(slices[name]); len(missing) > 0 {
+			results = append(results, CheckResult{Name: "requires", Status: CheckWarn, Message: fmt.Sprintf("slice %s: %s not found on PATH", name, strings.Join(missing, ", "))})
+		}
+	}
+	if len(results) == 0 {
+		return []CheckResult{{Name: "requires", Status: CheckOK, Message: fmt.Sprintf("required tools found for %d slice(s)", checked)}}
+	}
+	return results
+}
+
+func mergeRequires(lists ...[]string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, list := range lists {
+		for _, tool := range list {
+			if tool = strings.TrimSpace(tool); tool != "" && !seen[tool] {
+				seen[tool] = true
+				out = append(out, tool)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package controlplane
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func fakeToolPath(t *testing.T, tools ...string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, tool := range tools {
+		if err := os.WriteFile(filepath.Join(dir, tool), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestCheckRequirements(t *testing.T) {
+	fakeToolPath(t, "rg", "gh")
+
+	if err := CheckRequirements(SliceManifest{Requires: []string{"rg", " gh "}}); err != nil {
+		t.Fatalf("expected satisfied requirements to pass, got %v", err)
+	}
+
+	err := CheckRequirements(SliceManifest{Requires: []string{"rg", "jq", "fd"}})
+	if !errors.Is(err, ErrMissingRequirements) || !strings.Contains(err.Error(), "jq, fd not found on PATH") {
+		t.Fatalf("expected the missing tools listed, got %v", err)
+	}
+
+	results := CheckSliceRequirements(map[string]SliceManifest{
+		"ok":     {Requires: []string{"gh"}},
+		"broken": {Requires: []string{"jq"}},
+		"none":   {},
+	})
+	if len(results) != 1 || results[0].Status != CheckWarn || results[0].Message != "slice broken: jq not found on PATH" {
+		t.Fatalf("expected one warning for the unmet slice, got %+v", results)
+	}
+}
+
+func TestLoadSlicesMergesIncludedRequires(t *testing.T) {
+	root := t.TempDir()
+	writeSliceFiles(t, root, map[string]string{
+		"base.json":  `{"requires": ["rg"], "extensions": ["extensions/base.ts"]}`,
+		"combo.json": `{"include": ["base"], "requires": ["gh", "rg"], "extensions": ["extensions/own.ts"]}`,
+	})
+
+	slices, err := LoadSlices(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := slices["combo"].Requires; !reflect.DeepEqual(got, []string{"rg", "gh"}) {
+		t.Fatalf("expected included requirements first, deduplicated, got %v", got)
+	}
+}
@@ -0,0 +1,74 @@
+package controlplane
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+type rootCacheKey struct {
+	override string
+	envRoot  string
+	workdir  string
+	home     string
+	xdg      string
+	markers  string
+	subdirs  string
+}
+
+type rootCacheEntry struct {
+	once   sync.Once
+	root   string
+	source RootSource
+	err    error
+	steps  []string
+}
+
+var (
+	rootCacheEnabled atomic.Bool
+	rootCache        sync.Map
+)
+
+func UseRootCache(enabled bool) {
+	rootCacheEnabled.Store(enabled)
+	rootCache.Clear()
+}
+
+func cachedResolveRoot(rootOverride string, trace func(string)) (string, RootSource, error) {
+	if !rootCacheEnabled.Load() {
+		return ResolveRoot(rootOverride, trace)
+	}
+
+	workdir, _ := os.Getwd()
+	key := rootCacheKey{
+		override: rootOverride,
+		envRoot:  os.Getenv("PI_AGENT_CONFIG_ROOT"),
+		workdir:  workdir,
+		home:     os.Getenv("HOME"),
+		xdg:      os.Getenv("XDG_CONFIG_HOME"),
+		markers:  os.Getenv("PICTL_ROOT_MARKERS"),
+		subdirs:  os.Getenv("PICTL_ROOT_SUBDIRS"),
+	}
+	value, _ := rootCache.LoadOrStore(key, &rootCacheEntry{})
+	entry := value.(*rootCacheEntry)
+
+	cached := true
+	entry.once.Do(func() {
+		cached = false
+		entry.root, entry.source, entry.err = ResolveRoot(rootOverride, func(message string) {
+			entry.steps = append(entry.steps, message)
+			if trace != nil {
+				trace(message)
+			}
+		})
+	})
+	if cached && trace != nil {
+		for _, step := range entry.steps {
+			trace(step)
+		}
+		if entry.err == nil {
+			trace("reusing root resolved earlier: " + entry.root)
+		}
+	}
+	return entry.root, entry.source, entry.err
+}
@@ -0,0 +1,108 @@
+package controlplane
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRootCacheConcurrentCallers(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeRootMarkers(t, first)
+	writeRootMarkers(t, second)
+	UseRootCache(true)
+	t.Cleanup(func() { UseRootCache(false) })
+
+	var reused atomic.Int32
+	trace := func(message string) {
+		if strings.HasPrefix(message, "reusing root resolved earlier") {
+			reused.Add(1)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 64; i++ {
+		override := first
+		if i%2 == 1 {
+			override = second
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				root, err := DetermineRootTrace(override, trace)
+				if err != nil || root != override {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("expected every call to return its own --root, got error %v", err)
+	}
+	if got := 64*50 - reused.Load(); got != 2 {
+		t.Fatalf("expected one resolution per distinct --root, got %d", got)
+	}
+
+	if err := os.RemoveAll(first); err != nil {
+		t.Fatal(err)
+	}
+	if root, err := DetermineRoot(first); err != nil || root != first {
+		t.Fatalf("expected the cached root to be reused, got %q, %v", root, err)
+	}
+	UseRootCache(false)
+	if _, err := DetermineRoot(first); err == nil {
+		t.Fatalf("expected the bypassed cache to resolve again and fail")
+	}
+}
+
+func TestRootCacheReplaysTraceOnHit(t *testing.T) {
+	root := t.TempDir()
+	writeRootMarkers(t, root)
+	UseRootCache(true)
+	t.Cleanup(func() { UseRootCache(false) })
+
+	if _, err := DetermineRoot(root); err != nil {
+		t.Fatal(err)
+	}
+	var steps []string
+	if _, err := DetermineRootTrace(root, func(message string) { steps = append(steps, message) }); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"root from --root: " + root, "reusing root resolved earlier: " + root}
+	if strings.Join(steps, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected the untraced resolution to be replayed, got %q", steps)
+	}
+}
+
+func TestRootCacheKeysOnMarkerEnv(t *testing.T) {
+	root := t.TempDir()
+	writeRootMarkers(t, root)
+	UseRootCache(true)
+	t.Cleanup(func() { UseRootCache(false) })
+
+	if _, err := DetermineRoot(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PICTL_ROOT_MARKERS", "custom.marker")
+	if _, err := DetermineRoot(root); err == nil {
+		t.Fatalf("expected a PICTL_ROOT_MARKERS change to resolve the root again")
+	}
+	t.Setenv("PICTL_ROOT_MARKERS", "")
+	t.Setenv("PICTL_ROOT_SUBDIRS", "nested")
+	var steps []string
+	if _, err := DetermineRootTrace(root, func(message string) { steps = append(steps, message) }); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		if strings.HasPrefix(step, "reusing root resolved earlier") {
+			t.Fatalf("expected a PICTL_ROOT_SUBDIRS change to resolve the root again, got %q", steps)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package controlplane
+
+import (
+	"reflect"
+	"strings"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var sliceFieldDocs = map[string]string{
+	"schemaVersion":   "Manifest format version; pictl refuses versions newer than it supports.",
+	"description":     "One-line summary shown by pictl slices, list, and the picker; may use {{.Slice}}-style placeholders.",
+	"defaultProfile":  "Pi profile to launch with when nothing more specific sets one.",
+	"extensions":      "Extension files to load, relative to the root or PICTL_EXTENSION_PATH; globs allowed.",
+	"extensionDirs":   "Directories whose top-level .ts/.js files all load, in name order, after the listed extensions.",
+	"include":         "Other slices whose extensions load first.",
+	"enabled":         "Set false to hide the slice and refuse to launch it.",
+	"allowedProfiles": "Profiles the slice may launch with; empty means any.",
+	"model":           "Pi model passed as --model unless one is forwarded.",
+	"tags":            "Labels for pictl slices --tag.",
+	"requires":        "Executables that must be on PATH before launching.",
+	"cwd":             "Directory Pi starts in, relative to the root unless absolute; --cwd overrides it.",
+}
+
+func SliceManifestSchema() map[string]any {
+	schema := structSchema(reflect.TypeOf(SliceManifest{}), sliceFieldDocs)
+	schema["$schema"] = jsonSchemaDialect
+	schema["title"] = "pictl slice manifest"
+	schema["properties"].(map[string]any)["$schema"] = map[string]any{
+		"type":        "string",
+		"description": "Path or URL of this schema, for editors.",
+	}
+	return schema
+}
+
+func structSchema(t reflect.Type, docs map[string]string) map[string]any {
+	properties := map[string]any{}
+	for _, field := range reflect.VisibleFields(t) {
+		name := jsonFieldName(field)
+		if name == "" {
+			continue
+		}
+		property := typeSchema(field.Type)
+		if doc := docs[name]; doc != "" {
+			property["description"] = doc
+		}
+		properties[name] = property
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	if !field.IsExported() || field.Anonymous {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(ExtensionRef{}) {
+		object := structSchema(t, nil)
+		object["required"] = []string{"path"}
+		return map[string]any{"oneOf": []any{map[string]any{"type": "string"}, object}}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t, nil)
+	}
+	return map[string]any{}
+}
@@ -0,0 +1,36 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSliceManifestSchemaCoversEveryField(t *testing.T) {
+	schema := SliceManifestSchema()
+	properties := schema["properties"].(map[string]any)
+
+	manifestType := reflect.TypeOf(SliceManifest{})
+	for i := 0; i < manifestType.NumField(); i++ {
+		field := manifestType.Field(i)
+		name := jsonFieldName(field)
+		if name == "" {
+			continue
+		}
+		property, ok := properties[name].(map[string]any)
+		if !ok {
+			t.Fatalf("schema is missing SliceManifest.%s (%q)", field.Name, name)
+		}
+		if property["description"] == nil {
+			t.Fatalf("schema property %q has no description; add one to sliceFieldDocs", name)
+		}
+	}
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("schema does not marshal: %v", err)
+	}
+	extensions := properties["extensions"].(map[string]any)["items"].(map[string]any)["oneOf"].([]any)
+	if object := extensions[1].(map[string]any); !reflect.DeepEqual(object["required"], []string{"path"}) {
+		t.Fatalf("expected object extension entries to require path, got %v", object)
+	}
+}
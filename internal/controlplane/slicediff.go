@@ -0,0 +1,108 @@
+package controlplane
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type SliceChange struct {
+	Name                string        `json:"name"`
+	AddedExtensions     []string      `json:"addedExtensions,omitempty"`
+	RemovedExtensions   []string      `json:"removedExtensions,omitempty"`
+	ExtensionsReordered bool          `json:"extensionsReordered,omitempty"`
+	Fields              []FieldChange `json:"fields,omitempty"`
+}
+
+type SliceCatalogDiff struct {
+	Added   []string      `json:"added"`
+	Removed []string      `json:"removed"`
+	Changed []SliceChange `json:"changed"`
+}
+
+func (d SliceCatalogDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func DiffSlices(from map[string]SliceManifest, to map[string]SliceManifest) SliceCatalogDiff {
+	diff := SliceCatalogDiff{Added: []string{}, Removed: []string{}, Changed: []SliceChange{}}
+	for _, name := range sortedSliceNames(to) {
+		if _, ok := from[name]; !ok {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+	for _, name := range sortedSliceNames(from) {
+		other, ok := to[name]
+		if !ok {
+			diff.Removed = append(diff.Removed, name)
+			continue
+		}
+		if change := diffSlice(name, from[name], other); change != nil {
+			diff.Changed = append(diff.Changed, *change)
+		}
+	}
+	return diff
+}
+
+func diffSlice(name string, from SliceManifest, to SliceManifest) *SliceChange {
+	change := SliceChange{Name: name}
+	before, after := extensionPaths(from.Extensions), extensionPaths(to.Extensions)
+	change.AddedExtensions = missingFrom(after, before)
+	change.RemovedExtensions = missingFrom(before, after)
+	if len(change.AddedExtensions) == 0 && len(change.RemovedExtensions) == 0 && !reflect.DeepEqual(before, after) {
+		change.ExtensionsReordered = true
+	}
+
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"description", from.Description, to.Description},
+		{"defaultProfile", from.DefaultProfile, to.DefaultProfile},
+		{"allowedProfiles", strings.Join(from.AllowedProfiles, ", "), strings.Join(to.AllowedProfiles, ", ")},
+		{"extensionDirs", strings.Join(from.ExtensionDirs, ", "), strings.Join(to.ExtensionDirs, ", ")},
+		{"model", from.Model, to.Model},
+		{"cwd", from.Cwd, to.Cwd},
+		{"enabled", strconv.FormatBool(from.IsEnabled()), strconv.FormatBool(to.IsEnabled())},
+		{"requires", strings.Join(from.Requires, ", "), strings.Join(to.Requires, ", ")},
+		{"tags", strings.Join(from.Tags, ", "), strings.Join(to.Tags, ", ")},
+	}
+	for _, field := range fields {
+		if field.from != field.to {
+			change.Fields = append(change.Fields, FieldChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+
+	if len(change.AddedExtensions) == 0 && len(change.RemovedExtensions) == 0 && !change.ExtensionsReordered && len(change.Fields) == 0 {
+		return nil
+	}
+	return &change
+}
+
+func extensionPaths(refs []ExtensionRef) []string {
+	paths := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		paths = append(paths, strings.TrimSpace(ref.Path))
+	}
+	return paths
+}
+
+func missingFrom(items []string, other []string) []string {
+	present := map[string]bool{}
+	for _, item := range other {
+		present[item] = true
+	}
+	var out []string
+	for _, item := range items {
+		if !present[item] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
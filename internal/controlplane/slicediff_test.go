@@ -0,0 +1,42 @@
+package controlplane
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSlices(t *testing.T) {
+	from := map[string]SliceManifest{
+		"meta":    {DefaultProfile: "meta", Extensions: extensionRefs("extensions/a.ts", "extensions/b.ts")},
+		"order":   {Extensions: extensionRefs("extensions/a.ts", "extensions/b.ts")},
+		"retired": {Extensions: extensionRefs("extensions/a.ts")},
+		"same":    {Extensions: extensionRefs("extensions/a.ts")},
+	}
+	to := map[string]SliceManifest{
+		"meta":  {DefaultProfile: "fast", Extensions: extensionRefs("extensions/a.ts", "extensions/c.ts")},
+		"order": {Extensions: extensionRefs("extensions/b.ts", "extensions/a.ts")},
+		"same":  {Extensions: extensionRefs("extensions/a.ts")},
+		"fresh": {Extensions: extensionRefs("extensions/a.ts")},
+	}
+
+	diff := DiffSlices(from, to)
+	want := SliceCatalogDiff{
+		Added:   []string{"fresh"},
+		Removed: []string{"retired"},
+		Changed: []SliceChange{
+			{
+				Name:              "meta",
+				AddedExtensions:   []string{"extensions/c.ts"},
+				RemovedExtensions: []string{"extensions/b.ts"},
+				Fields:            []FieldChange{{Field: "defaultProfile", From: "meta", To: "fast"}},
+			},
+			{Name: "order", ExtensionsReordered: true},
+		},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("unexpected diff:\n got %+v\nwant %+v", diff, want)
+	}
+	if !DiffSlices(from, from).Empty() {
+		t.Fatalf("expected a catalog to have no differences from itself")
+	}
+}
@@ -0,0 +1,50 @@
+package controlplane
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type startupWatch struct {
+	once     sync.Once
+	output   chan struct{}
+	exited   chan struct{}
+	timedOut atomic.Bool
+}
+
+func newStartupWatch() *startupWatch {
+	return &startupWatch{output: make(chan struct{}), exited: make(chan struct{})}
+}
+
+func (w *startupWatch) wrap(out io.Writer) io.Writer {
+	if out == nil {
+		return nil
+	}
+	return startupWriter{watch: w, out: out}
+}
+
+func (w *startupWatch) wait(timeout time.Duration, stop func()) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-w.output:
+	case <-w.exited:
+	case <-timer.C:
+		w.timedOut.Store(true)
+		stop()
+	}
+}
+
+type startupWriter struct {
+	watch *startupWatch
+	out   io.Writer
+}
+
+func (s startupWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		s.watch.once.Do(func() { close(s.watch.output) })
+	}
+	return s.out.Write(p)
+}
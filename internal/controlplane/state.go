@@ -0,0 +1,153 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const StateFileEnv = "PICTL_STATE_FILE"
+
+const (
+	stateLockTimeout = 5 * time.Second
+	stateLockStale   = 30 * time.Second
+	stateLockPoll    = 10 * time.Millisecond
+)
+
+type UserState struct {
+	Profiles map[string]string `json:"profiles,omitempty"`
+}
+
+func StatePath() (string, error) {
+	if path := strings.TrimSpace(os.Getenv(StateFileEnv)); path != "" {
+		return expandHome(path), nil
+	}
+
+	stateHome := strings.TrimSpace(os.Getenv("XDG_STATE_HOME"))
+	if stateHome == "" || !filepath.IsAbs(stateHome) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("locate user state: %w", err)
+		}
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateHome, "pictl", "state.json"), nil
+}
+
+func LoadUserState(path string) (UserState, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return UserState{}, nil
+	}
+	if err != nil {
+		return UserState{}, err
+	}
+
+	var state UserState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return UserState{}, fmt.Errorf("parse user state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+func SaveUserState(path string, state UserState) error {
+	raw, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeStateFile(path, append(raw, '\n'))
+}
+
+func UpdateUserState(path string, update func(*UserState)) error {
+	unlock, err := lockStateFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := LoadUserState(path)
+	if err != nil {
+		return err
+	}
+	update(&state)
+	return SaveUserState(path, state)
+}
+
+func writeStateFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func lockStateFile(path string) (func(), error) {
+	lockPath := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(stateLockTimeout)
+	for {
+		lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			fmt.Fprintf(lock, "%d\n", os.Getpid())
+			lock.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("lock user state: %w", err)
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > stateLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("user state %s is locked by another pictl (remove %s if it is stale)", path, lockPath)
+		}
+		time.Sleep(stateLockPoll)
+	}
+}
+
+func (s UserState) TargetProfile(target string) string {
+	return strings.TrimSpace(s.Profiles[target])
+}
+
+func (s *UserState) SetTargetProfile(target string, profile string) {
+	if s.Profiles == nil {
+		s.Profiles = make(map[string]string)
+	}
+	s.Profiles[target] = profile
+}
+
+func (s *UserState) UnsetTargetProfile(target string) bool {
+	if _, ok := s.Profiles[target]; !ok {
+		return false
+	}
+	delete(s.Profiles, target)
+	return true
+}
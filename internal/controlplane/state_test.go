@@ -0,0 +1,118 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUserStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+
+	state, err := LoadUserState(path)
+	if err != nil {
+		t.Fatalf("expected missing state file to load empty, got %v", err)
+	}
+	if got := state.TargetProfile("meta"); got != "" {
+		t.Fatalf("expected no saved profile, got %q", got)
+	}
+
+	state.SetTargetProfile("meta", "fast")
+	if err := SaveUserState(path, state); err != nil {
+		t.Fatalf("unexpected save error: %v", err)
+	}
+	loaded, err := LoadUserState(path)
+	if err != nil {
+		t.Fatalf("unexpected load error: %v", err)
+	}
+	if got := loaded.TargetProfile("meta"); got != "fast" {
+		t.Fatalf("expected saved profile fast, got %q", got)
+	}
+
+	if !loaded.UnsetTargetProfile("meta") || loaded.UnsetTargetProfile("meta") {
+		t.Fatalf("expected unset to report removal exactly once")
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadUserState(path); err == nil {
+		t.Fatalf("expected malformed state file to fail")
+	}
+}
+
+func TestStatePathPrefersOverride(t *testing.T) {
+	t.Setenv(StateFileEnv, "/tmp/pictl-state.json")
+	if got, err := StatePath(); err != nil || got != "/tmp/pictl-state.json" {
+		t.Fatalf("expected override path, got %q, %v", got, err)
+	}
+
+	t.Setenv(StateFileEnv, "")
+	t.Setenv("XDG_STATE_HOME", "/var/state")
+	if got, err := StatePath(); err != nil || got != filepath.Join("/var/state", "pictl", "state.json") {
+		t.Fatalf("expected XDG state path, got %q, %v", got, err)
+	}
+}
+
+func TestUpdateUserStateConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- UpdateUserState(path, func(state *UserState) {
+				state.SetTargetProfile(fmt.Sprintf("target-%d", i), "fast")
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected update error: %v", err)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(raw) {
+		t.Fatalf("expected valid JSON after concurrent updates:\n%s", raw)
+	}
+	state, err := LoadUserState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Profiles) != writers {
+		t.Fatalf("expected every update to survive, got %v", state.Profiles)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".state.json.tmp-*"))
+	if _, err := os.Stat(path + ".lock"); err == nil || len(leftovers) > 0 {
+		t.Fatalf("expected no lock or temp files left behind, got %v", leftovers)
+	}
+}
+
+func TestLockStateFileBreaksStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	lockPath := path + ".lock"
+	if err := os.WriteFile(lockPath, []byte("1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * stateLockStale)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := UpdateUserState(path, func(state *UserState) { state.SetTargetProfile("meta", "fast") }); err != nil {
+		t.Fatalf("expected a stale lock to be replaced, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const TargetCatalogFile = "targets.json"
+
+var builtinTargets = CanonicalTargets()
+
+var targetCatalogPath string
+
+func DefaultTargetCatalogPath(root string) string {
+	return filepath.Join(root, TargetCatalogFile)
+}
+
+func LoadTargetCatalog(path string) ([]Target, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []Target
+	if err := json.Unmarshal(raw, &targets); err != nil {
+		return nil, fmt.Errorf("target catalog %s: %w", path, err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("target catalog %s: no targets defined", path)
+	}
+	for i, target := range targets {
+		if strings.TrimSpace(target.Name) == "" || strings.TrimSpace(target.Slice) == "" {
+			return nil, fmt.Errorf("target catalog %s: entry %d needs a name and a slice", path, i+1)
+		}
+	}
+	if err := ValidateTargets(targets); err != nil {
+		return nil, fmt.Errorf("target catalog %s: %w", path, err)
+	}
+	return targets, nil
+}
+
+func UseTargetCatalog(path string, targets []Target) {
+	if len(targets) == 0 {
+		path, targets = "", builtinTargets
+	}
+	targetCatalogPath = path
+	canonicalTargets = append([]Target(nil), targets...)
+	aliasToTarget = buildAliasMap(canonicalTargets)
+}
+
+func TargetCatalogPath() string {
+	return targetCatalogPath
+}
@@ -0,0 +1,60 @@
+package controlplane
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTargetCatalog(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), TargetCatalogFile)
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTargetCatalogResolves(t *testing.T) {
+	path := writeTargetCatalog(t, `[
+		{"name": "research", "slice": "daybook", "defaultProfile": "fast", "category": "Writing", "aliases": ["dig"]},
+		{"name": "meta", "slice": "meta", "aliases": ["m"]}
+	]`)
+	targets, err := LoadTargetCatalog(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	UseTargetCatalog(path, targets)
+	t.Cleanup(func() { UseTargetCatalog("", nil) })
+
+	target, ok := ResolveTarget("dig")
+	if !ok || target.Name != "research" || target.Slice != "daybook" {
+		t.Fatalf("expected dig to resolve to research, got %+v, %v", target, ok)
+	}
+	if _, ok := ResolveTarget("build"); ok {
+		t.Fatal("expected built-in targets to be replaced by the catalog")
+	}
+	if TargetCatalogPath() != path || len(CanonicalTargets()) != 2 {
+		t.Fatalf("expected the catalog to be active, got %q with %d targets", TargetCatalogPath(), len(CanonicalTargets()))
+	}
+
+	UseTargetCatalog("", nil)
+	if _, ok := ResolveTarget("build"); !ok || TargetCatalogPath() != "" {
+		t.Fatal("expected the built-in targets to be restored")
+	}
+}
+
+func TestLoadTargetCatalogRejectsBadFiles(t *testing.T) {
+	cases := map[string]string{
+		"not json":        `[{"name": "x",`,
+		"no targets":      `[]`,
+		"needs a slice":   `[{"name": "x"}]`,
+		"alias collision": `[{"name": "a", "slice": "s", "aliases": ["x"]}, {"name": "b", "slice": "s", "aliases": ["x"]}]`,
+	}
+	for name, body := range cases {
+		if _, err := LoadTargetCatalog(writeTargetCatalog(t, body)); err == nil || !strings.Contains(err.Error(), "target catalog") {
+			t.Fatalf("%s: expected a target catalog error, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const launchTraceVersion = 1
+
+type LaunchTrace struct {
+	Version  int               `json:"version"`
+	Root     string            `json:"root,omitempty"`
+	Target   string            `json:"target,omitempty"`
+	Slice    string            `json:"slice,omitempty"`
+	Profile  string            `json:"profile,omitempty"`
+	Strict   bool              `json:"strict"`
+	Dir      string            `json:"dir,omitempty"`
+	Stdin    string            `json:"stdinFile,omitempty"`
+	Args     []string          `json:"args"`
+	Env      map[string]string `json:"env,omitempty"`
+	UnsetEnv []string          `json:"unsetEnv,omitempty"`
+	Timeout  string            `json:"timeout,omitempty"`
+	Startup  string            `json:"startupTimeout,omitempty"`
+}
+
+func NewLaunchTrace(spec LaunchSpec, base []string) LaunchTrace {
+	trace := LaunchTrace{
+		Version: launchTraceVersion,
+		Target:  envValue(spec.Env, "PI_WORKFLOW_TARGET"),
+		Slice:   envValue(spec.Env, "PI_WORKFLOW_SLICE"),
+		Profile: envValue(spec.Env, "PI_DEFAULT_PROFILE"),
+		Dir:     spec.Dir,
+		Stdin:   spec.StdinFile,
+		Args:    append([]string{}, spec.Args...),
+	}
+	if value, ok := ProfileFlagValue(spec.Args); ok {
+		trace.Profile = value
+	}
+	if spec.Timeout > 0 {
+		trace.Timeout = spec.Timeout.String()
+	}
+	if spec.StartupTimeout > 0 {
+		trace.Startup = spec.StartupTimeout.String()
+	}
+
+	launched := envMap(spec.Env)
+	current := envMap(base)
+	for key, value := range launched {
+		if previous, ok := current[key]; !ok || previous != value {
+			if trace.Env == nil {
+				trace.Env = map[string]string{}
+			}
+			trace.Env[key] = value
+		}
+	}
+	for key := range current {
+		if _, ok := launched[key]; !ok {
+			trace.UnsetEnv = append(trace.UnsetEnv, key)
+		}
+	}
+	sort.Strings(trace.UnsetEnv)
+	return trace
+}
+
+func (t LaunchTrace) Spec(base []string) (LaunchSpec, error) {
+	spec := LaunchSpec{Args: append([]string{}, t.Args...), Dir: t.Dir, StdinFile: t.Stdin}
+	if t.Timeout != "" {
+		timeout, err := time.ParseDuration(t.Timeout)
+		if err != nil {
+			return LaunchSpec{}, fmt.Errorf("invalid timeout %q: %w", t.Timeout, err)
+		}
+		spec.Timeout = timeout
+	}
+	if t.Startup != "" {
+		timeout, err := time.ParseDuration(t.Startup)
+		if err != nil {
+			return LaunchSpec{}, fmt.Errorf("invalid startupTimeout %q: %w", t.Startup, err)
+		}
+		spec.StartupTimeout = timeout
+	}
+
+	unset := map[string]bool{}
+	for _, key := range t.UnsetEnv {
+		unset[key] = true
+	}
+	for _, entry := range base {
+		key, _, _ := strings.Cut(entry, "=")
+		if _, replaced := t.Env[key]; !unset[key] && !replaced {
+			spec.Env = append(spec.Env, entry)
+		}
+	}
+	keys := make([]string, 0, len(t.Env))
+	for key := range t.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		spec.Env = append(spec.Env, key+"="+t.Env[key])
+	}
+	return spec, nil
+}
+
+func WriteLaunchTrace(path string, trace LaunchTrace) error {
+	raw, err := json.MarshalIndent(trace, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o600)
+}
+
+func LoadLaunchTrace(path string) (LaunchTrace, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return LaunchTrace{}, err
+	}
+	var trace LaunchTrace
+	if err := json.Unmarshal(raw, &trace); err != nil {
+		return LaunchTrace{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if trace.Version != launchTraceVersion {
+		return LaunchTrace{}, fmt.Errorf("%s: unsupported trace version %d (want %d)", path, trace.Version, launchTraceVersion)
+	}
+	if len(trace.Args) == 0 {
+		return LaunchTrace{}, fmt.Errorf("%s: trace has no args", path)
+	}
+	return trace, nil
+}
+
+func envValue(env []string, key string) string {
+	value, _ := lookupEnv(env, key)
+	return value
+}
+
+func envMap(env []string) map[string]string {
+	out := make(map[string]string, len(env))
+	for _, entry := range env {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			out[key] = value
+		}
+	}
+	return out
+}
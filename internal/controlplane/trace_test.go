@@ -0,0 +1,45 @@
+package controlplane
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLaunchTraceRoundTrip(t *testing.T) {
+	base := []string{"HOME=/home/me", "PATH=/bin", "SECRET=x", "PI_DEFAULT_PROFILE=ship"}
+	spec := LaunchSpec{
+		Args:    []string{"--no-extensions", "-e", "/root/extensions/x.ts", "--model", "a b"},
+		Env:     []string{"HOME=/home/me", "PATH=/bin", "PI_DEFAULT_PROFILE=fast", "PI_WORKFLOW_TARGET=build", "PI_WORKFLOW_SLICE=software"},
+		Timeout: 90 * time.Second,
+	}
+
+	trace := NewLaunchTrace(spec, base)
+	if trace.Target != "build" || trace.Slice != "software" || trace.Profile != "fast" || trace.Timeout != "1m30s" {
+		t.Fatalf("unexpected trace metadata %+v", trace)
+	}
+	wantEnv := map[string]string{"PI_DEFAULT_PROFILE": "fast", "PI_WORKFLOW_TARGET": "build", "PI_WORKFLOW_SLICE": "software"}
+	if !reflect.DeepEqual(trace.Env, wantEnv) || !reflect.DeepEqual(trace.UnsetEnv, []string{"SECRET"}) {
+		t.Fatalf("expected only the env diff, got env=%v unset=%v", trace.Env, trace.UnsetEnv)
+	}
+
+	path := filepath.Join(t.TempDir(), "trace.json")
+	if err := WriteLaunchTrace(path, trace); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadLaunchTrace(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := loaded.Spec(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(replayed.Args, spec.Args) || replayed.Timeout != spec.Timeout {
+		t.Fatalf("expected the recorded args and timeout back, got %+v", replayed)
+	}
+	if !reflect.DeepEqual(envMap(replayed.Env), envMap(spec.Env)) {
+		t.Fatalf("expected the launch env to be reconstructed:\n got %v\nwant %v", replayed.Env, spec.Env)
+	}
+}
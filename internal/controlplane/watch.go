@@ -0,0 +1,115 @@
+package controlplane
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+type FileSnapshot map[string]fileStamp
+
+func WatchedPaths(root string, sliceName string, slices map[string]SliceManifest, opts LaunchOptions) ([]string, error) {
+	files, err := SliceFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	manifestPaths := make(map[string]string, len(files))
+	for _, file := range files {
+		manifestPaths[file.Name] = file.Path
+	}
+
+	seen := map[string]bool{}
+	var paths []string
+	add := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	visited := map[string]bool{}
+	var addSlice func(name string)
+	addSlice = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		add(manifestPaths[name])
+		for _, include := range slices[name].Include {
+			addSlice(strings.TrimSpace(include))
+		}
+	}
+	addSlice(sliceName)
+
+	resolved, err := ResolveExtensions(root, slices[sliceName], opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range resolved.Paths {
+		add(path)
+	}
+	for _, dir := range resolved.Dirs {
+		add(dir)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func SnapshotFiles(paths []string) FileSnapshot {
+	snapshot := make(FileSnapshot, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			snapshot[path] = fileStamp{}
+			continue
+		}
+		snapshot[path] = fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+	}
+	return snapshot
+}
+
+func ChangedFiles(before FileSnapshot, after FileSnapshot) []string {
+	var changed []string
+	for path, stamp := range after {
+		if previous, ok := before[path]; !ok || previous != stamp {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func WaitForChange(ctx context.Context, paths []string, poll time.Duration, debounce time.Duration) ([]string, error) {
+	baseline := SnapshotFiles(paths)
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	var changed []string
+	var settleAt time.Time
+	current := baseline
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case now := <-ticker.C:
+			next := SnapshotFiles(paths)
+			if len(ChangedFiles(current, next)) > 0 {
+				current = next
+				settleAt = now.Add(debounce)
+				changed = ChangedFiles(baseline, current)
+				continue
+			}
+			if changed != nil && !now.Before(settleAt) {
+				return changed, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package controlplane
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatchedPathsIncludesManifestsAndExtensions(t *testing.T) {
+	root := t.TempDir()
+	writeExtensionFiles(t, root, "extensions/x.ts", "extensions/y.ts", "extensions/unused.ts")
+	writeSliceFiles(t, root, map[string]string{
+		"base.json":   `{"extensions": ["extensions/x.ts"]}`,
+		"review.json": `{"include": ["base"], "extensions": ["extensions/y.ts"]}`,
+		"other.json":  `{"extensions": ["extensions/unused.ts"]}`,
+	})
+	slices, err := LoadSlices(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := WatchedPaths(root, "review", slices, LaunchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		filepath.Join(root, "extensions", "x.ts"),
+		filepath.Join(root, "extensions", "y.ts"),
+		filepath.Join(root, "slices", "base.json"),
+		filepath.Join(root, "slices", "review.json"),
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+}
+
+func TestChangedFilesDetectsEditsAndDeletes(t *testing.T) {
+	dir := t.TempDir()
+	kept, edited, removed := filepath.Join(dir, "kept"), filepath.Join(dir, "edited"), filepath.Join(dir, "removed")
+	for _, path := range []string{kept, edited, removed} {
+		if err := os.WriteFile(path, []byte("a"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := []string{kept, edited, removed}
+	before := SnapshotFiles(paths)
+
+	if err := os.WriteFile(edited, []byte("ab"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	if got := ChangedFiles(before, SnapshotFiles(paths)); !reflect.DeepEqual(got, []string{edited, removed}) {
+		t.Fatalf("expected edited and removed, got %v", got)
+	}
+	if got := ChangedFiles(before, before); got != nil {
+		t.Fatalf("expected no changes, got %v", got)
+	}
+}
+
+func TestWaitForChangeDebouncesBursts(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	for _, path := range []string{first, second} {
+		if err := os.WriteFile(path, []byte("a"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := make(chan []string, 1)
+	go func() {
+		changed, _ := WaitForChange(context.Background(), []string{first, second}, 5*time.Millisecond, 100*time.Millisecond)
+		result <- changed
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(first, []byte("ab"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	select {
+	case changed := <-result:
+		t.Fatalf("expected the debounce to hold, got %v early", changed)
+	default:
+	}
+	if err := os.WriteFile(second, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case changed := <-result:
+		if !reflect.DeepEqual(changed, []string{first, second}) {
+			t.Fatalf("expected both files in one batch, got %v", changed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a change to be reported")
+	}
+}
+
+func TestWaitForChangeStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := WaitForChange(ctx, []string{filepath.Join(t.TempDir(), "x")}, time.Millisecond, time.Millisecond); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package controlplane
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type WorkdirRelation string
+
+const (
+	WorkdirInside    WorkdirRelation = "inside"
+	WorkdirAncestor  WorkdirRelation = "ancestor"
+	WorkdirUnrelated WorkdirRelation = "unrelated"
+)
+
+func RelateWorkdir(root string, cwd string) WorkdirRelation {
+	root, cwd = realPath(root), realPath(cwd)
+	if isWithin(root, cwd) {
+		return WorkdirInside
+	}
+	if isWithin(cwd, root) {
+		return WorkdirAncestor
+	}
+	return WorkdirUnrelated
+}
+
+func CheckWorkdir(root string, cwd string) CheckResult {
+	switch RelateWorkdir(root, cwd) {
+	case WorkdirInside:
+		return CheckResult{Name: "cwd", Status: CheckOK, Message: fmt.Sprintf("working directory %s is inside root %s", cwd, root)}
+	case WorkdirAncestor:
+		return CheckResult{Name: "cwd", Status: CheckWarn, Message: fmt.Sprintf("working directory %s is an ancestor of root %s; Pi's AGENTS.md cascade will not include the root's context files", cwd, root)}
+	default:
+		return CheckResult{Name: "cwd", Status: CheckWarn, Message: fmt.Sprintf("working directory %s is unrelated to root %s; Pi layers context from %s upward, not from the config tree", cwd, root, cwd)}
+	}
+}
+
+func realPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return filepath.Clean(path)
+}
+
+func isWithin(parent string, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
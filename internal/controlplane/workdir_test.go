@@ -0,0 +1,44 @@
+package controlplane
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckWorkdirRelations(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "config", "pi-agent-config")
+	other := filepath.Join(base, "elsewhere")
+	for _, dir := range []string{filepath.Join(root, "slices"), other} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		cwd    string
+		want   WorkdirRelation
+		status CheckStatus
+		text   string
+	}{
+		{"root itself", root, WorkdirInside, CheckOK, "is inside root"},
+		{"inside", filepath.Join(root, "slices"), WorkdirInside, CheckOK, "is inside root"},
+		{"ancestor", filepath.Dir(root), WorkdirAncestor, CheckWarn, "is an ancestor of root"},
+		{"unrelated", other, WorkdirUnrelated, CheckWarn, "is unrelated to root"},
+		{"sibling prefix", root + "-old", WorkdirUnrelated, CheckWarn, "is unrelated to root"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RelateWorkdir(root, tc.cwd); got != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, got)
+			}
+			result := CheckWorkdir(root, tc.cwd)
+			if result.Status != tc.status || !strings.Contains(result.Message, tc.text) {
+				t.Fatalf("unexpected result %+v", result)
+			}
+		})
+	}
+}